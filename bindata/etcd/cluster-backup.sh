@@ -111,6 +111,13 @@ fi
 
 backup_latest_kube_static_resources "${BACKUP_TAR_FILE}"
 
+# Authenticate with the dedicated backup client cert if the certs of the node include it
+BACKUP_CERT="${CONFIG_FILE_DIR}/static-pod-certs/secrets/etcd-all-certs/etcd-client-backup.crt"
+if [ -f "${BACKUP_CERT}" ]; then
+  export ETCDCTL_CERT="${BACKUP_CERT}"
+  export ETCDCTL_KEY="${CONFIG_FILE_DIR}/static-pod-certs/secrets/etcd-all-certs/etcd-client-backup.key"
+fi
+
 # Download etcdctl and get the etcd snapshot
 dl_etcdctl
 ETCDCTL_ENDPOINTS="https://${NODE_NODE_ENVVAR_NAME_IP}:2379" etcdctl snapshot save "${SNAPSHOT_FILE}"
@@ -119,7 +119,8 @@ fi
 # Restore static pod resources
 tar -C "${CONFIG_FILE_DIR}" -xzf "${BACKUP_FILE}" static-pod-resources
 
-# Copy snapshot to backupdir
+# Copy snapshot to backupdir, the marker of a previous restore would make the restore pod skip the new snapshot
+rm -f "${ETCD_DATA_DIR_BACKUP}"/snapshot-restored
 cp -p "${SNAPSHOT_FILE}" "${ETCD_DATA_DIR_BACKUP}"/snapshot.db
 
 echo "starting restore-etcd static pod"
@@ -9,9 +9,11 @@ import (
 	"time"
 
 	"github.com/openshift/cluster-etcd-operator/pkg/cmd/backuprestore"
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/certs"
 	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor"
 	operatorcmd "github.com/openshift/cluster-etcd-operator/pkg/cmd/operator"
 	"github.com/openshift/cluster-etcd-operator/pkg/cmd/render"
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/verifynodeip"
 	"github.com/openshift/cluster-etcd-operator/pkg/cmd/waitforceo"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator"
 	"github.com/openshift/library-go/pkg/operator/staticpod/certsyncpod"
@@ -59,11 +61,17 @@ func NewSSCSCommand() *cobra.Command {
 	cmd.AddCommand(render.NewRenderCommand(os.Stderr))
 	cmd.AddCommand(backuprestore.NewBackupCommand(os.Stderr))
 	cmd.AddCommand(backuprestore.NewRestoreCommand(os.Stderr))
+	cmd.AddCommand(backuprestore.NewBackupStreamCommand(os.Stderr))
+	cmd.AddCommand(backuprestore.NewBackupStreamReplayCommand(os.Stderr))
+	cmd.AddCommand(backuprestore.NewBackupInventoryCommand(os.Stdout, os.Stderr))
+	cmd.AddCommand(backuprestore.NewQuorumRestoreCommand(os.Stderr))
 	cmd.AddCommand(installerpod.NewInstaller())
 	cmd.AddCommand(prune.NewPrune())
 	cmd.AddCommand(certsyncpod.NewCertSyncControllerCommand(operator.CertConfigMaps, operator.CertSecrets))
 	cmd.AddCommand(waitforceo.NewWaitForCeoCommand(os.Stderr))
 	cmd.AddCommand(monitor.NewMonitorCommand(os.Stderr))
+	cmd.AddCommand(certs.NewCertsCommand(os.Stdout, os.Stderr))
+	cmd.AddCommand(verifynodeip.NewVerifyNodeIPCommand(os.Stderr))
 
 	return cmd
 }
@@ -13,11 +13,12 @@ import (
 )
 
 type backupOptions struct {
-	endpoints []string
-	configDir string
-	dataDir   string
-	backupDir string
-	errOut    io.Writer
+	endpoints          []string
+	configDir          string
+	dataDir            string
+	backupDir          string
+	maxAppliedIndexLag uint64
+	errOut             io.Writer
 }
 
 func NewBackupCommand(errOut io.Writer) *cobra.Command {
@@ -47,10 +48,11 @@ func NewBackupCommand(errOut io.Writer) *cobra.Command {
 
 func (r *backupOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.Set("logtostderr", "true")
-	fs.StringSliceVar(&r.endpoints, "endpoints", []string{"127.0.0.1:2379"}, "etcd endpoints")
+	fs.StringSliceVar(&r.endpoints, "endpoints", []string{"127.0.0.1:2379"}, "etcd endpoints used to discover the cluster members")
 	fs.StringVar(&r.configDir, "config-dir", "/etc/kubernetes", "Path to the kubernetes config directory")
 	fs.StringVar(&r.dataDir, "data-dir", "/var/lib/etcd", "Path to the data directory")
 	fs.StringVar(&r.backupDir, "backup-dir", "", "Path to the directory where the backup is generated")
+	fs.Uint64Var(&r.maxAppliedIndexLag, "max-applied-index-lag", defaultMaxAppliedIndexLag, "Maximum number of entries a follower's applied index may trail the leader to be preferred as the backup source")
 }
 
 func (r *backupOptions) Validate() error {
@@ -0,0 +1,154 @@
+package backuprestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	"k8s.io/klog/v2"
+	"sort"
+	"time"
+)
+
+const (
+	// defaultMaxAppliedIndexLag is the number of raft entries a follower may trail the leader's applied index and
+	// still be preferred as the backup source.
+	defaultMaxAppliedIndexLag = 100
+	// memberRequestTimeout bounds every request made to select the member, an unresponsive cluster must not block the
+	// backup.
+	memberRequestTimeout = 5 * time.Second
+)
+
+// backupSourceMember describes the member a snapshot was taken from and its raft state at selection time.
+type backupSourceMember struct {
+	ID                     string `json:"id"`
+	Name                   string `json:"name"`
+	Endpoint               string `json:"endpoint"`
+	IsLeader               bool   `json:"isLeader"`
+	Leader                 string `json:"leader"`
+	RaftTerm               uint64 `json:"raftTerm"`
+	RaftIndex              uint64 `json:"raftIndex"`
+	RaftAppliedIndex       uint64 `json:"raftAppliedIndex"`
+	LeaderRaftAppliedIndex uint64 `json:"leaderRaftAppliedIndex"`
+}
+
+type backupMemberCandidate struct {
+	member   *etcdserverpb.Member
+	endpoint string
+	status   *clientv3.StatusResponse
+	alarms   []string
+	err      error
+}
+
+func (c *backupMemberCandidate) isHealthy() bool {
+	return c.err == nil && c.status != nil && len(c.alarms) == 0 && len(c.status.Errors) == 0 && !c.status.IsLearner
+}
+
+// selectBackupMember lists the members of the cluster reachable through cli and picks the member the backup should be
+// taken from.
+func selectBackupMember(ctx context.Context, cli *clientv3.Client, maxAppliedIndexLag uint64) (*backupSourceMember, error) {
+	listCtx, cancel := context.WithTimeout(ctx, memberRequestTimeout)
+	membersResp, err := cli.MemberList(listCtx)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	alarmCtx, cancel := context.WithTimeout(ctx, memberRequestTimeout)
+	alarmsResp, err := cli.AlarmList(alarmCtx)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alarms: %w", err)
+	}
+	alarms := map[uint64][]string{}
+	for _, alarm := range alarmsResp.Alarms {
+		alarms[alarm.MemberID] = append(alarms[alarm.MemberID], alarm.Alarm.String())
+	}
+
+	var candidates []*backupMemberCandidate
+	for _, member := range membersResp.Members {
+		if len(member.ClientURLs) == 0 {
+			// member has not started yet
+			continue
+		}
+		candidate := &backupMemberCandidate{
+			member:   member,
+			endpoint: member.ClientURLs[0],
+			alarms:   alarms[member.ID],
+		}
+		statusCtx, cancel := context.WithTimeout(ctx, memberRequestTimeout)
+		candidate.status, candidate.err = cli.Status(statusCtx, candidate.endpoint)
+		cancel()
+		if candidate.err != nil {
+			klog.Warningf("member %s is not a backup candidate: %v", member.Name, candidate.err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return pickBackupMember(candidates, maxAppliedIndexLag)
+}
+
+// pickBackupMember prefers the healthy follower with the smallest applied index lag behind the leader as long as the
+// lag is within maxAppliedIndexLag. Snapshots put additional load on a member, the leader is only used if no such
+// follower exists. Members with active alarms or failing status requests are never used, if no member is left an error
+// is returned and the backup falls back to the configured endpoints.
+func pickBackupMember(candidates []*backupMemberCandidate, maxAppliedIndexLag uint64) (*backupSourceMember, error) {
+	var leader *backupMemberCandidate
+	var followers []*backupMemberCandidate
+	for _, candidate := range candidates {
+		if !candidate.isHealthy() {
+			continue
+		}
+		if candidate.status.Leader == candidate.member.ID {
+			leader = candidate
+			continue
+		}
+		followers = append(followers, candidate)
+	}
+	if leader == nil && len(followers) == 0 {
+		return nil, errors.New("no healthy member without alarms found")
+	}
+
+	// without a healthy leader lag can not be determined, the follower with the highest applied index is used.
+	var leaderAppliedIndex uint64
+	if leader != nil {
+		leaderAppliedIndex = leader.status.RaftAppliedIndex
+	} else {
+		for _, follower := range followers {
+			if follower.status.RaftAppliedIndex > leaderAppliedIndex {
+				leaderAppliedIndex = follower.status.RaftAppliedIndex
+			}
+		}
+	}
+	lag := func(c *backupMemberCandidate) uint64 {
+		if c.status.RaftAppliedIndex >= leaderAppliedIndex {
+			return 0
+		}
+		return leaderAppliedIndex - c.status.RaftAppliedIndex
+	}
+	sort.SliceStable(followers, func(i, j int) bool {
+		if lag(followers[i]) == lag(followers[j]) {
+			return followers[i].member.Name < followers[j].member.Name
+		}
+		return lag(followers[i]) < lag(followers[j])
+	})
+
+	chosen := leader
+	if len(followers) > 0 && (lag(followers[0]) <= maxAppliedIndexLag || leader == nil) {
+		chosen = followers[0]
+	}
+	if leader == nil {
+		klog.Warningf("no healthy leader found, using member %s with the highest applied index", chosen.member.Name)
+	}
+
+	return &backupSourceMember{
+		ID:                     fmt.Sprintf("%x", chosen.member.ID),
+		Name:                   chosen.member.Name,
+		Endpoint:               chosen.endpoint,
+		IsLeader:               chosen == leader,
+		Leader:                 fmt.Sprintf("%x", chosen.status.Leader),
+		RaftTerm:               chosen.status.RaftTerm,
+		RaftIndex:              chosen.status.RaftIndex,
+		RaftAppliedIndex:       chosen.status.RaftAppliedIndex,
+		LeaderRaftAppliedIndex: leaderAppliedIndex,
+	}, nil
+}
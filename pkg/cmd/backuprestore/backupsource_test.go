@@ -0,0 +1,87 @@
+package backuprestore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+)
+
+func TestPickBackupMember(t *testing.T) {
+	candidate := func(id, leader, appliedIndex uint64) *backupMemberCandidate {
+		name := map[uint64]string{1: "etcd-1", 2: "etcd-2", 3: "etcd-3"}[id]
+		return &backupMemberCandidate{
+			member:   &etcdserverpb.Member{ID: id, Name: name, ClientURLs: []string{"https://" + name + ":2379"}},
+			endpoint: "https://" + name + ":2379",
+			status:   &clientv3.StatusResponse{Leader: leader, RaftAppliedIndex: appliedIndex},
+		}
+	}
+	withAlarm := func(c *backupMemberCandidate) *backupMemberCandidate {
+		c.alarms = []string{etcdserverpb.AlarmType_NOSPACE.String()}
+		return c
+	}
+	withErr := func(c *backupMemberCandidate) *backupMemberCandidate {
+		c.status, c.err = nil, errors.New("context deadline exceeded")
+		return c
+	}
+
+	testCases := map[string]struct {
+		candidates   []*backupMemberCandidate
+		wantMember   string
+		wantIsLeader bool
+		wantErr      bool
+	}{
+		"follower with smallest lag": {
+			candidates:   []*backupMemberCandidate{candidate(1, 1, 100), candidate(2, 1, 90), candidate(3, 1, 99)},
+			wantMember:   "etcd-3",
+			wantIsLeader: false,
+		},
+		"followers lagging fall back to leader": {
+			candidates:   []*backupMemberCandidate{candidate(1, 1, 1000), candidate(2, 1, 10), candidate(3, 1, 20)},
+			wantMember:   "etcd-1",
+			wantIsLeader: true,
+		},
+		"follower with alarm is skipped": {
+			candidates:   []*backupMemberCandidate{candidate(1, 1, 100), withAlarm(candidate(2, 1, 100)), candidate(3, 1, 95)},
+			wantMember:   "etcd-3",
+			wantIsLeader: false,
+		},
+		"unreachable follower is skipped": {
+			candidates:   []*backupMemberCandidate{candidate(1, 1, 100), withErr(candidate(2, 1, 100)), withErr(candidate(3, 1, 100))},
+			wantMember:   "etcd-1",
+			wantIsLeader: true,
+		},
+		"no leader uses highest applied index": {
+			candidates:   []*backupMemberCandidate{withErr(candidate(1, 1, 100)), candidate(2, 1, 50), candidate(3, 1, 60)},
+			wantMember:   "etcd-3",
+			wantIsLeader: false,
+		},
+		"no healthy member": {
+			candidates: []*backupMemberCandidate{withAlarm(candidate(1, 1, 100)), withErr(candidate(2, 1, 100))},
+			wantErr:    true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := pickBackupMember(tc.candidates, defaultMaxAppliedIndexLag)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantMember, got.Name)
+			require.Equal(t, tc.wantIsLeader, got.IsLeader)
+		})
+	}
+}
+
+func TestBackupEndpoints(t *testing.T) {
+	configured := []string{"https://localhost:2379"}
+	source := &backupSourceMember{Name: "etcd-2", Endpoint: "https://etcd-2:2379"}
+
+	require.Equal(t, []string{"https://etcd-2:2379"}, backupEndpoints(source, nil, configured))
+	require.Equal(t, configured, backupEndpoints(nil, errors.New("no healthy member without alarms found"), configured))
+	require.Equal(t, configured, backupEndpoints(nil, errors.New("failed to list members: context deadline exceeded"), configured))
+}
@@ -0,0 +1,291 @@
+package backuprestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/snapshot"
+	"go.etcd.io/etcd/mvcc/mvccpb"
+	"io"
+	"k8s.io/klog/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	segmentDirName            = "segments"
+	defaultSegmentMaxSize     = 64 * 1024 * 1024
+	defaultSegmentMaxDuration = 1 * time.Hour
+	compactedRetryInterval    = 10 * time.Second
+	watchRetryInterval        = 5 * time.Second
+
+	// maxCachedLeaseTTLs bounds the lease TTL cache, kube-apiserver reuses leases so the cache is rarely reset.
+	maxCachedLeaseTTLs = 10000
+)
+
+var (
+	errWatchCompacted = errors.New("watch revision has been compacted")
+	// errSegmentWrite is not recoverable by resuming the watch.
+	errSegmentWrite = errors.New("failed to write segment")
+)
+
+type backupStreamOptions struct {
+	endpoints          []string
+	configDir          string
+	backupDir          string
+	segmentMaxSize     int64
+	segmentMaxDuration time.Duration
+	maxAppliedIndexLag uint64
+	errOut             io.Writer
+}
+
+func NewBackupStreamCommand(errOut io.Writer) *cobra.Command {
+	backupStreamOpts := &backupStreamOptions{
+		errOut: errOut,
+	}
+	cmd := &cobra.Command{
+		Use:   "backup-stream",
+		Short: "Continuously backs up etcd by following a snapshot with a watch and appending every change to segment files",
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+					}
+					fmt.Fprint(backupStreamOpts.errOut, err.Error())
+				}
+			}
+
+			must(backupStreamOpts.Validate)
+			must(backupStreamOpts.Run)
+		},
+	}
+	backupStreamOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (r *backupStreamOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.Set("logtostderr", "true")
+	fs.StringSliceVar(&r.endpoints, "endpoints", []string{"127.0.0.1:2379"}, "etcd endpoints")
+	fs.StringVar(&r.configDir, "config-dir", "/etc/kubernetes", "Path to the kubernetes config directory")
+	fs.StringVar(&r.backupDir, "backup-dir", "", "Path to the directory where the snapshots and segments are stored")
+	fs.Int64Var(&r.segmentMaxSize, "segment-max-size", defaultSegmentMaxSize, "Size in bytes after which the active segment is rotated")
+	fs.DurationVar(&r.segmentMaxDuration, "segment-max-duration", defaultSegmentMaxDuration, "Duration after which the active segment is rotated")
+	fs.Uint64Var(&r.maxAppliedIndexLag, "max-applied-index-lag", defaultMaxAppliedIndexLag, "Maximum number of entries a follower's applied index may trail the leader to be preferred as the snapshot source")
+}
+
+func (r *backupStreamOptions) Validate() error {
+	if len(r.backupDir) == 0 {
+		return errors.New("missing required flag: --backup-dir")
+	}
+	if r.segmentMaxSize <= 0 {
+		return errors.New("--segment-max-size must be greater than 0")
+	}
+	if r.segmentMaxDuration <= 0 {
+		return errors.New("--segment-max-duration must be greater than 0")
+	}
+	return nil
+}
+
+func (r *backupStreamOptions) Run() error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		oscall := <-c
+		klog.Warningf("system call:%+v", oscall)
+		cancel()
+	}()
+
+	if err := backupStream(ctx, r); err != nil {
+		klog.Errorf("run: backup stream failed: %v", err)
+	}
+
+	return nil
+}
+
+// backupStream takes a full backup and then follows it with a watch starting at the snapshot revision. Every event is
+// appended to the active segment. A failed watch, e.g. on a leader election, is resumed after the last written
+// revision. Only if the watch falls behind compaction the revision log is no longer contiguous, a new full backup is
+// taken and the watch resumes from its revision.
+func backupStream(ctx context.Context, r *backupStreamOptions) error {
+	segmentDir := filepath.Join(r.backupDir, segmentDirName)
+	if err := os.MkdirAll(segmentDir, os.ModePerm); err != nil {
+		return fmt.Errorf("backup-stream: failed to create %s: %w", segmentDir, err)
+	}
+	if err := recoverPartialSegments(segmentDir); err != nil {
+		return fmt.Errorf("backup-stream: %w", err)
+	}
+
+	cli, err := getEtcdClient(r.endpoints)
+	if err != nil {
+		return fmt.Errorf("backup-stream: failed to get etcd client: %w", err)
+	}
+	defer cli.Close()
+
+	w := newSegmentWriter(segmentDir, r.segmentMaxSize, r.segmentMaxDuration)
+	defer func() {
+		if err := w.close(); err != nil {
+			klog.Errorf("failed to close segment: %v", err)
+		}
+	}()
+
+	for {
+		revision, err := streamBaseBackup(ctx, cli, r)
+		if err != nil {
+			return fmt.Errorf("backup-stream: %w", err)
+		}
+		klog.Infof("following revisions after %d", revision)
+
+		for {
+			revision, err = followRevisions(ctx, cli, w, revision)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, errWatchCompacted) {
+				break
+			}
+			if errors.Is(err, errSegmentWrite) {
+				return fmt.Errorf("backup-stream: %w", err)
+			}
+			klog.Warningf("backup-stream: %v, resuming the watch after revision %d in %s", err, revision, watchRetryInterval)
+			select {
+			case <-time.After(watchRetryInterval):
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		// segments must not span a gap in the revision log
+		if err := w.close(); err != nil {
+			return fmt.Errorf("backup-stream: %w", err)
+		}
+		klog.Warningf("backup-stream: %v, taking a new snapshot in %s", err, compactedRetryInterval)
+		select {
+		case <-time.After(compactedRetryInterval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// streamBaseBackup saves a snapshot of the selected backup member, the static pod resources and the backup metadata
+// next to the existing segments and returns the revision of the snapshot.
+func streamBaseBackup(ctx context.Context, cli *clientv3.Client, r *backupStreamOptions) (int64, error) {
+	source, err := selectBackupMember(ctx, cli, r.maxAppliedIndexLag)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select backup member: %w", err)
+	}
+	klog.Infof("backing up member %s (%s), leader: %v, applied index: %d, leader applied index: %d",
+		source.Name, source.Endpoint, source.IsLeader, source.RaftAppliedIndex, source.LeaderRaftAppliedIndex)
+	sourceCli, err := getEtcdClient([]string{source.Endpoint})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get etcd client: %w", err)
+	}
+	defer sourceCli.Close()
+
+	dateString := time.Now().Format(backupDateFormat)
+	outputArchive := "static_kuberesources_" + dateString + ".tar.gz"
+	snapshotOutFile := "snapshot_" + dateString + ".db"
+	metadataOutFile := "backup_metadata_" + dateString + ".json"
+	if err := saveSnapshot(sourceCli, filepath.Join(r.backupDir, snapshotOutFile)); err != nil {
+		return 0, fmt.Errorf("saveSnapshot failed: %w", err)
+	}
+	if err := archiveLatestResources(r.configDir, filepath.Join(r.backupDir, outputArchive)); err != nil {
+		return 0, fmt.Errorf("archiveLatestResources failed: %w", err)
+	}
+
+	status, err := snapshot.NewV3(nil).Status(filepath.Join(r.backupDir, snapshotOutFile))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read snapshot status: %w", err)
+	}
+	metadata := &backupMetadata{
+		Snapshot:           snapshotOutFile,
+		StaticPodResources: outputArchive,
+		Revision:           status.Revision,
+		Member:             source,
+	}
+	if err := writeBackupMetadata(filepath.Join(r.backupDir, metadataOutFile), metadata); err != nil {
+		return 0, fmt.Errorf("writeBackupMetadata failed: %w", err)
+	}
+	return status.Revision, nil
+}
+
+// followRevisions watches the whole keyspace after revision and writes every event to segments until the context is
+// cancelled or the watch fails. It returns the last revision written.
+func followRevisions(ctx context.Context, cli *clientv3.Client, w *segmentWriter, revision int64) (int64, error) {
+	// lease TTLs are looked up once per lease, they are required to recreate leases granted after the snapshot.
+	leaseTTLs := map[int64]int64{}
+	wch := cli.Watch(clientv3.WithRequireLeader(ctx), "", clientv3.WithPrefix(), clientv3.WithRev(revision+1))
+	for resp := range wch {
+		if resp.CompactRevision != 0 {
+			return revision, fmt.Errorf("%w: compacted at %d", errWatchCompacted, resp.CompactRevision)
+		}
+		if err := resp.Err(); err != nil {
+			return revision, fmt.Errorf("watch failed: %w", err)
+		}
+
+		received := time.Now()
+		records := make([]segmentRecord, 0, len(resp.Events))
+		for _, ev := range resp.Events {
+			record := segmentRecord{
+				Revision:  ev.Kv.ModRevision,
+				Key:       ev.Kv.Key,
+				Timestamp: received,
+			}
+			switch ev.Type {
+			case mvccpb.PUT:
+				record.Type = segmentRecordPut
+				record.Value = ev.Kv.Value
+				record.Lease = ev.Kv.Lease
+				if ev.Kv.Lease != 0 {
+					record.LeaseTTL = lookupLeaseTTL(ctx, cli, leaseTTLs, ev.Kv.Lease)
+				}
+			case mvccpb.DELETE:
+				record.Type = segmentRecordDelete
+			}
+			records = append(records, record)
+		}
+		if err := w.append(records); err != nil {
+			return revision, fmt.Errorf("%w: %v", errSegmentWrite, err)
+		}
+		if len(records) > 0 {
+			revision = records[len(records)-1].Revision
+		}
+	}
+	if ctx.Err() != nil {
+		return revision, ctx.Err()
+	}
+	return revision, errors.New("watch channel closed")
+}
+
+func lookupLeaseTTL(ctx context.Context, cli *clientv3.Client, leaseTTLs map[int64]int64, id int64) int64 {
+	if ttl, ok := leaseTTLs[id]; ok {
+		return ttl
+	}
+	resp, err := cli.TimeToLive(ctx, clientv3.LeaseID(id))
+	if err != nil {
+		klog.Warningf("failed to look up TTL of lease %x: %v", id, err)
+		return 0
+	}
+	// an expired lease reports a TTL of -1, keys attached to it are replayed without a lease.
+	ttl := resp.GrantedTTL
+	if resp.TTL < 0 {
+		ttl = 0
+	}
+	if len(leaseTTLs) >= maxCachedLeaseTTLs {
+		for k := range leaseTTLs {
+			delete(leaseTTLs, k)
+		}
+	}
+	leaseTTLs[id] = ttl
+	return ttl
+}
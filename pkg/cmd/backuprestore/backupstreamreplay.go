@@ -0,0 +1,380 @@
+package backuprestore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.etcd.io/etcd/clientv3/snapshot"
+	"go.etcd.io/etcd/lease"
+	"go.etcd.io/etcd/mvcc"
+	"go.etcd.io/etcd/mvcc/backend"
+	"go.etcd.io/etcd/pkg/traceutil"
+	"go.uber.org/zap"
+	"io"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type backupStreamReplayOptions struct {
+	backupDir    string
+	snapshotFile string
+	outputDir    string
+	toRevision   int64
+	toTime       string
+	errOut       io.Writer
+
+	// toTimestamp is the parsed value of toTime.
+	toTimestamp time.Time
+}
+
+func NewBackupStreamReplayCommand(errOut io.Writer) *cobra.Command {
+	replayOpts := &backupStreamReplayOptions{
+		errOut: errOut,
+	}
+	cmd := &cobra.Command{
+		Use:   "backup-stream-replay",
+		Short: "Replays backup-stream segments onto a snapshot and writes a backup that can be used by cluster-restore",
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+					}
+					fmt.Fprint(replayOpts.errOut, err.Error())
+				}
+			}
+
+			must(replayOpts.Validate)
+			must(replayOpts.Run)
+		},
+	}
+	replayOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (r *backupStreamReplayOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.Set("logtostderr", "true")
+	fs.StringVar(&r.backupDir, "backup-dir", "", "Path to the backup-stream directory containing snapshots and segments")
+	fs.StringVar(&r.snapshotFile, "snapshot", "", "Path to the snapshot the segments are replayed onto. Defaults to the latest snapshot in --backup-dir taken before the requested revision or time")
+	fs.StringVar(&r.outputDir, "output-dir", "", "Path to the directory where the replayed backup is generated")
+	fs.Int64Var(&r.toRevision, "to-revision", 0, "Last revision to replay. Defaults to all available revisions")
+	fs.StringVar(&r.toTime, "to-time", "", "Replay revisions received up to this RFC3339 timestamp")
+}
+
+func (r *backupStreamReplayOptions) Validate() error {
+	if len(r.backupDir) == 0 {
+		return errors.New("missing required flag: --backup-dir")
+	}
+	if len(r.outputDir) == 0 {
+		return errors.New("missing required flag: --output-dir")
+	}
+	if r.toRevision < 0 {
+		return errors.New("--to-revision must not be negative")
+	}
+	if len(r.toTime) > 0 {
+		if r.toRevision > 0 {
+			return errors.New("--to-revision and --to-time are mutually exclusive")
+		}
+		toTimestamp, err := time.Parse(time.RFC3339, r.toTime)
+		if err != nil {
+			return fmt.Errorf("invalid --to-time: %w", err)
+		}
+		r.toTimestamp = toTimestamp
+	}
+	return nil
+}
+
+func (r *backupStreamReplayOptions) Run() error {
+	if err := replay(r); err != nil {
+		klog.Errorf("run: replay failed: %v", err)
+	}
+	return nil
+}
+
+// reachedTarget returns true if the revision the record belongs to is past the requested target.
+func (r *backupStreamReplayOptions) reachedTarget(record segmentRecord) bool {
+	if r.toRevision > 0 && record.Revision > r.toRevision {
+		return true
+	}
+	if !r.toTimestamp.IsZero() && record.Timestamp.After(r.toTimestamp) {
+		return true
+	}
+	return false
+}
+
+// beforeTarget returns true if the backup was taken before the requested target, so that segments can be replayed onto
+// it. A --snapshot with an unknown revision is checked against the snapshot status once it is selected.
+func (r *backupStreamReplayOptions) beforeTarget(p *backupPair) bool {
+	if r.toRevision > 0 && p.Revision > r.toRevision {
+		return false
+	}
+	if !r.toTimestamp.IsZero() && p.Date.After(r.toTimestamp) {
+		return false
+	}
+	return true
+}
+
+// selectBackupPair returns the backup of --snapshot, or the newest complete backup in --backup-dir taken before the
+// requested target. Backups without a recorded revision, e.g. with metadata written by older versions, are skipped in
+// --backup-dir as they can not be placed before the target. The static pod resources of the backup are the ones
+// archived together with the snapshot.
+func (r *backupStreamReplayOptions) selectBackupPair() (*backupPair, error) {
+	if len(r.snapshotFile) > 0 {
+		snapshotFile := filepath.Clean(r.snapshotFile)
+		pairs, err := listBackupPairs(filepath.Dir(snapshotFile))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pairs {
+			if p.Snapshot != snapshotFile {
+				continue
+			}
+			if !p.complete() {
+				return nil, fmt.Errorf("could not find static pod resources of snapshot %s", snapshotFile)
+			}
+			if !r.beforeTarget(p) {
+				return nil, fmt.Errorf("snapshot %s at revision %d was taken after the requested target, choose an older --snapshot", snapshotFile, p.Revision)
+			}
+			return p, nil
+		}
+		return nil, fmt.Errorf("%s is not a backup snapshot, its name must match %s", snapshotFile, snapshotFileRegexp)
+	}
+
+	pairs, err := listBackupPairs(r.backupDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pairs {
+		if !p.complete() {
+			continue
+		}
+		if p.Revision == 0 {
+			klog.Infof("skipping backup %s without a recorded revision", p.Date.Format(backupDateFormat))
+			continue
+		}
+		if !r.beforeTarget(p) {
+			klog.Infof("skipping backup %s at revision %d taken after the requested target", p.Date.Format(backupDateFormat), p.Revision)
+			continue
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("could not find a complete backup in %s taken before the requested target", r.backupDir)
+}
+
+func replay(r *backupStreamReplayOptions) error {
+	backup, err := r.selectBackupPair()
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	snapshotFile, resourcesArchive := backup.Snapshot, backup.Resources
+
+	status, err := snapshot.NewV3(nil).Status(snapshotFile)
+	if err != nil {
+		return fmt.Errorf("replay: failed to read snapshot status: %w", err)
+	}
+	if r.toRevision > 0 && status.Revision > r.toRevision {
+		return fmt.Errorf("replay: snapshot %s at revision %d is newer than requested revision %d, choose an older --snapshot", snapshotFile, status.Revision, r.toRevision)
+	}
+	klog.Infof("replaying segments onto snapshot %s at revision %d", snapshotFile, status.Revision)
+
+	if err := checkAndCreateDir(r.outputDir); err != nil {
+		return fmt.Errorf("replay: checkAndCreateDir failed: %w", err)
+	}
+	dateString := time.Now().Format("2006-01-02_150405")
+	dbPath := filepath.Join(r.outputDir, "snapshot_"+dateString+".db")
+	partPath := dbPath + ".part"
+	defer os.RemoveAll(partPath)
+
+	if err := copySnapshotWithoutHash(snapshotFile, partPath); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	lastRevision, err := applySegments(partPath, filepath.Join(r.backupDir, segmentDirName), status.Revision, r)
+	if err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	if r.toRevision > 0 && lastRevision < r.toRevision {
+		return fmt.Errorf("replay: segments end at revision %d before requested revision %d", lastRevision, r.toRevision)
+	}
+	if err := appendSnapshotHash(partPath); err != nil {
+		return fmt.Errorf("replay: %w", err)
+	}
+	if err := os.Rename(partPath, dbPath); err != nil {
+		return fmt.Errorf("replay: could not rename %s to %s: %w", partPath, dbPath, err)
+	}
+	if _, err := fileCopy(resourcesArchive, filepath.Join(r.outputDir, filepath.Base(resourcesArchive))); err != nil {
+		return fmt.Errorf("replay: attempt to copy static pod resources %s failed: %w", resourcesArchive, err)
+	}
+	klog.Infof("replayed revisions %d to %d into %s", status.Revision, lastRevision, dbPath)
+	return nil
+}
+
+// applySegments applies all segment records after snapshotRevision to the backend at dbPath, one transaction per
+// revision, so that the resulting store revision matches the revision of the source cluster. It returns the last
+// applied revision. The active segment is replayed up to its last complete record, any other damage stops the replay
+// with an error naming the last applied revision.
+func applySegments(dbPath, segmentDir string, snapshotRevision int64, r *backupStreamReplayOptions) (int64, error) {
+	segments, err := listSegments(segmentDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list segments: %w", err)
+	}
+
+	lg, err := zap.NewProduction()
+	if err != nil {
+		return 0, err
+	}
+	be := backend.NewDefaultBackend(dbPath)
+	defer be.Close()
+	lessor := lease.NewLessor(lg, be, lease.LessorConfig{MinLeaseTTL: 1})
+	defer lessor.Stop()
+	kv := mvcc.NewStore(lg, be, lessor, nil, mvcc.StoreConfig{})
+	defer kv.Close()
+
+	lastRevision := snapshotRevision
+	var pending []segmentRecord
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := applyRevision(kv, lessor, pending); err != nil {
+			return err
+		}
+		lastRevision = pending[0].Revision
+		pending = pending[:0]
+		return nil
+	}
+
+	for _, segment := range segments {
+		if !segment.active && segment.lastRevision <= snapshotRevision {
+			continue
+		}
+		// the active segment may end within a record that is still being written, it is replayed up to its last
+		// complete record.
+		records, _, err := readSegment(segment.path, segment.active)
+		if err != nil {
+			return 0, fmt.Errorf("replay stopped after revision %d: %w", lastRevision, err)
+		}
+		for _, record := range records {
+			if record.Revision <= snapshotRevision {
+				continue
+			}
+			if len(pending) > 0 && record.Revision == pending[0].Revision {
+				pending = append(pending, record)
+				continue
+			}
+			if err := flush(); err != nil {
+				return 0, err
+			}
+			if r.reachedTarget(record) {
+				kv.Commit()
+				return lastRevision, nil
+			}
+			if record.Revision != lastRevision+1 {
+				return 0, fmt.Errorf("replay stopped after revision %d: segments are not contiguous: expected revision %d found %d in %s", lastRevision, lastRevision+1, record.Revision, segment.path)
+			}
+			pending = append(pending, record)
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	kv.Commit()
+	return lastRevision, nil
+}
+
+func applyRevision(kv mvcc.KV, lessor lease.Lessor, records []segmentRecord) error {
+	// leases must be granted before the write transaction is opened, both lock the backend batch transaction.
+	leaseIDs := make([]lease.LeaseID, len(records))
+	for i, record := range records {
+		leaseID := lease.LeaseID(record.Lease)
+		if record.Type == segmentRecordPut && leaseID != lease.NoLease && lessor.Lookup(leaseID) == nil {
+			if record.LeaseTTL > 0 {
+				if _, err := lessor.Grant(leaseID, record.LeaseTTL); err != nil {
+					return fmt.Errorf("failed to grant lease %x at revision %d: %w", record.Lease, record.Revision, err)
+				}
+			} else {
+				klog.Warningf("lease %x of key %q at revision %d is unknown, replaying without lease", record.Lease, record.Key, record.Revision)
+				leaseID = lease.NoLease
+			}
+		}
+		leaseIDs[i] = leaseID
+	}
+
+	txn := kv.Write(traceutil.TODO())
+	defer txn.End()
+	for i, record := range records {
+		switch record.Type {
+		case segmentRecordPut:
+			txn.Put(record.Key, record.Value, leaseIDs[i])
+		case segmentRecordDelete:
+			txn.DeleteRange(record.Key, nil)
+		default:
+			return fmt.Errorf("unknown record type %q at revision %d", record.Type, record.Revision)
+		}
+	}
+	return nil
+}
+
+// copySnapshotWithoutHash copies a snapshot received from the etcd snapshot API and verifies and strips the appended
+// sha256 digest so the copy can be opened as a backend.
+func copySnapshotWithoutHash(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	// a snapshot with an appended digest is not aligned to 512 bytes, see snapshot.Restore.
+	if info.Size()%512 != sha256.Size {
+		if _, err := io.Copy(out, in); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", src, err)
+		}
+		return out.Sync()
+	}
+
+	h := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(out, h), in, info.Size()-sha256.Size); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	digest := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(in, digest); err != nil {
+		return fmt.Errorf("failed to read sha256 checksum of %s: %w", src, err)
+	}
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return fmt.Errorf("snapshot %s sha256 checksum mismatch", src)
+	}
+	return out.Sync()
+}
+
+// appendSnapshotHash appends the sha256 digest of the db, matching the format of the etcd snapshot API so that the
+// result can be restored without skipping the hash check.
+func appendSnapshotHash(dbPath string) error {
+	f, err := os.OpenFile(dbPath, os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash %s: %w", dbPath, err)
+	}
+	if _, err := f.Write(h.Sum(nil)); err != nil {
+		return fmt.Errorf("failed to write hash to %s: %w", dbPath, err)
+	}
+	return f.Sync()
+}
@@ -0,0 +1,92 @@
+package backuprestore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectBackupPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, revision := range map[string]int64{
+		"2021-06-14_120000": 10,
+		"2021-06-15_120000": 20,
+		"2021-06-16_120000": 30,
+	} {
+		for _, file := range []string{"snapshot_" + name + ".db", "static_kuberesources_" + name + ".tar.gz", "backup_metadata_" + name + ".json"} {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), []byte(fmt.Sprintf(`{"revision": %d}`, revision)), 0600))
+		}
+	}
+	// backups of older versions have no revision in their metadata or no metadata at all
+	for _, name := range []string{"2021-06-15_180000", "2021-06-16_180000"} {
+		for _, file := range []string{"snapshot_" + name + ".db", "static_kuberesources_" + name + ".tar.gz"} {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), nil, 0600))
+		}
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "backup_metadata_2021-06-16_180000.json"), []byte(`{"snapshot": "snapshot_2021-06-16_180000.db"}`), 0600))
+	// the newest snapshot has no static pod resources
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "snapshot_2021-06-17_120000.db"), nil, 0600))
+
+	testCases := map[string]struct {
+		opts     backupStreamReplayOptions
+		wantDate string
+		wantErr  bool
+	}{
+		"latest complete backup": {
+			wantDate: "2021-06-16_120000",
+		},
+		"to revision": {
+			opts:     backupStreamReplayOptions{toRevision: 25},
+			wantDate: "2021-06-15_120000",
+		},
+		"to time": {
+			opts:     backupStreamReplayOptions{toTimestamp: time.Date(2021, 6, 15, 13, 0, 0, 0, time.Local)},
+			wantDate: "2021-06-15_120000",
+		},
+		"to time skips unknown revision": {
+			opts:     backupStreamReplayOptions{toTimestamp: time.Date(2021, 6, 15, 19, 0, 0, 0, time.Local)},
+			wantDate: "2021-06-15_120000",
+		},
+		"snapshot with unknown revision": {
+			opts:     backupStreamReplayOptions{snapshotFile: filepath.Join(dir, "snapshot_2021-06-16_180000.db")},
+			wantDate: "2021-06-16_180000",
+		},
+		"to time before all backups": {
+			opts:    backupStreamReplayOptions{toTimestamp: time.Date(2021, 6, 13, 0, 0, 0, 0, time.Local)},
+			wantErr: true,
+		},
+		"snapshot": {
+			opts:     backupStreamReplayOptions{snapshotFile: filepath.Join(dir, "snapshot_2021-06-14_120000.db")},
+			wantDate: "2021-06-14_120000",
+		},
+		"snapshot after the target": {
+			opts:    backupStreamReplayOptions{snapshotFile: filepath.Join(dir, "snapshot_2021-06-16_120000.db"), toRevision: 25},
+			wantErr: true,
+		},
+		"snapshot without static pod resources": {
+			opts:    backupStreamReplayOptions{snapshotFile: filepath.Join(dir, "snapshot_2021-06-17_120000.db")},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.opts.backupDir = dir
+			got, err := tc.opts.selectBackupPair()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, filepath.Join(dir, "snapshot_"+tc.wantDate+".db"), got.Snapshot)
+			require.Equal(t, filepath.Join(dir, "static_kuberesources_"+tc.wantDate+".tar.gz"), got.Resources)
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package backuprestore
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"go.etcd.io/etcd/clientv3/snapshot"
+	"io/ioutil"
 	"k8s.io/klog/v2"
 	"path/filepath"
 	"time"
@@ -37,7 +41,14 @@ func archiveLatestResources(configDir, backupFile string) error {
 }
 
 func backup(r *backupOptions) error {
-	cli, err := getEtcdClient(r.endpoints)
+	source, err := backupSource(r)
+	endpoints := backupEndpoints(source, err, r.endpoints)
+	if source != nil {
+		klog.Infof("backing up member %s (%s), leader: %v, applied index: %d, leader applied index: %d",
+			source.Name, source.Endpoint, source.IsLeader, source.RaftAppliedIndex, source.LeaderRaftAppliedIndex)
+	}
+
+	cli, err := getEtcdClient(endpoints)
 	if err != nil {
 		return fmt.Errorf("backup: failed to get etcd client: %w", err)
 	}
@@ -51,6 +62,7 @@ func backup(r *backupOptions) error {
 	dateString := time.Now().Format("2006-01-02_150405")
 	outputArchive := "static_kuberesources_" + dateString + ".tar.gz"
 	snapshotOutFile := "snapshot_" + dateString + ".db"
+	metadataOutFile := "backup_metadata_" + dateString + ".json"
 
 	// Save snapshot
 	if err := saveSnapshot(cli, filepath.Join(r.backupDir, snapshotOutFile)); err != nil {
@@ -62,5 +74,59 @@ func backup(r *backupOptions) error {
 		return fmt.Errorf("archiveLatestResources failed: %w", err)
 	}
 
+	// Check the integrity of the snapshot and record where it was taken from
+	status, err := snapshot.NewV3(nil).Status(filepath.Join(r.backupDir, snapshotOutFile))
+	if err != nil {
+		return fmt.Errorf("snapshot status failed: %w", err)
+	}
+	metadata := &backupMetadata{
+		Snapshot:           snapshotOutFile,
+		StaticPodResources: outputArchive,
+		Revision:           status.Revision,
+		Member:             source,
+	}
+	if err := writeBackupMetadata(filepath.Join(r.backupDir, metadataOutFile), metadata); err != nil {
+		return fmt.Errorf("writeBackupMetadata failed: %w", err)
+	}
+
 	return nil
 }
+
+// backupSource discovers the members of the cluster through the configured endpoints and selects the member to take
+// the snapshot from.
+func backupSource(r *backupOptions) (*backupSourceMember, error) {
+	cli, err := getEtcdClient(r.endpoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get etcd client: %w", err)
+	}
+	defer cli.Close()
+
+	return selectBackupMember(context.Background(), cli, r.maxAppliedIndexLag)
+}
+
+// backupEndpoints returns the endpoint of the selected member. A backup is still better than none, if no member could
+// be selected, e.g. every member has a NOSPACE alarm or member discovery failed, the configured endpoints are used.
+func backupEndpoints(source *backupSourceMember, selectErr error, configured []string) []string {
+	if selectErr != nil || source == nil {
+		klog.Warningf("failed to select backup member, falling back to the configured endpoints %v: %v", configured, selectErr)
+		return configured
+	}
+	return []string{source.Endpoint}
+}
+
+// backupMetadata is stored next to every backup.
+type backupMetadata struct {
+	Snapshot           string `json:"snapshot"`
+	StaticPodResources string `json:"staticPodResources"`
+	Revision           int64  `json:"revision"`
+	// Member is nil if the snapshot was taken through the configured endpoints because no member could be selected.
+	Member *backupSourceMember `json:"member,omitempty"`
+}
+
+func writeBackupMetadata(path string, metadata *backupMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
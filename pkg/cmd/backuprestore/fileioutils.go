@@ -86,3 +86,27 @@ func checkAndCreateDir(dirName string) error {
 	}
 	return nil
 }
+
+// copyDir recursively copies the regular files in src to dst.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if _, err := fileCopy(path, target); err != nil {
+			return fmt.Errorf("copyDir failed to copy %s: %w", path, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,330 @@
+package backuprestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.etcd.io/etcd/clientv3/snapshot"
+	"io"
+	"io/ioutil"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	backupDateFormat = "2006-01-02_150405"
+	// dirtySuffix is appended by cluster-backup.sh if a backup was forced while an operator was progressing.
+	dirtySuffix   = "__POSSIBLY_DIRTY__"
+	pruningSuffix = ".pruning"
+)
+
+var (
+	snapshotFileRegexp  = regexp.MustCompile(`^snapshot_(\d{4}-\d{2}-\d{2}_\d{6})(` + dirtySuffix + `)?\.db$`)
+	resourcesFileRegexp = regexp.MustCompile(`^static_kuberesources_(\d{4}-\d{2}-\d{2}_\d{6})(` + dirtySuffix + `)?\.tar\.gz$`)
+	metadataFileRegexp  = regexp.MustCompile(`^backup_metadata_(\d{4}-\d{2}-\d{2}_\d{6})(` + dirtySuffix + `)?\.json$`)
+)
+
+type backupInventoryOptions struct {
+	backupDirs []string
+	prune      bool
+	dryRun     bool
+	keepLast   int
+	keepDaily  int
+	keepWeekly int
+	// scanRevisions reads the revision of backups without metadata from their snapshot.
+	scanRevisions bool
+	errOut        io.Writer
+	out           io.Writer
+}
+
+func NewBackupInventoryCommand(out, errOut io.Writer) *cobra.Command {
+	inventoryOpts := &backupInventoryOptions{
+		out:    out,
+		errOut: errOut,
+	}
+	cmd := &cobra.Command{
+		Use:   "backup-inventory",
+		Short: "Lists the backups in the given directories and optionally prunes them by retention policy",
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+					}
+					fmt.Fprint(inventoryOpts.errOut, err.Error())
+				}
+			}
+
+			must(inventoryOpts.Validate)
+			must(inventoryOpts.Run)
+		},
+	}
+	inventoryOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (r *backupInventoryOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.Set("logtostderr", "true")
+	fs.StringSliceVar(&r.backupDirs, "backup-dirs", nil, "Comma separated list of backup directories, local or mounted remote storage")
+	fs.BoolVar(&r.prune, "prune", false, "Remove backups not retained by any of the --keep-* policies")
+	fs.BoolVar(&r.dryRun, "dry-run", false, "Only print the backups that would be pruned")
+	fs.IntVar(&r.keepLast, "keep-last", 0, "Keep the n most recent backups")
+	fs.IntVar(&r.keepDaily, "keep-daily", 0, "Keep the most recent backup of each of the last n days with backups")
+	fs.IntVar(&r.keepWeekly, "keep-weekly", 0, "Keep the most recent backup of each of the last n weeks with backups")
+	fs.BoolVar(&r.scanRevisions, "scan-revisions", false, "Read the revision of backups without metadata from their snapshot, this requires a full scan of each of those snapshots")
+}
+
+func (r *backupInventoryOptions) Validate() error {
+	if len(r.backupDirs) == 0 {
+		return errors.New("missing required flag: --backup-dirs")
+	}
+	if r.keepLast < 0 || r.keepDaily < 0 || r.keepWeekly < 0 {
+		return errors.New("--keep-last, --keep-daily and --keep-weekly must not be negative")
+	}
+	if r.prune && r.keepLast == 0 && r.keepDaily == 0 && r.keepWeekly == 0 {
+		return errors.New("--prune requires at least one of --keep-last, --keep-daily or --keep-weekly")
+	}
+	return nil
+}
+
+func (r *backupInventoryOptions) Run() error {
+	var all []*backupPair
+	for _, dir := range r.backupDirs {
+		pairs, err := listBackupPairs(dir)
+		if err != nil {
+			return fmt.Errorf("backup-inventory: %w", err)
+		}
+		if r.prune {
+			for _, pair := range prunableBackupPairs(pairs, r.keepLast, r.keepDaily, r.keepWeekly) {
+				if r.dryRun {
+					klog.Infof("would prune backup %s in %s", pair.Date.Format(backupDateFormat), pair.Dir)
+					continue
+				}
+				if err := pruneBackupPair(pair); err != nil {
+					return fmt.Errorf("backup-inventory: %w", err)
+				}
+				klog.Infof("pruned backup %s in %s", pair.Date.Format(backupDateFormat), pair.Dir)
+				pair.pruned = true
+			}
+		}
+		all = append(all, pairs...)
+	}
+	if r.scanRevisions {
+		for _, pair := range all {
+			if pair.Revision == 0 && !pair.pruned {
+				pair.Revision = scanSnapshotRevision(pair)
+			}
+		}
+	}
+	return printBackupPairs(r.out, all)
+}
+
+// backupPair is a snapshot together with the static pod resources archive taken at the same time. A backup can only be
+// restored if both files are present.
+type backupPair struct {
+	Dir       string
+	Date      time.Time
+	Dirty     bool
+	Snapshot  string
+	Resources string
+	Metadata  string
+	Size      int64
+	// Revision is read from the backup metadata if available, 0 if unknown.
+	Revision int64
+
+	pruned bool
+}
+
+func (p *backupPair) complete() bool {
+	return len(p.Snapshot) > 0 && len(p.Resources) > 0
+}
+
+// listBackupPairs groups the backup files in dir by timestamp and dirty flag, newest first.
+func listBackupPairs(dir string) ([]*backupPair, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := map[string]*backupPair{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		if match := snapshotFileRegexp.FindStringSubmatch(f.Name()); match != nil {
+			p, err := getBackupPair(pairs, dir, match)
+			if err != nil {
+				return nil, err
+			}
+			p.Snapshot = path
+			p.Size += f.Size()
+		}
+		if match := resourcesFileRegexp.FindStringSubmatch(f.Name()); match != nil {
+			p, err := getBackupPair(pairs, dir, match)
+			if err != nil {
+				return nil, err
+			}
+			p.Resources = path
+			p.Size += f.Size()
+		}
+		if match := metadataFileRegexp.FindStringSubmatch(f.Name()); match != nil {
+			p, err := getBackupPair(pairs, dir, match)
+			if err != nil {
+				return nil, err
+			}
+			p.Metadata = path
+		}
+	}
+
+	var result []*backupPair
+	for _, p := range pairs {
+		p.Revision = backupRevision(p)
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date.After(result[j].Date)
+	})
+	return result, nil
+}
+
+// getBackupPair returns the pair for the timestamp and dirty flag captured by match, creating it if required.
+func getBackupPair(pairs map[string]*backupPair, dir string, match []string) (*backupPair, error) {
+	key := match[1] + match[2]
+	if p, ok := pairs[key]; ok {
+		return p, nil
+	}
+	date, err := time.ParseInLocation(backupDateFormat, match[1], time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup timestamp %q: %w", match[0], err)
+	}
+	p := &backupPair{Dir: dir, Date: date, Dirty: len(match[2]) > 0}
+	pairs[key] = p
+	return p, nil
+}
+
+// backupRevision returns the snapshot revision recorded in the metadata, 0 if unknown. The snapshot itself is not read,
+// see scanSnapshotRevision.
+func backupRevision(p *backupPair) int64 {
+	if len(p.Metadata) == 0 {
+		return 0
+	}
+	data, err := ioutil.ReadFile(p.Metadata)
+	if err != nil {
+		klog.Warningf("failed to read %s: %v", p.Metadata, err)
+		return 0
+	}
+	metadata := &backupMetadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		klog.Warningf("failed to decode %s: %v", p.Metadata, err)
+		return 0
+	}
+	return metadata.Revision
+}
+
+// scanSnapshotRevision returns the revision read from the snapshot, 0 if unknown. It requires a full scan of the
+// snapshot, which is expensive for large snapshots.
+func scanSnapshotRevision(p *backupPair) int64 {
+	if len(p.Snapshot) == 0 {
+		return 0
+	}
+	status, err := snapshot.NewV3(nil).Status(p.Snapshot)
+	if err != nil {
+		klog.Warningf("failed to read revision of %s: %v", p.Snapshot, err)
+		return 0
+	}
+	return status.Revision
+}
+
+// prunableBackupPairs returns the complete backups that are not retained by any policy. pairs must be ordered newest
+// first. Incomplete backups are never pruned, they require manual inspection.
+func prunableBackupPairs(pairs []*backupPair, keepLast, keepDaily, keepWeekly int) []*backupPair {
+	keep := map[*backupPair]bool{}
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	last := 0
+	for _, p := range pairs {
+		if !p.complete() {
+			continue
+		}
+		if last < keepLast {
+			keep[p] = true
+			last++
+		}
+		day := p.Date.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[p] = true
+		}
+		year, week := p.Date.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%d", year, week)
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep[p] = true
+		}
+	}
+
+	var prunable []*backupPair
+	for _, p := range pairs {
+		if p.complete() && !keep[p] {
+			prunable = append(prunable, p)
+		}
+	}
+	return prunable
+}
+
+// pruneBackupPair removes all files of a backup. The files are first renamed so that a failure can not leave a snapshot
+// without its static pod resources or the other way round.
+func pruneBackupPair(p *backupPair) error {
+	files := []string{p.Snapshot, p.Resources}
+	if len(p.Metadata) > 0 {
+		files = append(files, p.Metadata)
+	}
+	var renamed []string
+	for _, file := range files {
+		if err := os.Rename(file, file+pruningSuffix); err != nil {
+			for _, r := range renamed {
+				if rerr := os.Rename(r+pruningSuffix, r); rerr != nil {
+					klog.Errorf("failed to restore %s: %v", r, rerr)
+				}
+			}
+			return fmt.Errorf("failed to prune backup %s in %s: %w", p.Date.Format(backupDateFormat), p.Dir, err)
+		}
+		renamed = append(renamed, file)
+	}
+	for _, file := range renamed {
+		if err := os.Remove(file + pruningSuffix); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", file+pruningSuffix, err)
+		}
+	}
+	return nil
+}
+
+func printBackupPairs(out io.Writer, pairs []*backupPair) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DIR\tDATE\tSIZE\tREVISION\tDIRTY\tSTATUS")
+	for _, p := range pairs {
+		status := "complete"
+		switch {
+		case p.pruned:
+			status = "pruned"
+		case len(p.Snapshot) == 0:
+			status = "missing snapshot"
+		case len(p.Resources) == 0:
+			status = "missing static pod resources"
+		}
+		revision := "unknown"
+		if p.Revision > 0 {
+			revision = fmt.Sprintf("%d", p.Revision)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%t\t%s\n", p.Dir, p.Date.Format(backupDateFormat), p.Size, revision, p.Dirty, status)
+	}
+	return w.Flush()
+}
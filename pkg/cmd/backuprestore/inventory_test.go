@@ -0,0 +1,101 @@
+package backuprestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrunableBackupPairs(t *testing.T) {
+	newPair := func(date string) *backupPair {
+		d, err := time.ParseInLocation(backupDateFormat, date, time.Local)
+		require.NoError(t, err)
+		return &backupPair{Date: d, Snapshot: "snapshot", Resources: "resources"}
+	}
+	// newest first, 2021-06-14 is a Monday
+	pairs := []*backupPair{
+		newPair("2021-06-16_120000"),
+		newPair("2021-06-16_060000"),
+		newPair("2021-06-15_120000"),
+		newPair("2021-06-14_120000"),
+		newPair("2021-06-13_120000"),
+		newPair("2021-06-06_120000"),
+	}
+	incomplete := newPair("2021-06-01_120000")
+	incomplete.Resources = ""
+	pairs = append(pairs, incomplete)
+
+	testCases := map[string]struct {
+		keepLast, keepDaily, keepWeekly int
+		wantPruned                      []int
+	}{
+		"keep last": {
+			keepLast:   2,
+			wantPruned: []int{2, 3, 4, 5},
+		},
+		"keep daily": {
+			keepDaily:  2,
+			wantPruned: []int{1, 3, 4, 5},
+		},
+		"keep weekly": {
+			keepWeekly: 3,
+			wantPruned: []int{1, 2, 3},
+		},
+		"combined policies": {
+			keepLast:   1,
+			keepDaily:  3,
+			keepWeekly: 2,
+			wantPruned: []int{1, 5},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var want []*backupPair
+			for _, i := range tc.wantPruned {
+				want = append(want, pairs[i])
+			}
+			require.Equal(t, want, prunableBackupPairs(pairs, tc.keepLast, tc.keepDaily, tc.keepWeekly))
+		})
+	}
+}
+
+func TestListAndPruneBackupPairs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{
+		"snapshot_2021-06-16_120000.db",
+		"static_kuberesources_2021-06-16_120000.tar.gz",
+		"backup_metadata_2021-06-16_120000.json",
+		"snapshot_2021-06-15_120000__POSSIBLY_DIRTY__.db",
+		"static_kuberesources_2021-06-15_120000__POSSIBLY_DIRTY__.tar.gz",
+		"static_kuberesources_2021-06-14_120000.tar.gz",
+		"unrelated.txt",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(`{"revision": 42}`), 0600))
+	}
+
+	pairs, err := listBackupPairs(dir)
+	require.NoError(t, err)
+	require.Len(t, pairs, 3)
+	require.True(t, pairs[0].complete())
+	require.Equal(t, int64(42), pairs[0].Revision)
+	require.True(t, pairs[1].complete())
+	require.True(t, pairs[1].Dirty)
+	// the snapshot is not scanned for backups without metadata
+	require.Zero(t, pairs[1].Revision)
+	require.False(t, pairs[2].complete())
+
+	require.NoError(t, pruneBackupPair(pairs[0]))
+	pairs, err = listBackupPairs(dir)
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+}
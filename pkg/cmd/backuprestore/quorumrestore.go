@@ -0,0 +1,396 @@
+package backuprestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ghodss/yaml"
+	operatorversionedclient "github.com/openshift/client-go/operator/clientset/versioned"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// quorumRestoreMarker tells the restore etcd pod to start from the existing data dir with --force-new-cluster.
+	quorumRestoreMarker        = "quorum-restore"
+	defaultQuorumRestoreWait   = 10 * time.Minute
+	quorumRestorePollInterval  = 5 * time.Second
+	quorumRestoreClientTimeout = 5 * time.Second
+	quorumRestoreNamespace     = "openshift-etcd"
+	// resetMemberPodPrefix names the pods moving the stale data dirs of the remaining members aside.
+	resetMemberPodPrefix = "etcd-quorum-restore-reset-"
+)
+
+var etcdPodContainers = sets.NewString(
+	"etcd",
+	"etcdctl",
+	"etcd-metrics",
+	"etcd-health-monitor",
+)
+
+type quorumRestoreOptions struct {
+	endpoints  []string
+	configDir  string
+	dataDir    string
+	kubeconfig string
+	timeout    time.Duration
+	errOut     io.Writer
+}
+
+func NewQuorumRestoreCommand(errOut io.Writer) *cobra.Command {
+	quorumRestoreOpts := &quorumRestoreOptions{
+		errOut: errOut,
+	}
+	cmd := &cobra.Command{
+		Use:   "quorum-restore",
+		Short: "Restores quorum by restarting the local member from its data directory as a single member cluster",
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+					}
+					fmt.Fprint(quorumRestoreOpts.errOut, err.Error())
+				}
+			}
+
+			must(quorumRestoreOpts.Validate)
+			must(quorumRestoreOpts.Run)
+		},
+	}
+	quorumRestoreOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (r *quorumRestoreOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.Set("logtostderr", "true")
+	fs.StringSliceVar(&r.endpoints, "endpoints", []string{"127.0.0.1:2379"}, "etcd endpoints of the local member")
+	fs.StringVar(&r.configDir, "config-dir", "/etc/kubernetes", "Path to the kubernetes config directory")
+	fs.StringVar(&r.dataDir, "data-dir", "/var/lib/etcd", "Path to the data directory")
+	fs.StringVar(&r.kubeconfig, "kubeconfig", "/etc/kubernetes/static-pod-resources/kube-apiserver-certs/secrets/node-kubeconfigs/localhost-recovery.kubeconfig", "Kubeconfig used to reset the remaining members once the local member is healthy")
+	fs.DurationVar(&r.timeout, "timeout", defaultQuorumRestoreWait, "Time to wait for the restored member to become healthy")
+}
+
+func (r *quorumRestoreOptions) Validate() error {
+	if r.timeout <= 0 {
+		return errors.New("--timeout must be greater than 0")
+	}
+	if len(r.kubeconfig) == 0 {
+		return errors.New("missing required flag: --kubeconfig")
+	}
+	return nil
+}
+
+func (r *quorumRestoreOptions) Run() error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		oscall := <-c
+		klog.Warningf("system call:%+v", oscall)
+		cancel()
+	}()
+
+	if err := quorumRestore(ctx, r); err != nil {
+		klog.Errorf("run: quorum restore failed: %v", err)
+	}
+
+	return nil
+}
+
+// quorumRestore replaces the etcd static pod with the restore etcd pod which starts the member from the local data
+// directory with --force-new-cluster. The member keeps its data and becomes the only member of a new cluster, no
+// snapshot is required. Once the member is healthy the stale data dirs of the remaining masters are moved aside and
+// etcd is redeployed, ClusterMemberController then adds the remaining masters.
+func quorumRestore(ctx context.Context, r *quorumRestoreOptions) error {
+	var (
+		assetDir           = filepath.Join(r.configDir, "assets")
+		manifestDir        = filepath.Join(r.configDir, "manifests")
+		manifestStoppedDir = filepath.Join(assetDir, "manifests-stopped")
+		restoreEtcdPodYaml = filepath.Join(r.configDir, "static-pod-resources", "etcd-certs", "configmaps", "restore-etcd-pod", "pod.yaml")
+		dataDirBackup      = r.dataDir + "-backup"
+		dataDirMember      = filepath.Join(r.dataDir, "member")
+	)
+
+	dataDirMemberExists, err := dirExists(dataDirMember)
+	if err != nil {
+		return fmt.Errorf("quorum-restore: Unexpected error checking dir: %s. Error: %w", dataDirMember, err)
+	}
+	if !dataDirMemberExists {
+		return fmt.Errorf("quorum-restore: data dir %s does not exist, a snapshot restore with cluster-restore is required", dataDirMember)
+	}
+	image, err := readPodImage(restoreEtcdPodYaml)
+	if err != nil {
+		return fmt.Errorf("quorum-restore: %w", err)
+	}
+
+	// Stop etcd
+	if err := os.MkdirAll(manifestStoppedDir, os.ModePerm); err != nil {
+		return fmt.Errorf("quorum-restore: failed to create %s: %w", manifestStoppedDir, err)
+	}
+	etcdPodYaml := filepath.Join(manifestDir, "etcd-pod.yaml")
+	if _, err := os.Stat(etcdPodYaml); err == nil {
+		if err := os.Rename(etcdPodYaml, filepath.Join(manifestStoppedDir, "etcd-pod.yaml")); err != nil {
+			return fmt.Errorf("quorum-restore: attempt to stop etcd-pod.yaml failed: %w", err)
+		}
+	}
+	if err := waitForStaticPodsToStop(ctx, etcdPodContainers); err != nil {
+		return fmt.Errorf("quorum-restore: waitForStaticPodsToStop failed %w", err)
+	}
+
+	// Keep a copy of the data dir, --force-new-cluster rewrites the membership in place
+	dataDirCopy := filepath.Join(dataDirBackup, "quorum-restore-"+time.Now().Format(backupDateFormat), "member")
+	klog.Infof("copying %s to %s", dataDirMember, dataDirCopy)
+	if err := copyDir(dataDirMember, dataDirCopy); err != nil {
+		return fmt.Errorf("quorum-restore: attempt to backup data-dir %s failed: %w", r.dataDir, err)
+	}
+
+	// Start the restore etcd pod in quorum restore mode
+	marker := filepath.Join(dataDirBackup, quorumRestoreMarker)
+	if err := ioutil.WriteFile(marker, nil, 0600); err != nil {
+		return fmt.Errorf("quorum-restore: failed to write marker: %w", err)
+	}
+	if _, err := fileCopy(restoreEtcdPodYaml, etcdPodYaml); err != nil {
+		return fmt.Errorf("quorum-restore: attempt to copy restore etcd %s failed: %w", restoreEtcdPodYaml, err)
+	}
+
+	memberName, err := waitForSingleMemberCluster(ctx, r.endpoints, r.timeout)
+	if err != nil {
+		return fmt.Errorf("quorum-restore: %w", err)
+	}
+	// A restart of the restore etcd pod must not force a new cluster once the remaining members are added
+	if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("quorum-restore: failed to remove marker: %w", err)
+	}
+	klog.Infof("etcd is running as a single member cluster, resetting the remaining members")
+
+	if err := resetRemainingMembers(ctx, r, memberName, image); err != nil {
+		return fmt.Errorf("quorum-restore: %w", err)
+	}
+	klog.Info("etcd is redeployed, ClusterMemberController adds the remaining masters back as members")
+	return nil
+}
+
+// readPodImage returns the image of the first container of the pod manifest.
+func readPodImage(podYaml string) (string, error) {
+	podBytes, err := ioutil.ReadFile(podYaml)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pod manifest: %w", err)
+	}
+	pod := &corev1.Pod{}
+	if err := yaml.Unmarshal(podBytes, pod); err != nil {
+		return "", fmt.Errorf("failed to decode pod manifest %s: %w", podYaml, err)
+	}
+	if len(pod.Spec.Containers) == 0 || len(pod.Spec.Containers[0].Image) == 0 {
+		return "", fmt.Errorf("pod manifest %s has no container image", podYaml)
+	}
+	return pod.Spec.Containers[0].Image, nil
+}
+
+// resetRemainingMembers moves the data dirs of the masters other than the restored member aside and forces a
+// redeployment of etcd. The stale members would otherwise keep their membership of the lost cluster, with an empty
+// data dir their etcd pods wait to be added by ClusterMemberController. The redeployment also replaces the restore
+// etcd pod of the restored member with the etcd static pod.
+func resetRemainingMembers(ctx context.Context, r *quorumRestoreOptions, memberName, image string) error {
+	config, err := clientcmd.BuildConfigFromFlags("", r.kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error loading kubeconfig: %w", err)
+	}
+	config.Timeout = quorumRestoreClientTimeout
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	operatorClient, err := operatorversionedclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	// kube-apiserver is available once it reconnected to the restored member
+	var nodes *corev1.NodeList
+	err = wait.PollImmediateUntil(quorumRestorePollInterval, func() (bool, error) {
+		nodes, err = kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/master"})
+		if err != nil {
+			klog.Infof("waiting for kube-apiserver: %v", err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return fmt.Errorf("failed to list master nodes: %w", err)
+	}
+
+	backupDir := "quorum-restore-" + time.Now().Format(backupDateFormat)
+	for _, node := range nodes.Items {
+		if node.Name == memberName {
+			continue
+		}
+		klog.Infof("resetting etcd member on node %s", node.Name)
+		if err := resetMember(ctx, kubeClient, newResetMemberPod(node.Name, image, backupDir)); err != nil {
+			return fmt.Errorf("failed to reset etcd member on node %s: %w", node.Name, err)
+		}
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"forceRedeploymentReason":"quorum-restore-%s"}}`, time.Now().Format(backupDateFormat))
+	if _, err := operatorClient.OperatorV1().Etcds().Patch(ctx, "cluster", types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to force etcd redeployment: %w", err)
+	}
+	return nil
+}
+
+// resetMember runs the reset pod to completion and deletes it.
+func resetMember(ctx context.Context, kubeClient kubernetes.Interface, pod *corev1.Pod) error {
+	pods := kubeClient.CoreV1().Pods(pod.Namespace)
+	// a pod left behind by a previous attempt has already finished
+	if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err == nil {
+		klog.Infof("deleted pod %s of a previous attempt", pod.Name)
+	}
+	err := wait.PollImmediateUntil(quorumRestorePollInterval, func() (bool, error) {
+		if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+			klog.Infof("waiting to create pod %s: %v", pod.Name, err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return fmt.Errorf("failed to create pod %s: %w", pod.Name, err)
+	}
+
+	var phase corev1.PodPhase
+	err = wait.PollImmediateUntil(quorumRestorePollInterval, func() (bool, error) {
+		current, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			klog.Infof("waiting for pod %s: %v", pod.Name, err)
+			return false, nil
+		}
+		phase = current.Status.Phase
+		return phase == corev1.PodSucceeded || phase == corev1.PodFailed, nil
+	}, ctx.Done())
+	if err != nil {
+		return fmt.Errorf("pod %s did not complete: %w", pod.Name, err)
+	}
+	if phase == corev1.PodFailed {
+		return fmt.Errorf("pod %s failed, see its logs", pod.Name)
+	}
+	return pods.Delete(ctx, pod.Name, metav1.DeleteOptions{})
+}
+
+// newResetMemberPod returns a pod stopping etcd on the node and moving its data dir to backupDir in the etcd backup
+// dir of the node. The etcd static pod is started again with the empty data dir.
+func newResetMemberPod(nodeName, image, backupDir string) *corev1.Pod {
+	privileged := true
+	script := fmt.Sprintf(`#!/bin/sh
+set -euo pipefail
+
+BACKUP_DIR=/var/lib/etcd-backup/%s
+mkdir -p ${BACKUP_DIR}
+if [ -f /etc/kubernetes/manifests/etcd-pod.yaml ]; then
+  mv /etc/kubernetes/manifests/etcd-pod.yaml ${BACKUP_DIR}/etcd-pod.yaml
+fi
+
+echo -n "Waiting for ports 2379, 2380 and 9978 to be released."
+while [ -n "$(ss -Htan '( sport = 2379 or sport = 2380 or sport = 9978 )')" ]; do
+  echo -n "."
+  sleep 1
+done
+
+if [ -d /var/lib/etcd/member ]; then
+  echo "moving /var/lib/etcd/member to ${BACKUP_DIR}"
+  mv /var/lib/etcd/member ${BACKUP_DIR}/member
+fi
+if [ -f ${BACKUP_DIR}/etcd-pod.yaml ]; then
+  mv ${BACKUP_DIR}/etcd-pod.yaml /etc/kubernetes/manifests/etcd-pod.yaml
+fi
+`, backupDir)
+
+	hostPathVolume := func(name, path string) corev1.Volume {
+		return corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: path}}}
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resetMemberPodPrefix + nodeName,
+			Namespace: quorumRestoreNamespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:          nodeName,
+			HostNetwork:       true,
+			RestartPolicy:     corev1.RestartPolicyNever,
+			PriorityClassName: "system-node-critical",
+			Tolerations:       []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:                     "reset",
+				Image:                    image,
+				ImagePullPolicy:          corev1.PullIfNotPresent,
+				TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+				Command:                  []string{"/bin/sh", "-c", script},
+				SecurityContext:          &corev1.SecurityContext{Privileged: &privileged},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "static-pod-dir", MountPath: "/etc/kubernetes/manifests"},
+					{Name: "data-dir", MountPath: "/var/lib/etcd/"},
+					{Name: "backup-dir", MountPath: "/var/lib/etcd-backup/"},
+				},
+			}},
+			Volumes: []corev1.Volume{
+				hostPathVolume("static-pod-dir", "/etc/kubernetes/manifests"),
+				hostPathVolume("data-dir", "/var/lib/etcd"),
+				hostPathVolume("backup-dir", "/var/lib/etcd-backup"),
+			},
+		},
+	}
+}
+
+// waitForSingleMemberCluster polls the local member until it is the only member of the cluster and serves
+// linearizable reads. It returns the name of the member.
+func waitForSingleMemberCluster(ctx context.Context, endpoints []string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var memberName string
+	err := wait.PollImmediateUntil(quorumRestorePollInterval, func() (bool, error) {
+		cli, err := getEtcdClient(endpoints)
+		if err != nil {
+			klog.Infof("waiting for etcd: %v", err)
+			return false, nil
+		}
+		defer cli.Close()
+
+		reqCtx, reqCancel := context.WithTimeout(ctx, quorumRestoreClientTimeout)
+		defer reqCancel()
+		members, err := cli.MemberList(reqCtx)
+		if err != nil {
+			klog.Infof("waiting for etcd: %v", err)
+			return false, nil
+		}
+		if len(members.Members) != 1 {
+			klog.Infof("waiting for etcd: cluster has %d members", len(members.Members))
+			return false, nil
+		}
+		if _, err := cli.Get(reqCtx, "health"); err != nil {
+			klog.Infof("waiting for etcd: %v", err)
+			return false, nil
+		}
+		memberName = members.Members[0].Name
+		klog.Infof("member %s is healthy", memberName)
+		return true, nil
+	}, ctx.Done())
+	return memberName, err
+}
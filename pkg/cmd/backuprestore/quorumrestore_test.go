@@ -0,0 +1,48 @@
+package backuprestore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestResetMember(t *testing.T) {
+	testCases := map[string]struct {
+		phase   corev1.PodPhase
+		wantErr bool
+	}{
+		"succeeded pod is deleted": {phase: corev1.PodSucceeded},
+		"failed pod":               {phase: corev1.PodFailed, wantErr: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			pod := newResetMemberPod("master-1", "etcd-image", "quorum-restore-2021-06-24_101008")
+			require.Equal(t, "master-1", pod.Spec.NodeName)
+			require.Equal(t, "etcd-image", pod.Spec.Containers[0].Image)
+
+			kubeClient := fake.NewSimpleClientset()
+			// the pod completes as soon as it is created
+			kubeClient.PrependReactor("get", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				completed := pod.DeepCopy()
+				completed.Status.Phase = tc.phase
+				return true, completed, nil
+			})
+
+			err := resetMember(context.TODO(), kubeClient, pod)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			pods, err := kubeClient.CoreV1().Pods(quorumRestoreNamespace).List(context.TODO(), metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Empty(t, pods.Items)
+		})
+	}
+}
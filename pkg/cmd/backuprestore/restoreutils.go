@@ -59,7 +59,7 @@ func restore(ctx context.Context, r *restoreOptions) error {
 	}
 
 	// Wait for static pods to stop
-	if err := waitForStaticPodsToStop(ctx); err != nil {
+	if err := waitForStaticPodsToStop(ctx, staticPodContainers); err != nil {
 		return fmt.Errorf("restore: waitForStaticPodsToStop failed %w", err)
 	}
 
@@ -123,7 +123,7 @@ func restore(ctx context.Context, r *restoreOptions) error {
 
 }
 
-func waitForStaticPodsToStop(ctx context.Context) error {
+func waitForStaticPodsToStop(ctx context.Context, containers sets.String) error {
 	runtimeClient, runtimeConn, err := getRuntimeCrioClient()
 	if err != nil {
 		return err
@@ -152,7 +152,7 @@ func waitForStaticPodsToStop(ctx context.Context) error {
 		}
 
 		for _, c := range containersList {
-			if staticPodContainers.Has(c.Metadata.Name) {
+			if containers.Has(c.Metadata.Name) {
 				allStaticPodsStopped = false
 				klog.Infof("Static Pod %s is still active", c.Metadata.Name)
 				break
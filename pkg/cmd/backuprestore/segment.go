@@ -0,0 +1,339 @@
+package backuprestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Segments are append only files of revision ordered records. Every record is framed as a big endian uint32 payload
+// length followed by the uint32 CRC-32C of the payload and the JSON encoded payload itself. The active segment is
+// written with a ".part" suffix and renamed to segment-<first revision>-<last revision>.log once it is rotated.
+
+const (
+	segmentPrefix     = "segment-"
+	segmentSuffix     = ".log"
+	segmentPartSuffix = ".part"
+	segmentHeaderSize = 8
+
+	// maxSegmentRecordSize guards against allocating for a corrupted length prefix. etcd rejects requests over 1.5MiB
+	// by default, a single key and value can not exceed that.
+	maxSegmentRecordSize = 8 * 1024 * 1024
+)
+
+var (
+	crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errSegmentChecksum = errors.New("segment record checksum mismatch")
+	errSegmentTorn     = errors.New("segment record is incomplete")
+)
+
+type segmentRecordType string
+
+const (
+	segmentRecordPut    segmentRecordType = "PUT"
+	segmentRecordDelete segmentRecordType = "DELETE"
+)
+
+// segmentRecord is a single mutation observed by the watch.
+type segmentRecord struct {
+	Revision int64             `json:"revision"`
+	Type     segmentRecordType `json:"type"`
+	Key      []byte            `json:"key"`
+	Value    []byte            `json:"value,omitempty"`
+	Lease    int64             `json:"lease,omitempty"`
+	// LeaseTTL is the granted TTL of Lease in seconds, it allows replay to recreate leases granted after the snapshot.
+	LeaseTTL int64 `json:"leaseTTL,omitempty"`
+	// Timestamp is the time the record was received from the watch, etcd does not record wall clock time for revisions.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type segmentWriter struct {
+	dir         string
+	maxSize     int64
+	maxDuration time.Duration
+
+	file          *os.File
+	buf           *bufio.Writer
+	size          int64
+	opened        time.Time
+	firstRevision int64
+	lastRevision  int64
+}
+
+func newSegmentWriter(dir string, maxSize int64, maxDuration time.Duration) *segmentWriter {
+	return &segmentWriter{
+		dir:         dir,
+		maxSize:     maxSize,
+		maxDuration: maxDuration,
+	}
+}
+
+// append writes the records of a single watch response. Segments are only rotated between watch responses so that the
+// events of a revision are never split across two segments.
+func (w *segmentWriter) append(records []segmentRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if w.file == nil {
+		if err := w.open(records[0].Revision); err != nil {
+			return err
+		}
+	}
+	for _, record := range records {
+		payload, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode record at revision %d: %w", record.Revision, err)
+		}
+		header := make([]byte, segmentHeaderSize)
+		binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, crc32cTable))
+		if _, err := w.buf.Write(header); err != nil {
+			return fmt.Errorf("failed to write segment %s: %w", w.file.Name(), err)
+		}
+		if _, err := w.buf.Write(payload); err != nil {
+			return fmt.Errorf("failed to write segment %s: %w", w.file.Name(), err)
+		}
+		w.size += int64(segmentHeaderSize + len(payload))
+		w.lastRevision = record.Revision
+	}
+	if err := w.sync(); err != nil {
+		return err
+	}
+	if w.size >= w.maxSize || time.Since(w.opened) >= w.maxDuration {
+		return w.rotate()
+	}
+	return nil
+}
+
+func (w *segmentWriter) open(firstRevision int64) error {
+	path := filepath.Join(w.dir, fmt.Sprintf("%s%016x%s", segmentPrefix, firstRevision, segmentPartSuffix))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open segment %s: %w", path, err)
+	}
+	w.file = f
+	w.buf = bufio.NewWriter(f)
+	w.size = 0
+	w.opened = time.Now()
+	w.firstRevision = firstRevision
+	w.lastRevision = firstRevision
+	return nil
+}
+
+func (w *segmentWriter) sync() error {
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to flush segment %s: %w", w.file.Name(), err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync segment %s: %w", w.file.Name(), err)
+	}
+	return nil
+}
+
+// rotate closes the active segment and moves it to its final name.
+func (w *segmentWriter) rotate() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.sync(); err != nil {
+		return err
+	}
+	partPath := w.file.Name()
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close segment %s: %w", partPath, err)
+	}
+	w.file = nil
+	w.buf = nil
+
+	finalPath := filepath.Join(w.dir, segmentName(w.firstRevision, w.lastRevision))
+	if err := os.Rename(partPath, finalPath); err != nil {
+		return fmt.Errorf("could not rename %s to %s: %w", partPath, finalPath, err)
+	}
+	klog.Infof("rotated segment %s", finalPath)
+	return nil
+}
+
+func (w *segmentWriter) close() error {
+	return w.rotate()
+}
+
+func segmentName(firstRevision, lastRevision int64) string {
+	return fmt.Sprintf("%s%016x-%016x%s", segmentPrefix, firstRevision, lastRevision, segmentSuffix)
+}
+
+// parseSegmentName returns the revision range encoded in a rotated segment file name.
+func parseSegmentName(name string) (int64, int64, error) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+		return 0, 0, fmt.Errorf("%s is not a segment file", name)
+	}
+	var first, last int64
+	revisions := strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix)
+	if _, err := fmt.Sscanf(revisions, "%016x-%016x", &first, &last); err != nil {
+		return 0, 0, fmt.Errorf("%s is not a segment file: %w", name, err)
+	}
+	return first, last, nil
+}
+
+// parseActiveSegmentName returns the first revision encoded in the file name of the active segment.
+func parseActiveSegmentName(name string) (int64, error) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentPartSuffix) {
+		return 0, fmt.Errorf("%s is not an active segment file", name)
+	}
+	var first int64
+	revision := strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentPartSuffix)
+	if _, err := fmt.Sscanf(revision, "%016x", &first); err != nil {
+		return 0, fmt.Errorf("%s is not an active segment file: %w", name, err)
+	}
+	return first, nil
+}
+
+// readSegmentRecord reads the next record and returns it with its framed size. io.EOF is returned at a clean record
+// boundary and errSegmentTorn if the file ends within a record.
+func readSegmentRecord(r io.Reader) (*segmentRecord, int64, error) {
+	header := make([]byte, segmentHeaderSize)
+	if n, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF && n == 0 {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, errSegmentTorn
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxSegmentRecordSize {
+		return nil, 0, fmt.Errorf("%w: record size %d exceeds %d", errSegmentChecksum, size, maxSegmentRecordSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, errSegmentTorn
+	}
+	if crc32.Checksum(payload, crc32cTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errSegmentChecksum
+	}
+	record := &segmentRecord{}
+	if err := json.Unmarshal(payload, record); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode segment record: %w", err)
+	}
+	return record, int64(segmentHeaderSize) + int64(size), nil
+}
+
+// readSegment returns all records of a segment file. If allowTorn is set a partially written trailing record is
+// dropped instead of failing, the offset of the last complete record is returned so the file can be truncated.
+func readSegment(path string, allowTorn bool) ([]segmentRecord, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var records []segmentRecord
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		record, n, err := readSegmentRecord(r)
+		if err == io.EOF {
+			return records, offset, nil
+		}
+		if errors.Is(err, errSegmentTorn) && allowTorn {
+			klog.Warningf("segment %s: dropping incomplete record after offset %d", path, offset)
+			return records, offset, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("segment %s at offset %d: %w", path, offset, err)
+		}
+		offset += n
+		records = append(records, *record)
+	}
+}
+
+// recoverPartialSegments finalizes active segments left behind by an unclean shutdown. Incomplete trailing records are
+// truncated and empty segments are removed.
+func recoverPartialSegments(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), segmentPrefix) || !strings.HasSuffix(f.Name(), segmentPartSuffix) {
+			continue
+		}
+		partPath := filepath.Join(dir, f.Name())
+		records, offset, err := readSegment(partPath, true)
+		if err != nil {
+			return fmt.Errorf("failed to recover segment: %w", err)
+		}
+		if len(records) == 0 {
+			if err := os.Remove(partPath); err != nil {
+				return fmt.Errorf("failed to remove empty segment %s: %w", partPath, err)
+			}
+			continue
+		}
+		if err := os.Truncate(partPath, offset); err != nil {
+			return fmt.Errorf("failed to truncate segment %s: %w", partPath, err)
+		}
+		finalPath := filepath.Join(dir, segmentName(records[0].Revision, records[len(records)-1].Revision))
+		if err := os.Rename(partPath, finalPath); err != nil {
+			return fmt.Errorf("could not rename %s to %s: %w", partPath, finalPath, err)
+		}
+		klog.Infof("recovered segment %s", finalPath)
+	}
+	return nil
+}
+
+type segmentFile struct {
+	path          string
+	firstRevision int64
+	// lastRevision is unknown for the active segment until it is read.
+	lastRevision int64
+	// active is set for the segment that is still written with the ".part" suffix.
+	active bool
+}
+
+// listSegments returns the rotated segments and the active segment in dir ordered by revision.
+func listSegments(dir string) ([]segmentFile, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segmentFile
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(f.Name(), segmentPartSuffix) {
+			first, err := parseActiveSegmentName(f.Name())
+			if err != nil {
+				continue
+			}
+			segments = append(segments, segmentFile{
+				path:          filepath.Join(dir, f.Name()),
+				firstRevision: first,
+				active:        true,
+			})
+			continue
+		}
+		first, last, err := parseSegmentName(f.Name())
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segmentFile{
+			path:          filepath.Join(dir, f.Name()),
+			firstRevision: first,
+			lastRevision:  last,
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].firstRevision < segments[j].firstRevision
+	})
+	return segments, nil
+}
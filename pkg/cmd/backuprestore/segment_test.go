@@ -0,0 +1,178 @@
+package backuprestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/lease"
+	"go.etcd.io/etcd/mvcc"
+	"go.etcd.io/etcd/mvcc/backend"
+)
+
+func TestSegmentRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "segment-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := newSegmentWriter(dir, 1, time.Hour)
+	require.NoError(t, w.append([]segmentRecord{
+		{Revision: 2, Type: segmentRecordPut, Key: []byte("a"), Value: []byte("1")},
+		{Revision: 2, Type: segmentRecordPut, Key: []byte("b"), Value: []byte("2")},
+	}))
+	require.NoError(t, w.append([]segmentRecord{{Revision: 3, Type: segmentRecordDelete, Key: []byte("a")}}))
+	require.NoError(t, w.close())
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Len(t, segments, 2)
+	require.Equal(t, int64(2), segments[0].firstRevision)
+	require.Equal(t, int64(2), segments[0].lastRevision)
+	require.Equal(t, int64(3), segments[1].firstRevision)
+
+	records, _, err := readSegment(segments[0].path, false)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, []byte("b"), records[1].Key)
+}
+
+func TestRecoverPartialSegments(t *testing.T) {
+	testCases := map[string]struct {
+		corrupt     func(t *testing.T, path string)
+		wantErr     bool
+		wantRecords int
+	}{
+		"torn trailing record": {
+			corrupt: func(t *testing.T, path string) {
+				f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+				require.NoError(t, err)
+				defer f.Close()
+				_, err = f.Write([]byte{0, 0, 1})
+				require.NoError(t, err)
+			},
+			wantRecords: 2,
+		},
+		"checksum mismatch": {
+			corrupt: func(t *testing.T, path string) {
+				data, err := ioutil.ReadFile(path)
+				require.NoError(t, err)
+				data[len(data)-2] ^= 0xff
+				require.NoError(t, ioutil.WriteFile(path, data, 0600))
+			},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "segment-test-")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			w := newSegmentWriter(dir, defaultSegmentMaxSize, time.Hour)
+			require.NoError(t, w.append([]segmentRecord{{Revision: 5, Type: segmentRecordPut, Key: []byte("a")}}))
+			require.NoError(t, w.append([]segmentRecord{{Revision: 6, Type: segmentRecordPut, Key: []byte("b")}}))
+			require.NoError(t, w.buf.Flush())
+			tc.corrupt(t, w.file.Name())
+
+			err = recoverPartialSegments(dir)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			segments, err := listSegments(dir)
+			require.NoError(t, err)
+			require.Len(t, segments, 1)
+			require.Equal(t, filepath.Join(dir, segmentName(5, 6)), segments[0].path)
+			records, _, err := readSegment(segments[0].path, false)
+			require.NoError(t, err)
+			require.Len(t, records, tc.wantRecords)
+		})
+	}
+}
+
+func TestApplySegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "segment-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// revision 2 and 3 are part of the snapshot
+	dbPath := filepath.Join(dir, "snapshot.db")
+	be := backend.NewDefaultBackend(dbPath)
+	kv := mvcc.NewStore(nil, be, &lease.FakeLessor{}, nil, mvcc.StoreConfig{})
+	kv.Put([]byte("a"), []byte("1"), lease.NoLease)
+	kv.Put([]byte("b"), []byte("1"), lease.NoLease)
+	kv.Commit()
+	kv.Close()
+	be.Close()
+
+	segmentDir := filepath.Join(dir, segmentDirName)
+	require.NoError(t, os.Mkdir(segmentDir, 0700))
+	w := newSegmentWriter(segmentDir, defaultSegmentMaxSize, time.Hour)
+	now := time.Now()
+	require.NoError(t, w.append([]segmentRecord{
+		{Revision: 3, Type: segmentRecordPut, Key: []byte("b"), Value: []byte("1"), Timestamp: now},
+		{Revision: 4, Type: segmentRecordPut, Key: []byte("c"), Value: []byte("1"), Timestamp: now},
+		{Revision: 4, Type: segmentRecordDelete, Key: []byte("a"), Timestamp: now},
+		{Revision: 5, Type: segmentRecordPut, Key: []byte("c"), Value: []byte("2"), Lease: 42, LeaseTTL: 60, Timestamp: now.Add(time.Minute)},
+	}))
+	require.NoError(t, w.close())
+
+	// the active segment ends within a record that is still being written
+	active := newSegmentWriter(segmentDir, defaultSegmentMaxSize, time.Hour)
+	require.NoError(t, active.append([]segmentRecord{
+		{Revision: 6, Type: segmentRecordPut, Key: []byte("d"), Value: []byte("1"), Timestamp: now.Add(2 * time.Minute)},
+	}))
+	_, err = active.file.Write([]byte{0, 0, 1})
+	require.NoError(t, err)
+	require.NoError(t, active.file.Close())
+
+	testCases := map[string]struct {
+		opts         backupStreamReplayOptions
+		wantRevision int64
+		wantKeys     map[string]string
+	}{
+		"all revisions": {
+			wantRevision: 6,
+			wantKeys:     map[string]string{"b": "1", "c": "2", "d": "1"},
+		},
+		"to revision": {
+			opts:         backupStreamReplayOptions{toRevision: 4},
+			wantRevision: 4,
+			wantKeys:     map[string]string{"b": "1", "c": "1"},
+		},
+		"to time": {
+			opts:         backupStreamReplayOptions{toTimestamp: now},
+			wantRevision: 4,
+			wantKeys:     map[string]string{"b": "1", "c": "1"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			replayPath := filepath.Join(dir, "replay.db")
+			_, err := fileCopy(dbPath, replayPath)
+			require.NoError(t, err)
+			defer os.Remove(replayPath)
+
+			gotRevision, err := applySegments(replayPath, segmentDir, 3, &tc.opts)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantRevision, gotRevision)
+
+			be := backend.NewDefaultBackend(replayPath)
+			defer be.Close()
+			kv := mvcc.NewStore(nil, be, &lease.FakeLessor{}, nil, mvcc.StoreConfig{})
+			defer kv.Close()
+			result, err := kv.Range([]byte("\x00"), []byte("\xff"), mvcc.RangeOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tc.wantRevision, result.Rev)
+			gotKeys := map[string]string{}
+			for _, kv := range result.KVs {
+				gotKeys[string(kv.Key)] = string(kv.Value)
+			}
+			require.Equal(t, tc.wantKeys, gotKeys)
+		})
+	}
+}
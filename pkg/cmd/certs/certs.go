@@ -0,0 +1,21 @@
+package certs
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCertsCommand(out, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Tools to troubleshoot the etcd certificates",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+			os.Exit(1)
+		},
+	}
+	cmd.AddCommand(NewInspectCommand(out, errOut))
+	return cmd
+}
@@ -0,0 +1,554 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	// defaultCertDir is where the installer pods copy the unrevisioned cert secrets and configmaps on every master.
+	defaultCertDir = "/etc/kubernetes/static-pod-resources/etcd-certs"
+
+	caBundleKey = "ca-bundle.crt"
+
+	// clientCertPrefix is the key prefix of the dedicated client certs in the etcd-all-certs secret. etcd verifies
+	// client certs with the etcd-serving-ca bundle.
+	clientCertPrefix = "etcd-client-"
+	clientCABundle   = "etcd-serving-ca"
+)
+
+// certKinds maps the key prefix of a node cert in the etcd-all-certs secret to the CA bundle configmap consumers
+// verify it with. The metrics prefix must come first as it shares the prefix of the serving certs.
+var certKinds = []struct {
+	prefix string
+	bundle string
+}{
+	{prefix: "etcd-serving-metrics-", bundle: "etcd-metrics-proxy-serving-ca"},
+	{prefix: "etcd-serving-", bundle: "etcd-serving-ca"},
+	{prefix: "etcd-peer-", bundle: "etcd-peer-client-ca"},
+}
+
+var (
+	bundleNames = []string{"etcd-serving-ca", "etcd-peer-client-ca", "etcd-metrics-proxy-serving-ca", "etcd-metrics-proxy-client-ca"}
+	signerNames = []string{"etcd-signer", "etcd-metric-signer"}
+	// clientSecretNamespaces are the namespaces of the etcd-client secrets of the consumers outside of the static pods.
+	clientSecretNamespaces = []string{
+		operatorclient.GlobalUserSpecifiedConfigNamespace,
+		operatorclient.OperatorNamespace,
+		operatorclient.TargetNamespace,
+	}
+)
+
+type inspectOpts struct {
+	kubeconfig string
+	certDir    string
+	output     string
+	out        io.Writer
+	errOut     io.Writer
+}
+
+func NewInspectCommand(out, errOut io.Writer) *cobra.Command {
+	inspectOpts := &inspectOpts{
+		out:    out,
+		errOut: errOut,
+	}
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Prints the etcd node certs, signers and CA bundles and verifies the node certs against the CA bundles",
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+					}
+					fmt.Fprint(inspectOpts.errOut, err.Error())
+				}
+			}
+			must(inspectOpts.Validate)
+			must(inspectOpts.Run)
+		},
+	}
+	inspectOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (o *inspectOpts) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Kubeconfig used to read the certs from the cluster. SANs are only checked against the node IPs in this mode.")
+	fs.StringVar(&o.certDir, "cert-dir", "", fmt.Sprintf("Directory the certs are read from if no kubeconfig is given, defaults to %s. Signers are not available on disk.", defaultCertDir))
+	fs.StringVarP(&o.output, "output", "o", outputText, "Output format, text or json.")
+}
+
+func (o *inspectOpts) Validate() error {
+	if len(o.kubeconfig) > 0 && len(o.certDir) > 0 {
+		return errors.New("--kubeconfig and --cert-dir are mutually exclusive")
+	}
+	if len(o.kubeconfig) == 0 && len(o.certDir) == 0 {
+		o.certDir = defaultCertDir
+	}
+	if o.output != outputText && o.output != outputJSON {
+		return fmt.Errorf("invalid --output %q, must be %s or %s", o.output, outputText, outputJSON)
+	}
+	return nil
+}
+
+func (o *inspectOpts) Run() error {
+	var sources *certSources
+	var err error
+	if len(o.kubeconfig) > 0 {
+		sources, err = readClusterCerts(context.TODO(), o.kubeconfig)
+	} else {
+		sources, err = readDirCerts(o.certDir)
+	}
+	if err != nil {
+		return err
+	}
+	report, err := inspectCerts(sources, time.Now())
+	if err != nil {
+		return err
+	}
+	if o.output == outputJSON {
+		encoder := json.NewEncoder(o.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return printReport(o.out, report)
+}
+
+// certSources are the certs read from the cluster or from disk.
+type certSources struct {
+	// allCerts is the content of the etcd-all-certs secret.
+	allCerts map[string][]byte
+	// bundles maps the name of a CA bundle configmap to its bundle.
+	bundles map[string][]byte
+	// signers maps the name of a signer secret to its cert.
+	signers map[string][]byte
+	// clientCerts maps namespace/name of the client cert secrets outside of etcd-all-certs to their cert.
+	clientCerts map[string][]byte
+	// nodeIPs maps master node names to their internal IPs, nil if unknown.
+	nodeIPs map[string][]string
+}
+
+// readDirCerts reads the certs from the layout written by the cert syncer, for example
+// <dir>/secrets/etcd-all-certs/etcd-peer-master-0.crt and <dir>/configmaps/etcd-serving-ca/ca-bundle.crt.
+func readDirCerts(dir string) (*certSources, error) {
+	sources := &certSources{
+		allCerts: map[string][]byte{},
+		bundles:  map[string][]byte{},
+	}
+	secretDir := filepath.Join(dir, "secrets", tlshelpers.EtcdAllCertsSecretName)
+	files, err := ioutil.ReadDir(secretDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(secretDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sources.allCerts[file.Name()] = data
+	}
+	for _, name := range bundleNames {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "configmaps", name, caBundleKey))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources.bundles[name] = data
+	}
+	return sources, nil
+}
+
+// readClusterCerts reads the certs, signers and CA bundles and the IPs of the master nodes using kubeconfig.
+func readClusterCerts(ctx context.Context, kubeconfig string) (*certSources, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := &certSources{
+		bundles:     map[string][]byte{},
+		signers:     map[string][]byte{},
+		clientCerts: map[string][]byte{},
+		nodeIPs:     map[string][]string{},
+	}
+	secret, err := client.CoreV1().Secrets(operatorclient.TargetNamespace).Get(ctx, tlshelpers.EtcdAllCertsSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	sources.allCerts = secret.Data
+	for _, name := range bundleNames {
+		configMap, err := client.CoreV1().ConfigMaps(operatorclient.TargetNamespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources.bundles[name] = []byte(configMap.Data[caBundleKey])
+	}
+	for _, name := range signerNames {
+		signer, err := client.CoreV1().Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources.signers[name] = signer.Data["tls.crt"]
+	}
+	for _, namespace := range clientSecretNamespaces {
+		clientSecret, err := client.CoreV1().Secrets(namespace).Get(ctx, "etcd-client", metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sources.clientCerts[namespace+"/"+clientSecret.Name] = clientSecret.Data["tls.crt"]
+	}
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/master"})
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes.Items {
+		ips, err := dnshelpers.GetInternalIPAddressesForNodeName(&nodes.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		sources.nodeIPs[nodes.Items[i].Name] = ips
+	}
+	return sources, nil
+}
+
+type certReport struct {
+	Signers []certInfo   `json:"signers"`
+	Bundles []bundleInfo `json:"bundles"`
+	Nodes   []nodeCerts  `json:"nodes"`
+	// Clients are the dedicated client certs of the etcd consumers.
+	Clients []certInfo `json:"clients"`
+}
+
+type bundleInfo struct {
+	Name  string     `json:"name"`
+	Certs []certInfo `json:"certs"`
+}
+
+type nodeCerts struct {
+	Node string `json:"node"`
+	// IPs are the internal IPs of the node, empty if unknown.
+	IPs   []string   `json:"ips,omitempty"`
+	Certs []certInfo `json:"certs"`
+}
+
+type certInfo struct {
+	Name        string    `json:"name"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	DNSNames    []string  `json:"dnsNames,omitempty"`
+	IPAddresses []string  `json:"ipAddresses,omitempty"`
+	NotBefore   time.Time `json:"notBefore"`
+	NotAfter    time.Time `json:"notAfter"`
+	KeyType     string    `json:"keyType"`
+	// Bundle is the CA bundle configmap the cert is verified against, only set for node certs.
+	Bundle string `json:"bundle,omitempty"`
+	// Verification is "ok" or the reason the chain could not be verified, only set for node certs.
+	Verification string   `json:"verification,omitempty"`
+	Problems     []string `json:"problems,omitempty"`
+}
+
+// inspectCerts builds the report of all certs in sources. Node certs are verified against the CA bundle
+// configmap of their kind and, if the node IPs are known, their IP SANs are compared with the node IPs.
+func inspectCerts(sources *certSources, now time.Time) (*certReport, error) {
+	report := &certReport{
+		Signers: []certInfo{},
+		Bundles: []bundleInfo{},
+		Nodes:   []nodeCerts{},
+		Clients: []certInfo{},
+	}
+
+	for _, name := range sortedKeys(sources.signers) {
+		certs, err := crypto.CertsFromPEM(sources.signers[name])
+		if err != nil {
+			return nil, fmt.Errorf("signer %s: %w", name, err)
+		}
+		report.Signers = append(report.Signers, newCertInfo(name, certs[0], now))
+	}
+
+	for _, name := range sortedKeys(sources.bundles) {
+		certs, err := crypto.CertsFromPEM(sources.bundles[name])
+		if err != nil {
+			return nil, fmt.Errorf("CA bundle %s: %w", name, err)
+		}
+		bundle := bundleInfo{Name: name, Certs: []certInfo{}}
+		for _, cert := range certs {
+			bundle.Certs = append(bundle.Certs, newCertInfo(name, cert, now))
+		}
+		report.Bundles = append(report.Bundles, bundle)
+	}
+
+	var nodeNames []string
+	byNode := map[string]*nodeCerts{}
+	for _, key := range sortedKeys(sources.allCerts) {
+		if !strings.HasSuffix(key, ".crt") {
+			continue
+		}
+		if strings.HasPrefix(key, clientCertPrefix) {
+			info, err := inspectClientCert(key, sources.allCerts[key], sources.bundles[clientCABundle], now)
+			if err != nil {
+				return nil, err
+			}
+			report.Clients = append(report.Clients, info)
+			continue
+		}
+		nodeName, bundleName := parseCertKey(strings.TrimSuffix(key, ".crt"))
+		if len(nodeName) == 0 {
+			klog.Warningf("skipping %s: unknown cert kind", key)
+			continue
+		}
+		certs, err := crypto.CertsFromPEM(sources.allCerts[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		info := newCertInfo(key, certs[0], now)
+		info.Bundle = bundleName
+		info.Verification = verifyChain(certs, sources.bundles[bundleName], now)
+		nodeIPs, nodeKnown := sources.nodeIPs[nodeName]
+		switch {
+		case sources.nodeIPs == nil:
+		case !nodeKnown:
+			info.Problems = append(info.Problems, fmt.Sprintf("node %s no longer exists", nodeName))
+		default:
+			info.Problems = append(info.Problems, sanProblems(certs[0], nodeIPs)...)
+		}
+
+		node, ok := byNode[nodeName]
+		if !ok {
+			node = &nodeCerts{Node: nodeName, IPs: nodeIPs, Certs: []certInfo{}}
+			byNode[nodeName] = node
+			nodeNames = append(nodeNames, nodeName)
+		}
+		node.Certs = append(node.Certs, info)
+	}
+	sort.Strings(nodeNames)
+	for _, name := range nodeNames {
+		report.Nodes = append(report.Nodes, *byNode[name])
+	}
+
+	for _, name := range sortedKeys(sources.clientCerts) {
+		info, err := inspectClientCert(name, sources.clientCerts[name], sources.bundles[clientCABundle], now)
+		if err != nil {
+			return nil, err
+		}
+		report.Clients = append(report.Clients, info)
+	}
+	return report, nil
+}
+
+func inspectClientCert(name string, data, bundle []byte, now time.Time) (certInfo, error) {
+	certs, err := crypto.CertsFromPEM(data)
+	if err != nil {
+		return certInfo{}, fmt.Errorf("%s: %w", name, err)
+	}
+	info := newCertInfo(name, certs[0], now)
+	info.Bundle = clientCABundle
+	info.Verification = verifyChain(certs, bundle, now)
+	return info, nil
+}
+
+// parseCertKey returns the node name and the CA bundle of a node cert named like the cert signer names the secrets,
+// for example etcd-serving-metrics-master-0. The node name is empty for unknown kinds.
+func parseCertKey(name string) (string, string) {
+	for _, kind := range certKinds {
+		if strings.HasPrefix(name, kind.prefix) {
+			return strings.TrimPrefix(name, kind.prefix), kind.bundle
+		}
+	}
+	return "", ""
+}
+
+func newCertInfo(name string, cert *x509.Certificate, now time.Time) certInfo {
+	info := certInfo{
+		Name:      name,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore.UTC(),
+		NotAfter:  cert.NotAfter.UTC(),
+		KeyType:   keyType(cert),
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	switch {
+	case now.Before(cert.NotBefore):
+		info.Problems = append(info.Problems, "not yet valid")
+	case now.After(cert.NotAfter):
+		info.Problems = append(info.Problems, "expired")
+	}
+	return info
+}
+
+func keyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// verifyChain verifies the leaf of certs against bundle, the remaining certs are used as intermediates.
+func verifyChain(certs []*x509.Certificate, bundle []byte, now time.Time) string {
+	if len(bundle) == 0 {
+		return "CA bundle not found"
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		return "CA bundle contains no certs"
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// sanProblems compares the IP SANs of cert with the current IPs of its node. Loopback SANs are ignored.
+func sanProblems(cert *x509.Certificate, nodeIPs []string) []string {
+	var problems []string
+	for _, nodeIP := range nodeIPs {
+		if !containsIP(cert.IPAddresses, net.ParseIP(nodeIP)) {
+			problems = append(problems, fmt.Sprintf("node IP %s is missing from the SANs", nodeIP))
+		}
+	}
+	var parsedNodeIPs []net.IP
+	for _, nodeIP := range nodeIPs {
+		parsedNodeIPs = append(parsedNodeIPs, net.ParseIP(nodeIP))
+	}
+	for _, ip := range cert.IPAddresses {
+		if ip.IsLoopback() || containsIP(parsedNodeIPs, ip) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("SAN %s is not an IP of the node", ip))
+	}
+	return problems
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, candidate := range ips {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func printReport(out io.Writer, report *certReport) error {
+	if len(report.Signers) == 0 {
+		fmt.Fprintln(out, "signers: not available")
+	}
+	for _, signer := range report.Signers {
+		fmt.Fprintf(out, "signer %s\n", signer.Name)
+		printCertInfo(out, signer)
+	}
+	for _, bundle := range report.Bundles {
+		fmt.Fprintf(out, "CA bundle %s\n", bundle.Name)
+		for i, cert := range bundle.Certs {
+			fmt.Fprintf(out, "  cert %d\n", i)
+			printCertInfo(out, cert)
+		}
+	}
+	for _, node := range report.Nodes {
+		if len(node.IPs) > 0 {
+			fmt.Fprintf(out, "node %s (%s)\n", node.Node, strings.Join(node.IPs, ", "))
+		} else {
+			fmt.Fprintf(out, "node %s\n", node.Node)
+		}
+		for _, cert := range node.Certs {
+			fmt.Fprintf(out, "  %s\n", cert.Name)
+			printCertInfo(out, cert)
+		}
+	}
+	for _, cert := range report.Clients {
+		fmt.Fprintf(out, "client %s\n", cert.Name)
+		printCertInfo(out, cert)
+	}
+	return nil
+}
+
+func printCertInfo(out io.Writer, cert certInfo) {
+	fmt.Fprintf(out, "    subject:   %s\n", cert.Subject)
+	fmt.Fprintf(out, "    issuer:    %s\n", cert.Issuer)
+	if sans := append(append([]string{}, cert.DNSNames...), cert.IPAddresses...); len(sans) > 0 {
+		fmt.Fprintf(out, "    SANs:      %s\n", strings.Join(sans, ", "))
+	}
+	fmt.Fprintf(out, "    validity:  %s - %s\n", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+	fmt.Fprintf(out, "    key:       %s\n", cert.KeyType)
+	if len(cert.Verification) > 0 {
+		fmt.Fprintf(out, "    verified:  %s (%s)\n", cert.Verification, cert.Bundle)
+	}
+	for _, problem := range cert.Problems {
+		fmt.Fprintf(out, "    problem:   %s\n", problem)
+	}
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
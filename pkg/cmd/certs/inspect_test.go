@@ -0,0 +1,126 @@
+package certs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inspect-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	newCA := func(name string) ([]byte, []byte) {
+		caConfig, err := crypto.MakeSelfSignedCAConfig(name, 365)
+		require.NoError(t, err)
+		cert, key, err := caConfig.GetPEMBytes()
+		require.NoError(t, err)
+		return cert, key
+	}
+	signerCert, signerKey := newCA("etcd-signer")
+	metricSignerCert, metricSignerKey := newCA("etcd-metric-signer")
+	otherCert, otherKey := newCA("other-signer")
+
+	writeFile := func(path string, data []byte) {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	}
+	writeCert := func(name string, cert, key *bytes.Buffer, err error) {
+		require.NoError(t, err)
+		writeFile(filepath.Join("secrets", tlshelpers.EtcdAllCertsSecretName, name+".crt"), cert.Bytes())
+		writeFile(filepath.Join("secrets", tlshelpers.EtcdAllCertsSecretName, name+".key"), key.Bytes())
+	}
+	ips := []string{"10.0.0.1"}
+	opts := tlshelpers.DefaultCertOptions()
+	cert, key, err := tlshelpers.CreatePeerCertKey(signerCert, signerKey, ips, opts)
+	writeCert(tlshelpers.GetPeerClientSecretNameForNode("master-0"), cert, key, err)
+	// the serving cert is issued by a signer that is not part of the bundle
+	cert, key, err = tlshelpers.CreateServerCertKey(otherCert, otherKey, ips, opts)
+	writeCert(tlshelpers.GetServingSecretNameForNode("master-0"), cert, key, err)
+	opts.KeyAlgorithm = tlshelpers.ECDSAP256KeyAlgorithm
+	cert, key, err = tlshelpers.CreateMetricCertKey(metricSignerCert, metricSignerKey, []string{"10.0.0.9"}, opts)
+	writeCert(tlshelpers.GetServingMetricsSecretNameForNode("master-0"), cert, key, err)
+
+	opts.KeyAlgorithm = tlshelpers.RSAKeyAlgorithm
+	cert, key, err = tlshelpers.CreateClientCertKey(signerCert, signerKey, "monitor", opts)
+	writeCert("etcd-client-monitor", cert, key, err)
+
+	writeFile(filepath.Join("configmaps", "etcd-peer-client-ca", caBundleKey), signerCert)
+	writeFile(filepath.Join("configmaps", "etcd-serving-ca", caBundleKey), signerCert)
+	writeFile(filepath.Join("configmaps", "etcd-metrics-proxy-serving-ca", caBundleKey), metricSignerCert)
+
+	var out bytes.Buffer
+	inspectOpts := &inspectOpts{certDir: dir, output: outputJSON, out: &out}
+	require.NoError(t, inspectOpts.Validate())
+	require.NoError(t, inspectOpts.Run())
+
+	report := &certReport{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), report))
+	require.Empty(t, report.Signers)
+	require.Len(t, report.Bundles, 3)
+	require.Len(t, report.Nodes, 1)
+	require.Equal(t, "master-0", report.Nodes[0].Node)
+
+	certs := report.Nodes[0].Certs
+	require.Len(t, certs, 3)
+	require.Equal(t, "etcd-peer-master-0.crt", certs[0].Name)
+	require.Equal(t, "ok", certs[0].Verification)
+	require.Equal(t, "RSA 2048", certs[0].KeyType)
+	require.Contains(t, certs[0].IPAddresses, "10.0.0.1")
+	require.Contains(t, certs[0].Issuer, "etcd-signer")
+
+	require.Equal(t, "etcd-serving-master-0.crt", certs[1].Name)
+	require.Equal(t, "etcd-serving-ca", certs[1].Bundle)
+	require.Contains(t, certs[1].Verification, "unknown authority")
+
+	require.Equal(t, "etcd-serving-metrics-master-0.crt", certs[2].Name)
+	require.Equal(t, "ok", certs[2].Verification)
+	require.Equal(t, "ECDSA P-256", certs[2].KeyType)
+	// SANs are only checked if the node IPs are known
+	require.Empty(t, certs[2].Problems)
+
+	require.Len(t, report.Clients, 1)
+	require.Equal(t, "etcd-client-monitor.crt", report.Clients[0].Name)
+	require.Equal(t, "CN=system:etcd-client:monitor,O=system:etcd-clients", report.Clients[0].Subject)
+	require.Equal(t, "ok", report.Clients[0].Verification)
+
+	// against the node IPs read from the cluster
+	sources, err := readDirCerts(dir)
+	require.NoError(t, err)
+	sources.nodeIPs = map[string][]string{"master-0": {"10.0.0.1"}}
+	report, err = inspectCerts(sources, time.Now())
+	require.NoError(t, err)
+	certs = report.Nodes[0].Certs
+	require.Empty(t, certs[0].Problems)
+	require.Equal(t, []string{
+		"node IP 10.0.0.1 is missing from the SANs",
+		"SAN 10.0.0.9 is not an IP of the node",
+	}, certs[2].Problems)
+
+	sources.nodeIPs = map[string][]string{}
+	report, err = inspectCerts(sources, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, []string{"node master-0 no longer exists"}, report.Nodes[0].Certs[0].Problems)
+
+	// expiry is evaluated at the given time
+	report, err = inspectCerts(sources, time.Now().AddDate(10, 0, 0))
+	require.NoError(t, err)
+	require.Contains(t, report.Nodes[0].Certs[0].Problems, "expired")
+	require.Contains(t, report.Nodes[0].Certs[0].Verification, "expired")
+
+	// text output
+	out.Reset()
+	require.NoError(t, printReport(&out, report))
+	require.Contains(t, out.String(), "node master-0\n  etcd-peer-master-0.crt\n")
+	require.Contains(t, out.String(), "key:       ECDSA P-256")
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+type analyzeOpts struct {
+	files  []string
+	output string
+	out    io.Writer
+	errOut io.Writer
+}
+
+func NewAnalyzeCommand(out, errOut io.Writer) *cobra.Command {
+	analyzeOpts := &analyzeOpts{
+		out:    out,
+		errOut: errOut,
+	}
+	cmd := &cobra.Command{
+		Use:   "analyze FILE...",
+		Short: "Merges the disruption intervals written by the monitors of all masters into a single timeline",
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+					}
+					fmt.Fprint(analyzeOpts.errOut, err.Error())
+				}
+			}
+			analyzeOpts.files = args
+			must(analyzeOpts.Validate)
+			must(analyzeOpts.Run)
+		},
+	}
+	analyzeOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (o *analyzeOpts) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.output, "output", "o", outputText, "Output format, text or json.")
+}
+
+func (o *analyzeOpts) Validate() error {
+	if len(o.files) == 0 {
+		return errors.New("at least one intervals file is required")
+	}
+	if o.output != outputText && o.output != outputJSON {
+		return fmt.Errorf("invalid --output %q, must be %s or %s", o.output, outputText, outputJSON)
+	}
+	return nil
+}
+
+func (o *analyzeOpts) Run() error {
+	var intervals []health.Interval
+	for _, file := range o.files {
+		fileIntervals, err := readIntervals(file)
+		if err != nil {
+			return err
+		}
+		intervals = append(intervals, fileIntervals...)
+	}
+	result := analyzeIntervals(intervals)
+	if o.output == outputJSON {
+		encoder := json.NewEncoder(o.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	return printAnalysis(o.out, result)
+}
+
+// analysis is the merged timeline of all intervals. Durations are rounded to milliseconds so results of different runs
+// can be diffed.
+type analysis struct {
+	Intervals []health.Interval `json:"intervals"`
+	// Unavailable is the time any check observed disruption of any target.
+	Unavailable string               `json:"unavailable"`
+	Targets     []targetAvailability `json:"targets"`
+}
+
+type targetAvailability struct {
+	Target string `json:"target"`
+	// Unavailable is the time any check observed disruption of the target, overlapping intervals are counted once.
+	Unavailable string `json:"unavailable"`
+	Intervals   int    `json:"intervals"`
+}
+
+// readIntervals reads the completed intervals of a file written by health.IntervalWriter.
+func readIntervals(path string) ([]health.Interval, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var intervals []health.Interval
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		interval := health.Interval{}
+		if err := json.Unmarshal(scanner.Bytes(), &interval); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if interval.End == nil {
+			continue
+		}
+		intervals = append(intervals, interval)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return intervals, nil
+}
+
+func analyzeIntervals(intervals []health.Interval) *analysis {
+	sort.SliceStable(intervals, func(i, j int) bool {
+		a, b := intervals[i], intervals[j]
+		switch {
+		case !a.Start.Equal(b.Start):
+			return a.Start.Before(b.Start)
+		case !a.End.Equal(*b.End):
+			return a.End.Before(*b.End)
+		case a.Pod != b.Pod:
+			return a.Pod < b.Pod
+		case a.Check != b.Check:
+			return a.Check < b.Check
+		default:
+			return a.Target < b.Target
+		}
+	})
+
+	byTarget := map[string][]health.Interval{}
+	for _, interval := range intervals {
+		byTarget[interval.Target] = append(byTarget[interval.Target], interval)
+	}
+	result := &analysis{
+		Intervals:   intervals,
+		Unavailable: formatDuration(unionDuration(intervals)),
+		Targets:     []targetAvailability{},
+	}
+	if result.Intervals == nil {
+		result.Intervals = []health.Interval{}
+	}
+	for target, targetIntervals := range byTarget {
+		result.Targets = append(result.Targets, targetAvailability{
+			Target:      target,
+			Unavailable: formatDuration(unionDuration(targetIntervals)),
+			Intervals:   len(targetIntervals),
+		})
+	}
+	sort.Slice(result.Targets, func(i, j int) bool {
+		return result.Targets[i].Target < result.Targets[j].Target
+	})
+	return result
+}
+
+// unionDuration returns the time covered by at least one interval. intervals must be sorted by start.
+func unionDuration(intervals []health.Interval) time.Duration {
+	var total time.Duration
+	var start, end time.Time
+	for _, interval := range intervals {
+		if interval.Start.After(end) {
+			total += end.Sub(start)
+			start, end = interval.Start, *interval.End
+			continue
+		}
+		if interval.End.After(end) {
+			end = *interval.End
+		}
+	}
+	return total + end.Sub(start)
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func printAnalysis(out io.Writer, result *analysis) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "START\tEND\tDURATION\tPOD\tCHECK\tTARGET\tREASON")
+	for _, interval := range result.Intervals {
+		reason := interval.Reason
+		if interval.Terminated {
+			reason = "monitor terminated: " + reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			interval.Start.UTC().Format(time.RFC3339Nano),
+			interval.End.UTC().Format(time.RFC3339Nano),
+			formatDuration(interval.Duration()),
+			interval.Pod,
+			interval.Check,
+			interval.Target,
+			reason,
+		)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "TARGET\tUNAVAILABLE\tINTERVALS")
+	for _, target := range result.Targets {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", target.Target, target.Unavailable, target.Intervals)
+	}
+	fmt.Fprintf(w, "total\t%s\t%d\n", result.Unavailable, len(result.Intervals))
+	return w.Flush()
+}
@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analyze-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	interval := func(pod string, check health.CheckName, target string, start, end int) health.Interval {
+		endTime := base.Add(time.Duration(end) * time.Second)
+		return health.Interval{Pod: pod, Check: check, Target: target, Start: base.Add(time.Duration(start) * time.Second), End: &endTime, Reason: "context deadline exceeded"}
+	}
+	writeIntervals := func(name string, intervals ...health.Interval) string {
+		var buf bytes.Buffer
+		for _, i := range intervals {
+			data, err := json.Marshal(i)
+			require.NoError(t, err)
+			buf.Write(append(data, '\n'))
+		}
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0600))
+		return path
+	}
+	master0 := writeIntervals("master-0.jsonl",
+		interval("etcd-master-0", health.QuorumReadSingleTarget, "https://10.0.0.1:2379", 10, 15),
+		interval("etcd-master-0", health.QuorumRead, "https://10.0.0.1:2379,https://10.0.0.2:2379", 30, 32),
+	)
+	master1 := writeIntervals("master-1.jsonl",
+		interval("etcd-master-1", health.QuorumReadSingleTarget, "https://10.0.0.1:2379", 12, 17),
+		interval("etcd-master-1", health.QuorumReadSingleTarget, "https://10.0.0.2:2379", 1, 2),
+	)
+
+	var out bytes.Buffer
+	opts := &analyzeOpts{files: []string{master0, master1}, output: outputJSON, out: &out}
+	require.NoError(t, opts.Validate())
+	require.NoError(t, opts.Run())
+
+	result := &analysis{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), result))
+	require.Len(t, result.Intervals, 4)
+	require.Equal(t, "etcd-master-1", result.Intervals[0].Pod)
+	require.Equal(t, health.QuorumRead, result.Intervals[3].Check)
+	// 1-2, 10-17 and 30-32
+	require.Equal(t, "10s", result.Unavailable)
+	require.Equal(t, []targetAvailability{
+		{Target: "https://10.0.0.1:2379", Unavailable: "7s", Intervals: 2},
+		{Target: "https://10.0.0.1:2379,https://10.0.0.2:2379", Unavailable: "2s", Intervals: 1},
+		{Target: "https://10.0.0.2:2379", Unavailable: "1s", Intervals: 1},
+	}, result.Targets)
+
+	out.Reset()
+	opts.output = outputText
+	require.NoError(t, opts.Run())
+	require.Contains(t, out.String(), "total")
+}
@@ -0,0 +1,178 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	eventComponent = "etcd-health-monitor"
+	// events are rate limited across all checks, a disruption of all members must not flood the apiserver.
+	eventRateLimitQPS   = 0.1
+	eventRateLimitBurst = 10
+	// the apiserver is likely unavailable during etcd disruption, requests must not block the monitor.
+	eventClientTimeout = 5 * time.Second
+	// eventSinkRetryInterval is the interval connecting the event sink is retried at.
+	eventSinkRetryInterval = 30 * time.Second
+
+	disruptionStartReason = "EtcdDisruptionDetected"
+	disruptionEndReason   = "EtcdDisruptionEnded"
+)
+
+// eventSink records disruption windows as events against the etcd pod. Events are sent asynchronously by the event
+// broadcaster, events that can not be sent while the apiserver is down are retried and eventually dropped.
+type eventSink struct {
+	lg      *zap.Logger
+	limiter flowcontrol.RateLimiter
+
+	lock sync.Mutex
+	// recorder is nil until the sink connected to the apiserver, events are dropped until then.
+	recorder record.EventRecorder
+	pod      *corev1.ObjectReference
+	// started holds the disruptions whose start event was sent. Their end event is not rate limited, otherwise a
+	// disruption could be left open.
+	started map[disruptionKey]struct{}
+}
+
+type disruptionKey struct {
+	check  health.CheckName
+	target string
+	start  int64
+}
+
+func keyOf(interval health.Interval) disruptionKey {
+	return disruptionKey{check: interval.Check, target: interval.Target, start: interval.Start.UnixNano()}
+}
+
+// newEventSink returns an event sink that connects using kubeconfig in the background. The kubeconfig or the apiserver
+// are likely unavailable when the monitor starts after a control plane outage, connecting is retried until ctx is
+// done. The returned stop func stops connecting, flushes and stops the broadcaster.
+func newEventSink(ctx context.Context, lg *zap.Logger, kubeconfig, namespace, podName string) (*eventSink, func()) {
+	sink := &eventSink{
+		lg:      lg,
+		limiter: flowcontrol.NewTokenBucketRateLimiter(eventRateLimitQPS, eventRateLimitBurst),
+		started: map[disruptionKey]struct{}{},
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	var broadcaster record.EventBroadcaster
+	go func() {
+		defer close(done)
+		wait.PollImmediateUntil(eventSinkRetryInterval, func() (bool, error) {
+			var err error
+			broadcaster, err = sink.connect(ctx, kubeconfig, namespace, podName)
+			if err != nil {
+				lg.Warn("disruption events are not sent yet, retrying", zap.Duration("interval", eventSinkRetryInterval), zap.Error(err))
+				return false, nil
+			}
+			lg.Info("disruption events are enabled", zap.String("pod", podName))
+			return true, nil
+		}, ctx.Done())
+	}()
+	stop := func() {
+		cancel()
+		<-done
+		if broadcaster != nil {
+			broadcaster.Shutdown()
+		}
+	}
+	return sink, stop
+}
+
+// connect looks up the pod the events are recorded against and starts sending events.
+func (s *eventSink) connect(ctx context.Context, kubeconfig, namespace, podName string) (record.EventBroadcaster, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("error loading kubeconfig: %w", err)
+	}
+	config.Timeout = eventClientTimeout
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the uid links the events to the pod, without it events are still recorded but not shown by describe.
+	lookupCtx, cancel := context.WithTimeout(ctx, eventClientTimeout)
+	defer cancel()
+	p, err := client.CoreV1().Pods(namespace).Get(lookupCtx, podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup pod %s: %w", podName, err)
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events(namespace)})
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.recorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventComponent})
+	s.pod = &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  namespace,
+		Name:       podName,
+		UID:        p.UID,
+	}
+	return broadcaster, nil
+}
+
+func (s *eventSink) DisruptionStart(interval health.Interval) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.accept(interval) {
+		return
+	}
+	s.started[keyOf(interval)] = struct{}{}
+	s.recorder.Eventf(s.pod, corev1.EventTypeWarning, disruptionStartReason, "%s check of %s detected disruption: %s",
+		interval.Check, interval.Target, interval.Reason)
+}
+
+func (s *eventSink) DisruptionEnd(interval health.Interval) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := keyOf(interval)
+	if _, ok := s.started[key]; ok {
+		delete(s.started, key)
+	} else if !s.accept(interval) {
+		return
+	}
+	if interval.Terminated {
+		s.recorder.Eventf(s.pod, corev1.EventTypeWarning, disruptionEndReason, "%s check of %s terminated after %s of disruption",
+			interval.Check, interval.Target, interval.Duration().Round(time.Millisecond))
+		return
+	}
+	s.recorder.Eventf(s.pod, corev1.EventTypeNormal, disruptionEndReason, "%s check of %s restored after %s of disruption",
+		interval.Check, interval.Target, interval.Duration().Round(time.Millisecond))
+}
+
+// accept indicates whether an event may be sent. It must be called with the lock held.
+func (s *eventSink) accept(interval health.Interval) bool {
+	if s.recorder == nil {
+		s.lg.Debug("event sink is not connected, dropping event",
+			zap.Any("check", interval.Check),
+			zap.String("target", interval.Target),
+		)
+		return false
+	}
+	if s.limiter.TryAccept() {
+		return true
+	}
+	s.lg.Debug("event rate limit exceeded, dropping event",
+		zap.Any("check", interval.Check),
+		zap.String("target", interval.Target),
+	)
+	return false
+}
@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+func newTestEventSink(recorder record.EventRecorder, burst int) *eventSink {
+	sink := &eventSink{
+		lg:      zap.NewNop(),
+		limiter: flowcontrol.NewTokenBucketRateLimiter(0.001, burst),
+		started: map[disruptionKey]struct{}{},
+	}
+	if recorder != nil {
+		sink.recorder = recorder
+		sink.pod = &corev1.ObjectReference{Kind: "Pod", Namespace: "openshift-etcd", Name: "etcd-master-0"}
+	}
+	return sink
+}
+
+func TestEventSink(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	sink := newTestEventSink(recorder, 1)
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(1500 * time.Millisecond)
+	interval := health.Interval{Check: health.QuorumRead, Target: "https://10.0.0.1:2379", Start: start, Reason: "context deadline exceeded"}
+
+	sink.DisruptionStart(interval)
+	interval.End = &end
+	sink.DisruptionEnd(interval)
+	// rate limited
+	sink.DisruptionStart(interval)
+
+	require.Len(t, recorder.Events, 2)
+	require.Equal(t, "Warning EtcdDisruptionDetected QuorumRead check of https://10.0.0.1:2379 detected disruption: context deadline exceeded", <-recorder.Events)
+	require.Equal(t, "Normal EtcdDisruptionEnded QuorumRead check of https://10.0.0.1:2379 restored after 1.5s of disruption", <-recorder.Events)
+}
+
+func TestEventSinkEndOfSentStartIsNotRateLimited(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	sink := newTestEventSink(recorder, 1)
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Second)
+	sent := health.Interval{Check: health.QuorumRead, Target: "https://10.0.0.1:2379", Start: start}
+	dropped := health.Interval{Check: health.QuorumRead, Target: "https://10.0.0.2:2379", Start: start}
+
+	sink.DisruptionStart(sent)
+	// rate limited
+	sink.DisruptionStart(dropped)
+	sent.End, dropped.End = &end, &end
+	// the end of a dropped start is rate limited, the end of a sent start is not
+	sink.DisruptionEnd(dropped)
+	sink.DisruptionEnd(sent)
+
+	require.Len(t, recorder.Events, 2)
+	require.Contains(t, <-recorder.Events, "EtcdDisruptionDetected QuorumRead check of https://10.0.0.1:2379")
+	require.Contains(t, <-recorder.Events, "EtcdDisruptionEnded QuorumRead check of https://10.0.0.1:2379")
+	require.Empty(t, sink.started)
+}
+
+func TestEventSinkNotConnected(t *testing.T) {
+	sink := newTestEventSink(nil, 2)
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	interval := health.Interval{Check: health.QuorumRead, Target: "https://10.0.0.1:2379", Start: start}
+
+	sink.DisruptionStart(interval)
+	interval.End = &start
+	sink.DisruptionEnd(interval)
+	require.Empty(t, sink.started)
+}
+
+func TestNewEventSinkRetries(t *testing.T) {
+	// the kubeconfig does not exist, the sink keeps retrying in the background until stopped
+	sink, stop := newEventSink(context.Background(), zap.NewNop(), "testdata/missing.kubeconfig", "openshift-etcd", "etcd-master-0")
+	sink.DisruptionStart(health.Interval{Check: health.QuorumRead, Target: "https://10.0.0.1:2379", Start: time.Now()})
+	stop()
+	require.Nil(t, sink.recorder)
+}
@@ -3,11 +3,15 @@ package health
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"go.etcd.io/etcd/clientv3"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/connectivity"
+	"k8s.io/apimachinery/pkg/util/uuid"
 )
 
 const (
@@ -15,10 +19,18 @@ const (
 	QuorumReadSingleTarget     CheckName = "QuorumReadSingleTarget"
 	GRPCReadySingleTarget      CheckName = "GRPCReadySingleTarget"
 	QuorumRead                 CheckName = "QuorumRead"
+	QuorumWrite                CheckName = "QuorumWrite"
 
 	DefaultSlowRequestDuration = 500 * time.Millisecond
 	DefaultContextDeadline     = 1 * time.Second
 	DefaultNamespaceKey        = "/kubernetes.io/namespaces/default"
+
+	// CanaryKeyPrefix is the only prefix written to by the monitor. Keys are attached to a lease of CanaryLeaseTTL
+	// seconds so they expire even if the delete fails.
+	CanaryKeyPrefix = "/etcd-health-monitor/canary/"
+	CanaryLeaseTTL  = 10
+	// canaryRevokeTimeout bounds revoking the canary lease after the check.
+	canaryRevokeTimeout = 1 * time.Second
 )
 
 type CheckFunc func(context.Context, *Check)
@@ -34,6 +46,8 @@ type CheckStatus struct {
 	disruption         bool
 	slowRequest        bool
 	check              CheckName
+	disruptionReason   string
+	disruptionLeader   string
 }
 
 type Check struct {
@@ -44,6 +58,12 @@ type Check struct {
 	targets []string
 	status  CheckStatus
 	Probe   CheckFunc
+	Options CheckOptions
+	// Sinks are notified about disruption windows in addition to the log.
+	Sinks []DisruptionSink
+
+	reportLock sync.Mutex
+	report     CheckReport
 }
 
 func NewCheck(lg *zap.Logger, client *clientv3.Client, targets []string) *Check {
@@ -51,10 +71,24 @@ func NewCheck(lg *zap.Logger, client *clientv3.Client, targets []string) *Check
 		client:  client,
 		lg:      lg,
 		targets: targets,
+		Options: DefaultCheckOptions(),
+	}
+}
+
+// Reconfigure replaces the client and targets of the check while keeping its disruption state. It must not be called
+// while the probe is running.
+func (c *Check) Reconfigure(client *clientv3.Client, targets []string) {
+	oldLabels := c.metricLabels()
+	c.reportLock.Lock()
+	defer c.reportLock.Unlock()
+	c.client = client
+	c.targets = targets
+	if newLabels := c.metricLabels(); newLabels["target"] != oldLabels["target"] {
+		deleteMetrics(oldLabels)
 	}
 }
 
-// Health checks will log requests over DefaultSlowRequestDuration with a timeout at DefaultContextDeadline.
+// Health checks will log requests over Options.SlowRequestDuration with a timeout at Options.ContextDeadline.
 
 // WithSerializedReadSingleTarget performs a serialized RangeRequest against a single target. This test is of lower
 // overall importance because linearized read/write are the default for kubernetes. But some requests will not fail
@@ -97,15 +131,29 @@ func WithQuorumRead() CheckFunc {
 	}
 }
 
+// WithQuorumWrite writes a canary key with a short lease, reads it back with a quorate RangeRequest and deletes it.
+// Read checks pass as long as a member can serve reads, this check ensures the cluster can also commit writes. Keys are
+// only ever written below CanaryKeyPrefix, one key per host and target so monitors on different nodes and the checks
+// of different targets do not interfere.
+func WithQuorumWrite() CheckFunc {
+	hostID := newCanaryHostID()
+	return func(ctx context.Context, c *Check) {
+		c.name = QuorumWrite
+		c.checkKVSWrite(ctx, canaryKeyFor(hostID+"-"+c.targets[0]))
+	}
+}
+
 func (c *Check) checkDialStatus(_ context.Context) {
 	c.ensureCheckStatus()
 	// Due to client retry failure will not be observed directly.
-	slowRequestTimer := time.AfterFunc(DefaultSlowRequestDuration, func() {
+	slowRequestTimer := time.AfterFunc(c.Options.SlowRequestDuration, func() {
 		c.logSlowRequest(fmt.Errorf("slow request"))
 	})
 	defer slowRequestTimer.Stop()
 
+	start := time.Now()
 	resp, err := c.client.Dial(c.targets[0])
+	c.observeDuration(start)
 	if err != nil {
 		c.logHealthFail(err)
 		return
@@ -122,15 +170,17 @@ func (c *Check) checkKVSRead(ctx context.Context, opts []clientv3.OpOption) {
 	c.ensureCheckStatus()
 
 	//Due to client retry failure will not be observed directly.
-	slowRequestTimer := time.AfterFunc(DefaultSlowRequestDuration, func() {
+	slowRequestTimer := time.AfterFunc(c.Options.SlowRequestDuration, func() {
 		c.logSlowRequest(fmt.Errorf("slow request"))
 	})
 	defer slowRequestTimer.Stop()
 
 	// Without a context timeout we will retry non mutating request indefinitely.
-	ctx, cancel := context.WithTimeout(ctx, DefaultContextDeadline)
+	ctx, cancel := context.WithTimeout(ctx, c.Options.ContextDeadline)
 	defer cancel()
+	start := time.Now()
 	resp, err := c.client.Get(ctx, DefaultNamespaceKey, opts...)
+	c.observeDuration(start)
 	if err != nil {
 		c.logHealthFail(err)
 		return
@@ -142,6 +192,78 @@ func (c *Check) checkKVSRead(ctx context.Context, opts []clientv3.OpOption) {
 	c.logHealthSuccess()
 }
 
+func (c *Check) checkKVSWrite(ctx context.Context, key string) {
+	c.ensureCheckStatus()
+	if !strings.HasPrefix(key, CanaryKeyPrefix) {
+		c.logHealthFail(fmt.Errorf("canary key %q is outside of prefix %s", key, CanaryKeyPrefix))
+		return
+	}
+
+	//Due to client retry failure will not be observed directly.
+	slowRequestTimer := time.AfterFunc(c.Options.SlowRequestDuration, func() {
+		c.logSlowRequest(fmt.Errorf("slow request"))
+	})
+	defer slowRequestTimer.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, c.Options.ContextDeadline)
+	defer cancel()
+	start := time.Now()
+	err := c.writeCanary(ctx, key, start.UTC().Format(time.RFC3339Nano))
+	c.observeDuration(start)
+	if err != nil {
+		c.logHealthFail(err)
+		return
+	}
+	c.logHealthSuccess()
+}
+
+// writeCanary puts, reads back and deletes the canary key.
+func (c *Check) writeCanary(ctx context.Context, key, value string) error {
+	lease, err := c.client.Grant(ctx, CanaryLeaseTTL)
+	if err != nil {
+		return fmt.Errorf("grant canary lease: %w", err)
+	}
+	// the lease expires on its own, revoke is only an optimization. ctx may already be done once the check failed, so
+	// revoke gets a context of its own.
+	defer func() {
+		revokeCtx, cancel := context.WithTimeout(context.Background(), canaryRevokeTimeout)
+		defer cancel()
+		if _, err := c.client.Revoke(revokeCtx, lease.ID); err != nil {
+			c.lg.Warn("failed to revoke canary lease", zap.String("check", string(c.name)), zap.Int64("lease", int64(lease.ID)), zap.Error(err))
+		}
+	}()
+
+	if _, err := c.client.Put(ctx, key, value, clientv3.WithLease(lease.ID)); err != nil {
+		return fmt.Errorf("put canary key: %w", err)
+	}
+	resp, err := c.client.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("get canary key: %w", err)
+	}
+	if len(resp.Kvs) == 0 || string(resp.Kvs[0].Value) != value {
+		return fmt.Errorf("canary key %s does not contain the written value", key)
+	}
+	if _, err := c.client.Delete(ctx, key); err != nil {
+		return fmt.Errorf("delete canary key: %w", err)
+	}
+	return nil
+}
+
+// newCanaryHostID identifies this host in canary keys.
+func newCanaryHostID() string {
+	id, err := os.Hostname()
+	if err != nil || len(id) == 0 {
+		id = string(uuid.NewUUID())
+	}
+	return id
+}
+
+func canaryKeyFor(id string) string {
+	// never allow the id to escape the prefix
+	id = strings.NewReplacer("/", "-", ".", "-").Replace(id)
+	return CanaryKeyPrefix + id
+}
+
 func (c *Check) ensureCheckStatus() {
 	// start counter before call to capture timeout duration, toggle slow request signal.
 	if !c.status.disruption {
@@ -0,0 +1,16 @@
+package health
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanaryKeyFor(t *testing.T) {
+	for _, id := range []string{"master-0", "master-0.example.com", "../../kubernetes.io/secrets", "/", "master-0-https://10.0.0.1:2379"} {
+		key := canaryKeyFor(id)
+		require.True(t, strings.HasPrefix(key, CanaryKeyPrefix), key)
+		require.NotContains(t, strings.TrimPrefix(key, CanaryKeyPrefix), "/", key)
+	}
+}
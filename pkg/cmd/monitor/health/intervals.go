@@ -0,0 +1,121 @@
+package health
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Interval is a disruption window of a check against its targets. Multi target checks join their targets with ",".
+type Interval struct {
+	Pod    string    `json:"pod,omitempty"`
+	Check  CheckName `json:"check"`
+	Target string    `json:"target"`
+	Start  time.Time `json:"start"`
+	// End is nil while the disruption is ongoing.
+	End    *time.Time `json:"end,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+	// Leader is the raft leader observed by the targets when the disruption started, if tracked.
+	Leader string `json:"leader,omitempty"`
+	// Terminated is set if the monitor stopped before service was restored, End is the time of termination.
+	Terminated bool `json:"terminated,omitempty"`
+}
+
+// Duration returns the length of a completed interval, 0 while the disruption is ongoing.
+func (i Interval) Duration() time.Duration {
+	if i.End == nil {
+		return 0
+	}
+	return i.End.Sub(i.Start)
+}
+
+// DisruptionSink is notified when a check starts and ends a disruption window.
+type DisruptionSink interface {
+	DisruptionStart(interval Interval)
+	DisruptionEnd(interval Interval)
+}
+
+// IntervalWriter is a DisruptionSink appending completed intervals to a file, one JSON record per line. Intervals
+// that cannot be written are logged.
+type IntervalWriter struct {
+	lg   *zap.Logger
+	lock sync.Mutex
+	pod  string
+	file *os.File
+}
+
+func NewIntervalWriter(lg *zap.Logger, path, pod string) (*IntervalWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &IntervalWriter{lg: lg, pod: pod, file: file}, nil
+}
+
+// DisruptionStart is a noop, intervals are written once complete.
+func (w *IntervalWriter) DisruptionStart(Interval) {}
+
+func (w *IntervalWriter) DisruptionEnd(interval Interval) {
+	interval.Pod = w.pod
+	data, err := json.Marshal(interval)
+	if err != nil {
+		w.logWriteError(interval, err)
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	// a single write per record so concurrent checks do not interleave lines
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		w.logWriteError(interval, err)
+	}
+}
+
+func (w *IntervalWriter) logWriteError(interval Interval, err error) {
+	w.lg.Error("failed to write disruption interval",
+		zap.String("file", w.file.Name()),
+		zap.Any("check", interval.Check),
+		zap.String("target", interval.Target),
+		zap.Time("start", interval.Start),
+		zap.Error(err),
+	)
+}
+
+func (w *IntervalWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.file.Close()
+}
+
+// interval returns the current disruption window of the check.
+func (c *Check) interval() Interval {
+	interval := Interval{
+		Check:  c.name,
+		Target: strings.Join(c.targets, ","),
+		Start:  c.status.logDisruptionStart,
+		Reason: c.status.disruptionReason,
+		Leader: c.status.disruptionLeader,
+	}
+	if !c.status.logDisruptionEnd.IsZero() {
+		end := c.status.logDisruptionEnd
+		interval.End = &end
+	}
+	return interval
+}
+
+func (c *Check) notifyDisruptionStart() {
+	for _, sink := range c.Sinks {
+		sink.DisruptionStart(c.interval())
+	}
+}
+
+func (c *Check) notifyDisruptionEnd(terminated bool) {
+	interval := c.interval()
+	interval.Terminated = terminated
+	for _, sink := range c.Sinks {
+		sink.DisruptionEnd(interval)
+	}
+}
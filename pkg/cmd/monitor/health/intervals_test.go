@@ -0,0 +1,66 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestIntervalWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intervals-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "intervals.jsonl")
+
+	writer, err := NewIntervalWriter(zap.NewNop(), path, "etcd-master-0")
+	require.NoError(t, err)
+	c := NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.1:2379"})
+	c.name = QuorumReadSingleTarget
+	c.Sinks = []DisruptionSink{writer}
+
+	c.ensureCheckStatus()
+	c.logHealthFail(errors.New("context deadline exceeded"))
+	c.ensureCheckStatus()
+	c.logHealthFail(errors.New("connection refused"))
+	c.logHealthSuccess()
+	require.NoError(t, writer.Close())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+	interval := Interval{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &interval))
+	require.Equal(t, "etcd-master-0", interval.Pod)
+	require.Equal(t, QuorumReadSingleTarget, interval.Check)
+	require.Equal(t, "https://10.0.0.1:2379", interval.Target)
+	require.Equal(t, "context deadline exceeded", interval.Reason)
+	require.NotNil(t, interval.End)
+	require.False(t, interval.End.Before(interval.Start))
+	require.False(t, interval.Terminated)
+}
+
+func TestIntervalWriterLogsWriteErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "intervals-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logs := &bytes.Buffer{}
+	lg := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(logs), zap.DebugLevel))
+	writer, err := NewIntervalWriter(lg, filepath.Join(dir, "intervals.jsonl"), "etcd-master-0")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	writer.DisruptionEnd(Interval{Check: QuorumReadSingleTarget, Target: "https://10.0.0.1:2379"})
+	require.Contains(t, logs.String(), "failed to write disruption interval")
+	require.Contains(t, logs.String(), "https://10.0.0.1:2379")
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const LeaderTracking CheckName = "LeaderTracking"
+
+// leaderState is the raft leader as last observed through a target.
+type leaderState struct {
+	leader  uint64
+	term    uint64
+	changed time.Time
+}
+
+// leaders records the leader observed per target so disruption logs can be attributed to elections.
+var leaders = &leaderTracker{states: map[string]leaderState{}, names: map[uint64]string{}}
+
+type leaderTracker struct {
+	sync.Mutex
+	states map[string]leaderState
+	// names maps member ids to names, refreshed from MemberList when an unknown leader is observed.
+	names map[uint64]string
+}
+
+// observe records the leader seen by target and returns the previous state.
+func (l *leaderTracker) observe(target string, leader, term uint64, now time.Time) (leaderState, bool) {
+	l.Lock()
+	defer l.Unlock()
+	previous, ok := l.states[target]
+	if ok && previous.leader == leader && previous.term == term {
+		return previous, ok
+	}
+	l.states[target] = leaderState{leader: leader, term: term, changed: now}
+	return previous, ok
+}
+
+func (l *leaderTracker) name(id uint64) string {
+	l.Lock()
+	defer l.Unlock()
+	if id == 0 {
+		return "none"
+	}
+	if name, ok := l.names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%x", id)
+}
+
+func (l *leaderTracker) knows(id uint64) bool {
+	l.Lock()
+	defer l.Unlock()
+	_, ok := l.names[id]
+	return ok || id == 0
+}
+
+func (l *leaderTracker) setName(id uint64, name string) {
+	l.Lock()
+	defer l.Unlock()
+	l.names[id] = name
+}
+
+// current returns the leader with the highest term observed through any of the targets.
+func (l *leaderTracker) current(targets []string) (leaderState, bool) {
+	l.Lock()
+	defer l.Unlock()
+	var current leaderState
+	var found bool
+	for _, target := range targets {
+		state, ok := l.states[target]
+		if ok && (!found || state.term > current.term) {
+			current, found = state, true
+		}
+	}
+	return current, found
+}
+
+// WithLeaderTracking polls the Status of a single target and logs every change of the raft leader or term as seen by
+// that member. Elections are the main cause of slow requests and short disruptions, the current leader is added to the
+// disruption logs of all checks of the target.
+func WithLeaderTracking() CheckFunc {
+	return func(ctx context.Context, c *Check) {
+		c.name = LeaderTracking
+		c.checkLeader(ctx)
+	}
+}
+
+func (c *Check) checkLeader(ctx context.Context) {
+	c.ensureCheckStatus()
+
+	slowRequestTimer := time.AfterFunc(c.Options.SlowRequestDuration, func() {
+		c.logSlowRequest(fmt.Errorf("slow request"))
+	})
+	defer slowRequestTimer.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, c.Options.ContextDeadline)
+	defer cancel()
+	start := time.Now()
+	resp, err := c.client.Status(ctx, c.targets[0])
+	c.observeDuration(start)
+	if err != nil {
+		c.logHealthFail(err)
+		return
+	}
+	if !leaders.knows(resp.Leader) {
+		if members, err := c.client.MemberList(ctx); err == nil {
+			for _, member := range members.Members {
+				leaders.setName(member.ID, member.Name)
+			}
+		}
+	}
+
+	previous, ok := leaders.observe(c.targets[0], resp.Leader, resp.RaftTerm, time.Now())
+	if ok && (previous.leader != resp.Leader || previous.term != resp.RaftTerm) {
+		leaderChanges.With(c.metricLabels()).Inc()
+		c.lg.Info("leader changed",
+			zap.Strings("addresses", c.targets),
+			zap.Any("check", c.name),
+			zap.String("previous-leader", leaders.name(previous.leader)),
+			zap.String("leader", leaders.name(resp.Leader)),
+			zap.Uint64("previous-raft-term", previous.term),
+			zap.Uint64("raft-term", resp.RaftTerm),
+			zap.Duration("previous-leader-duration", time.Since(previous.changed)),
+		)
+	}
+	if resp.Leader == 0 {
+		c.logHealthFail(fmt.Errorf("member has no leader"))
+		return
+	}
+	c.logHealthSuccess()
+}
+
+// leaderFields returns the log fields describing the current leader as seen by the targets of a check.
+func (c *Check) leaderFields() []zap.Field {
+	state, ok := leaders.current(c.targets)
+	if !ok {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("leader", leaders.name(state.leader)),
+		zap.Uint64("raft-term", state.term),
+		zap.Time("leader-since", state.changed),
+	}
+}
+
+// contextFields are added to disruption logs to correlate them with the network and raft state.
+func (c *Check) contextFields() []zap.Field {
+	return append(c.networkFields(), c.leaderFields()...)
+}
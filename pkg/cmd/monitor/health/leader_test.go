@@ -0,0 +1,52 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLeaderTracker(t *testing.T) {
+	tracker := &leaderTracker{states: map[string]leaderState{}, names: map[uint64]string{}}
+	tracker.setName(1, "etcd-1")
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	_, ok := tracker.observe("https://10.0.0.1:2379", 1, 2, now)
+	require.False(t, ok)
+	previous, ok := tracker.observe("https://10.0.0.1:2379", 1, 2, now.Add(time.Second))
+	require.True(t, ok)
+	require.Equal(t, leaderState{leader: 1, term: 2, changed: now}, previous)
+
+	// election observed by the second target first
+	tracker.observe("https://10.0.0.2:2379", 2, 3, now.Add(2*time.Second))
+	current, ok := tracker.current([]string{"https://10.0.0.1:2379", "https://10.0.0.2:2379"})
+	require.True(t, ok)
+	require.Equal(t, uint64(2), current.leader)
+	require.Equal(t, uint64(3), current.term)
+
+	require.Equal(t, "etcd-1", tracker.name(1))
+	require.Equal(t, "2", tracker.name(2))
+	require.Equal(t, "none", tracker.name(0))
+	require.True(t, tracker.knows(0))
+	require.False(t, tracker.knows(2))
+
+	_, ok = tracker.current([]string{"https://10.0.0.3:2379"})
+	require.False(t, ok)
+}
+
+func TestLeaderFields(t *testing.T) {
+	now := time.Now()
+	leaders.setName(10, "etcd-10")
+	leaders.observe("https://10.0.0.10:2379", 10, 5, now)
+
+	c := NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.10:2379"})
+	c.name = QuorumReadSingleTarget
+	require.Equal(t, []zap.Field{
+		zap.String("leader", "etcd-10"),
+		zap.Uint64("raft-term", 5),
+		zap.Time("leader-since", now),
+	}, c.leaderFields())
+	require.Nil(t, NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.11:2379"}).leaderFields())
+}
@@ -10,33 +10,47 @@ import (
 )
 
 func (c *Check) logHealthFail(err error) {
+	available.With(c.metricLabels()).Set(0)
+	c.reportResult(err)
 	if !c.isDisruption() {
 		c.status.disruption = true
+		c.status.disruptionReason = err.Error()
+		if state, ok := leaders.current(c.targets); ok {
+			c.status.disruptionLeader = leaders.name(state.leader)
+		}
+		disruptionStarts.With(c.metricLabels()).Inc()
+		c.notifyDisruptionStart()
 		c.lg.Info(
 			"service disruption detected",
-			zap.Strings("addresses", c.targets),
-			zap.Any("check", c.name),
-			zap.Bool("health", false),
-			zap.Time("start", c.status.logDisruptionStart),
-			zap.Error(err),
+			append([]zap.Field{
+				zap.Strings("addresses", c.targets),
+				zap.Any("check", c.name),
+				zap.Bool("health", false),
+				zap.Time("start", c.status.logDisruptionStart),
+				zap.Error(err),
+			}, c.contextFields()...)...,
 		)
 		return
 	}
 	//sanitize context error
 	if errors.Is(err, context.DeadlineExceeded) {
-		err = fmt.Errorf("client timeout exceeded: %s", DefaultContextDeadline)
+		err = fmt.Errorf("client timeout exceeded: %s", c.Options.ContextDeadline)
 	}
 	c.lg.Info("health check",
-		zap.Strings("addresses", c.targets),
-		zap.Any("check", c.name),
-		zap.Bool("health", false),
-		zap.Time("start", c.status.logDisruptionStart),
-		zap.Error(err),
+		append([]zap.Field{
+			zap.Strings("addresses", c.targets),
+			zap.Any("check", c.name),
+			zap.Bool("health", false),
+			zap.Time("start", c.status.logDisruptionStart),
+			zap.Error(err),
+		}, c.contextFields()...)...,
 	)
 	return
 }
 
 func (c *Check) logSlowRequest(err error) {
+	slowRequests.With(c.metricLabels()).Inc()
+	c.reportSlowRequest()
 	if !c.isDisruption() {
 		c.lg.Info(
 			"possible service disruption detected",
@@ -51,23 +65,31 @@ func (c *Check) logSlowRequest(err error) {
 }
 
 func (c *Check) logHealthSuccess() {
+	available.With(c.metricLabels()).Set(1)
+	c.reportResult(nil)
 	if c.isDisruption() {
+		disruptionEnds.With(c.metricLabels()).Inc()
 		c.status.logDisruptionEnd = time.Now()
 		duration := time.Since(c.status.logDisruptionStart)
 		c.lg.Info(
 			"service restored",
-			zap.Strings("addresses", c.targets),
-			zap.Any("check", c.name),
-			zap.Bool("health", true),
-			zap.Time("start", c.status.logDisruptionStart),
-			zap.Time("end", c.status.logDisruptionEnd),
-			zap.Duration("duration", duration),
+			append([]zap.Field{
+				zap.Strings("addresses", c.targets),
+				zap.Any("check", c.name),
+				zap.Bool("health", true),
+				zap.Time("start", c.status.logDisruptionStart),
+				zap.Time("end", c.status.logDisruptionEnd),
+				zap.Duration("duration", duration),
+			}, c.contextFields()...)...,
 		)
+		c.notifyDisruptionEnd(false)
 		// reset status
 		c.status.disruption = false
 		c.status.slowRequest = false
 		c.status.logDisruptionStart = time.Time{}
 		c.status.logDisruptionEnd = time.Time{}
+		c.status.disruptionReason = ""
+		c.status.disruptionLeader = ""
 		return
 	}
 	c.status.slowRequest = false
@@ -80,8 +102,6 @@ func (c *Check) logHealthSuccess() {
 }
 
 func (c *Check) LogTermination() {
-	// close etcd client
-	c.client.Close()
 	if c.isDisruption() || c.isSlowRequest() {
 		c.status.logDisruptionEnd = time.Now()
 		duration := time.Since(c.status.logDisruptionStart)
@@ -93,6 +113,9 @@ func (c *Check) LogTermination() {
 			zap.Time("end", c.status.logDisruptionEnd),
 			zap.Duration("duration", duration),
 		)
+		if c.isDisruption() {
+			c.notifyDisruptionEnd(true)
+		}
 		return
 	}
 	c.lg.Info("health probe terminated",
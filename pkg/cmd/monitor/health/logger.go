@@ -27,6 +27,8 @@ type LogLine struct {
 	DisruptionEnd   string `json:"end,omitempty"`
 	Duration        string `json:"duration,omitempty"`
 	Check           string `json:"check,omitempty"`
+	Network         string `json:"network,omitempty"`
+	Leader          string `json:"leader,omitempty"`
 }
 
 var (
@@ -0,0 +1,99 @@
+package health
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "etcd_health_monitor"
+
+// MetricsRegistry contains the metrics of all health checks. A dedicated registry is used so the monitor only exposes
+// its own metrics and not those of the etcd client or the go runtime of the operator.
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	checkDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "check_duration_seconds",
+			Help:      "Latency of health check requests by check and target.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"check", "target"},
+	)
+	slowRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "slow_requests_total",
+			Help:      "Number of health check requests exceeding the slow request duration.",
+		},
+		[]string{"check", "target"},
+	)
+	disruptionStarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "disruption_start_total",
+			Help:      "Number of service disruptions detected.",
+		},
+		[]string{"check", "target"},
+	)
+	disruptionEnds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "disruption_end_total",
+			Help:      "Number of service disruptions that ended with service restored.",
+		},
+		[]string{"check", "target"},
+	)
+	leaderChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "leader_changes_total",
+			Help:      "Number of raft leader or term changes observed through a target.",
+		},
+		[]string{"check", "target"},
+	)
+	available = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "available",
+			Help:      "Result of the last health check, 1 if the target is available and 0 if it is disrupted.",
+		},
+		[]string{"check", "target"},
+	)
+)
+
+// checkMetrics are the metrics labeled by check and target.
+var checkMetrics = []interface {
+	prometheus.Collector
+	Delete(prometheus.Labels) bool
+}{checkDuration, slowRequests, disruptionStarts, disruptionEnds, leaderChanges, available}
+
+func init() {
+	for _, metric := range checkMetrics {
+		MetricsRegistry.MustRegister(metric)
+	}
+}
+
+// metricLabels returns the check and target labels. Multi target checks join all targets.
+func (c *Check) metricLabels() prometheus.Labels {
+	return prometheus.Labels{"check": string(c.name), "target": strings.Join(c.targets, ",")}
+}
+
+// DeleteMetrics deletes the series of the check. It is called once the check is removed, otherwise its series would
+// be exported with their last values.
+func (c *Check) DeleteMetrics() {
+	deleteMetrics(c.metricLabels())
+}
+
+func deleteMetrics(labels prometheus.Labels) {
+	for _, metric := range checkMetrics {
+		metric.Delete(labels)
+	}
+}
+
+func (c *Check) observeDuration(start time.Time) {
+	checkDuration.With(c.metricLabels()).Observe(time.Since(start).Seconds())
+}
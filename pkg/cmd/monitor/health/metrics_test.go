@@ -0,0 +1,63 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDisruptionMetrics(t *testing.T) {
+	c := NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.1:2379", "https://10.0.0.2:2379"})
+	c.name = QuorumRead
+	labels := c.metricLabels()
+	require.Equal(t, "https://10.0.0.1:2379,https://10.0.0.2:2379", labels["target"])
+
+	c.ensureCheckStatus()
+	c.logSlowRequest(errors.New("slow request"))
+	c.logHealthFail(errors.New("context deadline exceeded"))
+	c.ensureCheckStatus()
+	c.logHealthFail(errors.New("context deadline exceeded"))
+	require.Equal(t, float64(0), testutil.ToFloat64(available.With(labels)))
+	require.Equal(t, float64(1), testutil.ToFloat64(slowRequests.With(labels)))
+	require.Equal(t, float64(1), testutil.ToFloat64(disruptionStarts.With(labels)))
+	require.Equal(t, float64(0), testutil.ToFloat64(disruptionEnds.With(labels)))
+
+	c.logHealthSuccess()
+	c.logHealthSuccess()
+	require.Equal(t, float64(1), testutil.ToFloat64(available.With(labels)))
+	require.Equal(t, float64(1), testutil.ToFloat64(disruptionStarts.With(labels)))
+	require.Equal(t, float64(1), testutil.ToFloat64(disruptionEnds.With(labels)))
+}
+
+func TestDeleteMetrics(t *testing.T) {
+	seriesCount := func() int {
+		return testutil.CollectAndCount(available) + testutil.CollectAndCount(slowRequests) + testutil.CollectAndCount(checkDuration)
+	}
+	before := seriesCount()
+
+	c := NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.3:2379"})
+	c.name = SerializedReadSingleTarget
+	c.ensureCheckStatus()
+	c.observeDuration(time.Now())
+	c.logSlowRequest(errors.New("slow request"))
+	c.logHealthFail(errors.New("context deadline exceeded"))
+	require.Equal(t, before+3, seriesCount())
+
+	// changed target labels drop the series of the old target
+	c.Reconfigure(nil, []string{"https://10.0.0.4:2379"})
+	require.Equal(t, before, seriesCount())
+
+	c.ensureCheckStatus()
+	c.observeDuration(time.Now())
+	c.logSlowRequest(errors.New("slow request"))
+	c.logHealthFail(errors.New("context deadline exceeded"))
+	require.Equal(t, before+3, seriesCount())
+
+	// a removed check, which was disrupted, must not export its last values
+	c.DeleteMetrics()
+	require.Equal(t, before, seriesCount())
+}
@@ -0,0 +1,280 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	TCPClientSingleTarget CheckName = "TCPClientSingleTarget"
+	TCPPeerSingleTarget   CheckName = "TCPPeerSingleTarget"
+	ICMPSingleTarget      CheckName = "ICMPSingleTarget"
+
+	DefaultPeerPort = "2380"
+
+	icmpProtocolIPv4 = 1
+	icmpProtocolIPv6 = 58
+)
+
+var errICMPNotPermitted = errors.New("icmp sockets are not permitted")
+
+// WithTCPClientSingleTarget opens a TCP connection to the client port of the target. Unlike the etcd checks it does
+// not depend on the etcd process, a failure points to the network or the host.
+func WithTCPClientSingleTarget() CheckFunc {
+	return func(ctx context.Context, c *Check) {
+		c.name = TCPClientSingleTarget
+		c.checkTCPConnect(ctx, "")
+	}
+}
+
+// WithTCPPeerSingleTarget opens a TCP connection to the peer port of the target host. Peer traffic carries raft
+// messages, a member unreachable on this port can not take part in quorum even if clients can reach it.
+func WithTCPPeerSingleTarget() CheckFunc {
+	return func(ctx context.Context, c *Check) {
+		c.name = TCPPeerSingleTarget
+		c.checkTCPConnect(ctx, DefaultPeerPort)
+	}
+}
+
+// icmpEcho is the state kept between probes of an ICMPSingleTarget check, one per check and so per target.
+type icmpEcho struct {
+	// disabled is set once the host is found not to permit unprivileged ICMP sockets.
+	disabled bool
+	seq      int
+}
+
+// nextSeq returns the sequence number of the next echo request of the check.
+func (e *icmpEcho) nextSeq() int {
+	e.seq = (e.seq + 1) & 0xffff
+	return e.seq
+}
+
+// WithICMPSingleTarget sends an ICMP echo request to the target host using an unprivileged ICMP socket. If the host
+// does not permit unprivileged ICMP sockets (net.ipv4.ping_group_range) the check logs this once and is skipped.
+func WithICMPSingleTarget() CheckFunc {
+	e := &icmpEcho{}
+	return func(ctx context.Context, c *Check) {
+		c.name = ICMPSingleTarget
+		c.checkICMPEcho(ctx, e)
+	}
+}
+
+func (c *Check) checkICMPEcho(ctx context.Context, e *icmpEcho) {
+	if e.disabled {
+		return
+	}
+	if err := c.checkICMP(ctx, e.nextSeq()); errors.Is(err, errICMPNotPermitted) {
+		e.disabled = true
+		c.lg.Info("health check disabled",
+			zap.Strings("addresses", c.targets),
+			zap.Any("check", c.name),
+			zap.Error(err),
+		)
+	}
+}
+
+func (c *Check) checkTCPConnect(ctx context.Context, port string) {
+	c.ensureCheckStatus()
+	host, targetPort, err := targetHostPort(c.targets[0])
+	if err != nil {
+		c.logHealthFail(err)
+		return
+	}
+	if len(port) == 0 {
+		port = targetPort
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Options.ContextDeadline)
+	defer cancel()
+	start := time.Now()
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	c.observeDuration(start)
+	reachability.set(host, c.name, err)
+	if err != nil {
+		c.logHealthFail(err)
+		return
+	}
+	conn.Close()
+	if time.Since(start) > c.Options.SlowRequestDuration {
+		c.logSlowRequest(fmt.Errorf("slow request"))
+	}
+	c.logHealthSuccess()
+}
+
+func (c *Check) checkICMP(ctx context.Context, seq int) error {
+	host, _, err := targetHostPort(c.targets[0])
+	if err != nil {
+		c.ensureCheckStatus()
+		c.logHealthFail(err)
+		return err
+	}
+	ip, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		c.ensureCheckStatus()
+		c.logHealthFail(err)
+		return err
+	}
+
+	network, listenAddress, protocol := "udp4", "0.0.0.0", icmpProtocolIPv4
+	var echoType, echoReplyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if ip.IP.To4() == nil {
+		network, listenAddress, protocol = "udp6", "::", icmpProtocolIPv6
+		echoType, echoReplyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	conn, err := icmp.ListenPacket(network, listenAddress)
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %v", errICMPNotPermitted, err)
+		}
+		c.ensureCheckStatus()
+		c.logHealthFail(err)
+		return err
+	}
+	defer conn.Close()
+
+	c.ensureCheckStatus()
+	start := time.Now()
+	err = ping(ctx, conn, &net.UDPAddr{IP: ip.IP, Zone: ip.Zone}, protocol, echoType, echoReplyType, seq, start.Add(c.Options.ContextDeadline))
+	c.observeDuration(start)
+	reachability.set(host, c.name, err)
+	if err != nil {
+		c.logHealthFail(err)
+		return err
+	}
+	if time.Since(start) > c.Options.SlowRequestDuration {
+		c.logSlowRequest(fmt.Errorf("slow request"))
+	}
+	c.logHealthSuccess()
+	return nil
+}
+
+// ping sends a single echo request and waits for the matching reply. The kernel assigns the echo id of unprivileged
+// ICMP sockets, replies are matched by sequence number only.
+func ping(ctx context.Context, conn *icmp.PacketConn, addr net.Addr, protocol int, echoType, echoReplyType icmp.Type, seq int, deadline time.Time) error {
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	msg := icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: []byte("etcd-health-monitor")},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.WriteTo(b, addr); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		reply, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && reply.Type == echoReplyType && echo.Seq == seq {
+			return nil
+		}
+	}
+}
+
+// targetHostPort returns the host and port of a target with or without URL scheme.
+func targetHostPort(target string) (string, string, error) {
+	hostPort := target
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid target %q: %w", target, err)
+		}
+		hostPort = u.Host
+	}
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid target %q: %w", target, err)
+	}
+	return host, port, nil
+}
+
+func isNetworkCheck(name CheckName) bool {
+	return name == TCPClientSingleTarget || name == TCPPeerSingleTarget || name == ICMPSingleTarget
+}
+
+// reachability records the latest network check results by host so etcd check logs can tell a network partition
+// from an etcd stall.
+var reachability = &networkReachability{results: map[string]map[CheckName]error{}}
+
+type networkReachability struct {
+	sync.Mutex
+	results map[string]map[CheckName]error
+}
+
+func (n *networkReachability) set(host string, check CheckName, err error) {
+	n.Lock()
+	defer n.Unlock()
+	if n.results[host] == nil {
+		n.results[host] = map[CheckName]error{}
+	}
+	n.results[host][check] = err
+}
+
+// unreachable returns the hosts of targets failing any network check. known is false if no network check has run
+// against any of the targets.
+func (n *networkReachability) unreachable(targets []string) (hosts []string, known bool) {
+	n.Lock()
+	defer n.Unlock()
+	for _, target := range targets {
+		host, _, err := targetHostPort(target)
+		if err != nil {
+			continue
+		}
+		results, ok := n.results[host]
+		if !ok {
+			continue
+		}
+		known = true
+		for _, err := range results {
+			if err != nil {
+				hosts = append(hosts, host)
+				break
+			}
+		}
+	}
+	sort.Strings(hosts)
+	return hosts, known
+}
+
+// networkFields returns the log fields describing the network reachability of the targets of an etcd check.
+func (c *Check) networkFields() []zap.Field {
+	if isNetworkCheck(c.name) {
+		return nil
+	}
+	hosts, known := reachability.unreachable(c.targets)
+	if !known {
+		return nil
+	}
+	if len(hosts) == 0 {
+		return []zap.Field{zap.String("network", "reachable")}
+	}
+	return []zap.Field{zap.String("network", "unreachable"), zap.Strings("unreachable-hosts", hosts)}
+}
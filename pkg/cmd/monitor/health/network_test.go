@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTargetHostPort(t *testing.T) {
+	testCases := map[string]struct {
+		target   string
+		wantHost string
+		wantPort string
+		wantErr  bool
+	}{
+		"url":       {target: "https://10.0.0.1:2379", wantHost: "10.0.0.1", wantPort: "2379"},
+		"ipv6 url":  {target: "https://[fd00::1]:2379", wantHost: "fd00::1", wantPort: "2379"},
+		"host port": {target: "localhost:2379", wantHost: "localhost", wantPort: "2379"},
+		"no port":   {target: "https://10.0.0.1", wantErr: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			host, port, err := targetHostPort(tc.target)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantHost, host)
+			require.Equal(t, tc.wantPort, port)
+		})
+	}
+}
+
+func TestTCPClientCheckReachability(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	target := "https://" + listener.Addr().String()
+
+	tcpCheck := NewCheck(zap.NewNop(), nil, []string{target})
+	tcpCheck.Probe = WithTCPClientSingleTarget()
+	readCheck := NewCheck(zap.NewNop(), nil, []string{target})
+	readCheck.name = QuorumReadSingleTarget
+
+	tcpCheck.Probe(context.Background(), tcpCheck)
+	require.False(t, tcpCheck.isDisruption())
+	require.Equal(t, []zap.Field{zap.String("network", "reachable")}, readCheck.networkFields())
+	require.Nil(t, tcpCheck.networkFields())
+
+	require.NoError(t, listener.Close())
+	tcpCheck.Probe(context.Background(), tcpCheck)
+	require.True(t, tcpCheck.isDisruption())
+	require.Equal(t, []zap.Field{zap.String("network", "unreachable"), zap.Strings("unreachable-hosts", []string{"127.0.0.1"})}, readCheck.networkFields())
+}
+
+func TestICMPEchoSeq(t *testing.T) {
+	first, second := &icmpEcho{}, &icmpEcho{}
+	require.Equal(t, 1, first.nextSeq())
+	require.Equal(t, 2, first.nextSeq())
+	// every check has its own sequence so replies are matched per target
+	require.Equal(t, 1, second.nextSeq())
+
+	wrapping := &icmpEcho{seq: 0xffff}
+	require.Equal(t, 0, wrapping.nextSeq())
+}
@@ -0,0 +1,137 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	intervalParam            = "interval"
+	slowRequestDurationParam = "slow-request-duration"
+	contextDeadlineParam     = "deadline"
+	progressTimeoutParam     = "progress-timeout"
+)
+
+// CheckOptions are the per check parameters. A zero Interval uses the probe interval of the monitor.
+type CheckOptions struct {
+	Interval            time.Duration
+	SlowRequestDuration time.Duration
+	ContextDeadline     time.Duration
+	// ProgressTimeout is only used by WatchProgress, zero derives the timeout from the etcd configuration.
+	ProgressTimeout time.Duration
+}
+
+// DefaultCheckOptions returns the options used if a check is selected without parameters.
+func DefaultCheckOptions() CheckOptions {
+	return CheckOptions{
+		SlowRequestDuration: DefaultSlowRequestDuration,
+		ContextDeadline:     DefaultContextDeadline,
+	}
+}
+
+type checkRegistration struct {
+	newCheckFunc func() CheckFunc
+	multiTarget  bool
+}
+
+// checkRegistry contains all checks selectable by name. Single target checks are run once per target with the client
+// pinned to that target, multi target checks are run once with a client balancing across all targets.
+var checkRegistry = map[CheckName]checkRegistration{
+	SerializedReadSingleTarget: {newCheckFunc: WithSerializedReadSingleTarget},
+	QuorumReadSingleTarget:     {newCheckFunc: WithQuorumReadSingleTarget},
+	GRPCReadySingleTarget:      {newCheckFunc: WithGRPCReadySingleTarget},
+	WatchProgress:              {newCheckFunc: WithWatchProgress},
+	TCPClientSingleTarget:      {newCheckFunc: WithTCPClientSingleTarget},
+	TCPPeerSingleTarget:        {newCheckFunc: WithTCPPeerSingleTarget},
+	ICMPSingleTarget:           {newCheckFunc: WithICMPSingleTarget},
+	LeaderTracking:             {newCheckFunc: WithLeaderTracking},
+	// QuorumWrite is single target so it also runs with the single localhost target of the static pod, writes are
+	// forwarded to the leader by the member either way.
+	QuorumWrite: {newCheckFunc: WithQuorumWrite},
+	QuorumRead:  {newCheckFunc: WithQuorumRead, multiTarget: true},
+}
+
+// CheckSpec is a check selected by name together with its options.
+type CheckSpec struct {
+	Name CheckName
+	// NewProbe returns a new probe, it is called once per check so probes keeping state between runs are not shared
+	// across targets.
+	NewProbe func() CheckFunc
+	Options  CheckOptions
+}
+
+// CheckNames returns the sorted names of all registered single or multi target checks.
+func CheckNames(multiTarget bool) []string {
+	var names []string
+	for name, registration := range checkRegistry {
+		if registration.multiTarget == multiTarget {
+			names = append(names, string(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseCheckSpecs parses check specs of the form Name[:param=value...], for example
+// QuorumRead:interval=5s:slow-request-duration=1s:deadline=2s. Supported params are interval, slow-request-duration,
+// deadline and progress-timeout.
+func ParseCheckSpecs(specs []string, multiTarget bool) ([]CheckSpec, error) {
+	var checkSpecs []CheckSpec
+	seen := map[CheckName]bool{}
+	for _, spec := range specs {
+		checkSpec, err := parseCheckSpec(spec, multiTarget)
+		if err != nil {
+			return nil, err
+		}
+		if seen[checkSpec.Name] {
+			return nil, fmt.Errorf("check %q selected more than once", checkSpec.Name)
+		}
+		seen[checkSpec.Name] = true
+		checkSpecs = append(checkSpecs, *checkSpec)
+	}
+	return checkSpecs, nil
+}
+
+func parseCheckSpec(spec string, multiTarget bool) (*CheckSpec, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	name := CheckName(parts[0])
+	registration, ok := checkRegistry[name]
+	if !ok || registration.multiTarget != multiTarget {
+		return nil, fmt.Errorf("unknown check %q, valid checks are %s", name, strings.Join(CheckNames(multiTarget), ", "))
+	}
+
+	options := DefaultCheckOptions()
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("check %q: invalid parameter %q, expected param=value", name, param)
+		}
+		value, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("check %q: invalid %s: %w", name, kv[0], err)
+		}
+		if value <= 0 {
+			return nil, fmt.Errorf("check %q: %s must be greater than 0", name, kv[0])
+		}
+		switch kv[0] {
+		case intervalParam:
+			options.Interval = value
+		case slowRequestDurationParam:
+			options.SlowRequestDuration = value
+		case contextDeadlineParam:
+			options.ContextDeadline = value
+		case progressTimeoutParam:
+			options.ProgressTimeout = value
+		default:
+			return nil, fmt.Errorf("check %q: unknown parameter %q", name, kv[0])
+		}
+	}
+
+	return &CheckSpec{
+		Name:     name,
+		NewProbe: registration.newCheckFunc,
+		Options:  options,
+	}, nil
+}
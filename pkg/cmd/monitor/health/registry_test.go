@@ -0,0 +1,85 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCheckSpecs(t *testing.T) {
+	testCases := map[string]struct {
+		specs       []string
+		multiTarget bool
+		want        []CheckName
+		wantOptions []CheckOptions
+		wantErr     bool
+	}{
+		"defaults": {
+			specs:       []string{"SerializedReadSingleTarget", "GRPCReadySingleTarget"},
+			want:        []CheckName{SerializedReadSingleTarget, GRPCReadySingleTarget},
+			wantOptions: []CheckOptions{DefaultCheckOptions(), DefaultCheckOptions()},
+		},
+		"params": {
+			specs:       []string{"QuorumRead:interval=5s:slow-request-duration=1s:deadline=2s"},
+			multiTarget: true,
+			want:        []CheckName{QuorumRead},
+			wantOptions: []CheckOptions{{Interval: 5 * time.Second, SlowRequestDuration: time.Second, ContextDeadline: 2 * time.Second}},
+		},
+		"partial params": {
+			specs:       []string{"QuorumReadSingleTarget:deadline=3s"},
+			want:        []CheckName{QuorumReadSingleTarget},
+			wantOptions: []CheckOptions{{SlowRequestDuration: DefaultSlowRequestDuration, ContextDeadline: 3 * time.Second}},
+		},
+		"quorum write is single target": {
+			specs:       []string{"QuorumWrite"},
+			want:        []CheckName{QuorumWrite},
+			wantOptions: []CheckOptions{DefaultCheckOptions()},
+		},
+		"quorum write as multi target": {
+			specs:       []string{"QuorumWrite"},
+			multiTarget: true,
+			wantErr:     true,
+		},
+		"unknown check": {
+			specs:   []string{"Foo"},
+			wantErr: true,
+		},
+		"multi target check as single target": {
+			specs:   []string{"QuorumRead"},
+			wantErr: true,
+		},
+		"duplicate check": {
+			specs:   []string{"GRPCReadySingleTarget", "GRPCReadySingleTarget:interval=1s"},
+			wantErr: true,
+		},
+		"unknown param": {
+			specs:   []string{"GRPCReadySingleTarget:timeout=1s"},
+			wantErr: true,
+		},
+		"invalid duration": {
+			specs:   []string{"GRPCReadySingleTarget:interval=1"},
+			wantErr: true,
+		},
+		"negative duration": {
+			specs:   []string{"GRPCReadySingleTarget:deadline=-1s"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCheckSpecs(tc.specs, tc.multiTarget)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got, len(tc.want))
+			for i := range got {
+				require.Equal(t, tc.want[i], got[i].Name)
+				require.Equal(t, tc.wantOptions[i], got[i].Options)
+				require.NotNil(t, got[i].NewProbe)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"time"
+)
+
+// CheckReport is the externally visible state of a check, served by the monitor on /status.
+type CheckReport struct {
+	Check   CheckName `json:"check"`
+	Targets []string  `json:"targets"`
+	Healthy bool      `json:"healthy"`
+	// LastCheck is the time of the last completed probe, nil if the check has not completed yet.
+	LastCheck *time.Time `json:"lastCheck,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	// DisruptionStart is set while the check is in a disruption window.
+	DisruptionStart *time.Time `json:"disruptionStart,omitempty"`
+	SlowRequests    int64      `json:"slowRequests"`
+}
+
+// Report returns a copy of the current state of the check. It is safe to call concurrently with the probe.
+func (c *Check) Report() CheckReport {
+	c.reportLock.Lock()
+	defer c.reportLock.Unlock()
+	report := c.report
+	report.Targets = append([]string(nil), c.targets...)
+	return report
+}
+
+func (c *Check) reportResult(err error) {
+	c.reportLock.Lock()
+	defer c.reportLock.Unlock()
+	now := time.Now()
+	c.report.Check = c.name
+	c.report.LastCheck = &now
+	c.report.Healthy = err == nil
+	c.report.LastError = ""
+	c.report.DisruptionStart = nil
+	if err != nil {
+		c.report.LastError = err.Error()
+		start := c.status.logDisruptionStart
+		c.report.DisruptionStart = &start
+	}
+}
+
+func (c *Check) reportSlowRequest() {
+	c.reportLock.Lock()
+	defer c.reportLock.Unlock()
+	c.report.SlowRequests++
+}
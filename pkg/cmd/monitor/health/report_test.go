@@ -0,0 +1,35 @@
+package health
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheckReport(t *testing.T) {
+	c := NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.1:2379"})
+	c.name = SerializedReadSingleTarget
+	require.Nil(t, c.Report().LastCheck)
+
+	c.ensureCheckStatus()
+	c.logSlowRequest(errors.New("slow request"))
+	c.logHealthFail(errors.New("context deadline exceeded"))
+	report := c.Report()
+	require.Equal(t, SerializedReadSingleTarget, report.Check)
+	require.Equal(t, []string{"https://10.0.0.1:2379"}, report.Targets)
+	require.False(t, report.Healthy)
+	require.NotNil(t, report.LastCheck)
+	require.Equal(t, "context deadline exceeded", report.LastError)
+	require.NotNil(t, report.DisruptionStart)
+	require.Equal(t, c.status.logDisruptionStart, *report.DisruptionStart)
+	require.Equal(t, int64(1), report.SlowRequests)
+
+	c.logHealthSuccess()
+	report = c.Report()
+	require.True(t, report.Healthy)
+	require.Empty(t, report.LastError)
+	require.Nil(t, report.DisruptionStart)
+	require.Equal(t, int64(1), report.SlowRequests)
+}
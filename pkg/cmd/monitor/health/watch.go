@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+)
+
+const (
+	WatchProgress CheckName = "WatchProgress"
+
+	// WatchProgressNotifyIntervalEnv is set on the etcd pod and configures how often etcd sends progress
+	// notifications to idle watchers.
+	WatchProgressNotifyIntervalEnv = "ETCD_EXPERIMENTAL_WATCH_PROGRESS_NOTIFY_INTERVAL"
+	// DefaultWatchProgressNotifyInterval is the etcd default if WatchProgressNotifyIntervalEnv is not set.
+	DefaultWatchProgressNotifyInterval = 10 * time.Minute
+	// watchProgressIntervals is the number of missed progress notifications before disruption is logged.
+	watchProgressIntervals = 3
+)
+
+// watchProgress is the state kept between probes of a WatchProgress check, one per check and so per target.
+type watchProgress struct {
+	// client the watch was created with, the watch is restarted if the check is reconfigured.
+	client       *clientv3.Client
+	watchCh      clientv3.WatchChan
+	cancel       context.CancelFunc
+	lastProgress time.Time
+	// failed is set if the watch was canceled and cleared once the restarted watch is created.
+	failed bool
+}
+
+// WithWatchProgress holds a long-lived watch against a single target and verifies that progress notifications or
+// events are delivered. A member can serve ranges while no longer delivering watch events, which breaks the caches of
+// kubernetes. The watch requires a leader so it is canceled if the member loses quorum. If no progress is observed
+// within Options.ProgressTimeout, or watchProgressIntervals times WatchProgressNotifyIntervalEnv if unset, disruption
+// is logged.
+func WithWatchProgress() CheckFunc {
+	w := &watchProgress{}
+	return func(ctx context.Context, c *Check) {
+		c.name = WatchProgress
+		c.checkWatchProgress(ctx, w)
+	}
+}
+
+func (c *Check) checkWatchProgress(ctx context.Context, w *watchProgress) {
+	c.ensureCheckStatus()
+
+	if w.watchCh != nil && w.client != c.client {
+		w.cancel()
+		w.watchCh = nil
+	}
+	if w.watchCh == nil {
+		if w.lastProgress.IsZero() {
+			w.lastProgress = time.Now()
+		}
+		watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+		w.cancel = cancel
+		w.client = c.client
+		w.watchCh = c.client.Watch(watchCtx, DefaultNamespaceKey, clientv3.WithProgressNotify(), clientv3.WithCreatedNotify())
+	}
+
+	var watchErr error
+	for drained := false; !drained && watchErr == nil; {
+		select {
+		case resp, ok := <-w.watchCh:
+			switch {
+			case !ok:
+				watchErr = fmt.Errorf("watch closed")
+			case resp.Err() != nil:
+				watchErr = resp.Err()
+			case resp.Created:
+				// the watch is established, but creating it is no progress of the member
+				w.failed = false
+			default:
+				w.lastProgress = time.Now()
+				w.failed = false
+			}
+		default:
+			drained = true
+		}
+	}
+	if watchErr != nil && ctx.Err() != nil {
+		// the monitor is shutting down
+		return
+	}
+	if watchErr != nil {
+		// restart the watch on the next probe
+		w.cancel()
+		w.watchCh = nil
+		w.failed = true
+		c.failWatchProgress(w, fmt.Errorf("watch: %w", watchErr))
+		return
+	}
+	if w.failed {
+		c.failWatchProgress(w, fmt.Errorf("waiting for restarted watch to be created"))
+		return
+	}
+
+	timeout := c.Options.ProgressTimeout
+	if timeout == 0 {
+		timeout = watchProgressIntervals * watchProgressNotifyInterval()
+	}
+	if since := time.Since(w.lastProgress); since > timeout {
+		c.failWatchProgress(w, fmt.Errorf("no watch progress for %s", since.Round(time.Second)))
+		return
+	}
+	c.logHealthSuccess()
+}
+
+// failWatchProgress logs the failure with the disruption starting at the last observed progress.
+func (c *Check) failWatchProgress(w *watchProgress, err error) {
+	if !c.isDisruption() {
+		c.status.logDisruptionStart = w.lastProgress
+	}
+	c.logHealthFail(err)
+}
+
+// watchProgressNotifyInterval returns the progress notify interval etcd is configured with.
+func watchProgressNotifyInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv(WatchProgressNotifyIntervalEnv)); err == nil && interval > 0 {
+		return interval
+	}
+	return DefaultWatchProgressNotifyInterval
+}
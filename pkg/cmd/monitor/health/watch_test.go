@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+func TestCheckWatchProgress(t *testing.T) {
+	testCases := map[string]struct {
+		responses   []clientv3.WatchResponse
+		failed      bool
+		wantHealthy bool
+	}{
+		"progress notification": {
+			responses:   []clientv3.WatchResponse{{}},
+			wantHealthy: true,
+		},
+		"created notification is no progress": {
+			responses: []clientv3.WatchResponse{{Created: true}},
+		},
+		"restarted watch without progress": {
+			responses: []clientv3.WatchResponse{{Created: true}},
+			failed:    true,
+		},
+		"restarted watch with progress": {
+			responses:   []clientv3.WatchResponse{{Created: true}, {}},
+			failed:      true,
+			wantHealthy: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			watchCh := make(chan clientv3.WatchResponse, len(tc.responses))
+			for _, resp := range tc.responses {
+				watchCh <- resp
+			}
+			w := &watchProgress{
+				watchCh:      watchCh,
+				cancel:       func() {},
+				lastProgress: time.Now().Add(-time.Minute),
+				failed:       tc.failed,
+			}
+			c := NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.1:2379"})
+			c.name = WatchProgress
+			c.Options.ProgressTimeout = 10 * time.Second
+			c.checkWatchProgress(context.Background(), w)
+			require.Equal(t, tc.wantHealthy, c.Report().Healthy, c.Report().LastError)
+		})
+	}
+}
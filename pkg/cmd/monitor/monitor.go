@@ -5,14 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	"github.com/openshift/library-go/pkg/controller/fileobserver"
 	"github.com/openshift/library-go/pkg/serviceability"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -33,6 +37,9 @@ const (
 	DefaultLogOutputs          = "stderr"
 	DefaultStaticPodVersion    = 0
 
+	// DefaultFileObserverInterval is the interval the targets file and TLS files are checked for changes.
+	DefaultFileObserverInterval = 5 * time.Second
+
 	// DefaultLogRotationConfig is the default configuration used for log rotation.
 	// Log rotation is disabled by default.
 	// MaxSize    = 100 // MB
@@ -43,6 +50,21 @@ const (
 	DefaultLogRotationConfig = `{"maxsize": 100, "maxage": 0, "maxbackups": 10, "localtime": false, "compress": true}`
 )
 
+var (
+	DefaultSingleTargetChecks = []string{
+		string(health.SerializedReadSingleTarget),
+		string(health.GRPCReadySingleTarget),
+		string(health.QuorumReadSingleTarget),
+		string(health.WatchProgress),
+		string(health.TCPClientSingleTarget),
+		string(health.TCPPeerSingleTarget),
+		string(health.LeaderTracking),
+	}
+	DefaultMultiTargetChecks = []string{
+		string(health.QuorumRead),
+	}
+)
+
 // defaults used by kube-apiserver
 const keepaliveTime = 30 * time.Second
 const keepaliveTimeout = 10 * time.Second
@@ -51,6 +73,10 @@ const dialTimeout = 20 * time.Second
 type monitorOpts struct {
 	errOut           io.Writer
 	Targets          string
+	targetsFile      string
+	intervalsFile    string
+	kubeconfig       string
+	podNamespace     string
 	dialTimeout      time.Duration
 	interval         time.Duration
 	logLevel         int
@@ -60,6 +86,17 @@ type monitorOpts struct {
 	clientCertFile   string
 	clientKeyFile    string
 	clientCACertFile string
+	// fallbackCertFile and fallbackKeyFile are used while the client cert files do not exist.
+	fallbackCertFile string
+	fallbackKeyFile  string
+	listenAddress    string
+	servingCertFile  string
+	servingKeyFile   string
+	// servingClientCAFile is the CA client certs to /metrics and /status are verified against.
+	servingClientCAFile string
+
+	singleTargetChecks []string
+	multiTargetChecks  []string
 
 	// enableLogRotation enables log rotation of a single LogOutputs file target.
 	enableLogRotation bool
@@ -68,9 +105,19 @@ type monitorOpts struct {
 }
 
 type Monitor struct {
-	wg           sync.WaitGroup
-	interval     time.Duration
+	lg                 *zap.Logger
+	interval           time.Duration
+	singleTargetChecks []health.CheckSpec
+	multiTargetChecks  []health.CheckSpec
+	sinks              []health.DisruptionSink
+	startTime          time.Time
+
+	lock         sync.RWMutex
+	clients      []*clientv3.Client
 	healthChecks []*health.Check
+	// checksByKey identifies checks by name and target for single target checks and by name for multi target
+	// checks so the same check is reused on reload.
+	checksByKey map[string]*health.Check
 }
 
 func NewMonitorCommand(errOut io.Writer) *cobra.Command {
@@ -95,6 +142,7 @@ func NewMonitorCommand(errOut io.Writer) *cobra.Command {
 		},
 	}
 	monitorOpts.AddFlags(cmd.Flags())
+	cmd.AddCommand(NewAnalyzeCommand(os.Stdout, errOut))
 	return cmd
 }
 
@@ -102,6 +150,10 @@ func (o *monitorOpts) AddFlags(fs *pflag.FlagSet) {
 	fs.DurationVar(&o.dialTimeout, "dial-timeout", DefaultHTTPDialTimeout, "Dial timeout for the client. Default 2s")
 	fs.DurationVar(&o.interval, "probe-interval", DefaultHealthCheckInterval, "Frequency of health checks. Default 5s")
 	fs.StringVar(&o.Targets, "targets", DefaultEndpoint, "Comma separated listed of targets to perform health checks against. Default https://localhost:2379")
+	fs.StringVar(&o.targetsFile, "targets-file", "", "File containing a comma or newline separated list of targets. Overrides --targets, changes are applied without restart.")
+	fs.StringVar(&o.intervalsFile, "intervals-file", "", "File disruption intervals are appended to as JSON lines, see monitor analyze. Disabled if empty.")
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Kubeconfig used to record disruption events against the pod, for example the kubelet kubeconfig on masters. Disabled if empty.")
+	fs.StringVar(&o.podNamespace, "pod-namespace", operatorclient.TargetNamespace, "Namespace of the pod the health probe will monitor.")
 	fs.StringSliceVar(&o.logOutputs, "log-outputs", []string{DefaultLogOutputs}, "Logger output targets. Default stderr")
 	fs.BoolVar(&o.enableLogRotation, "enable-log-rotation", false, "Enable log rotation of a single log-outputs file target.")
 	fs.StringVar(&o.LogRotationConfigJSON, "log-rotation-config-json", DefaultLogRotationConfig, "Configures log rotation if enabled with a JSON logger config. Default: MaxSize=100(MB), MaxAge=0(days,no limit), MaxBackups=10(no limit), LocalTime=false(UTC), Compress=false(true)")
@@ -110,6 +162,14 @@ func (o *monitorOpts) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.clientCertFile, "cert-file", "", "Health probe TLS client certificate file. (required)")
 	fs.StringVar(&o.clientKeyFile, "key-file", "", "Health probe TLS client key file. (required)")
 	fs.StringVar(&o.clientCACertFile, "cacert-file", "", "Health probe TLS client CA certificate file. (required)")
+	fs.StringVar(&o.fallbackCertFile, "fallback-cert-file", "", "Health probe TLS client certificate file used while --cert-file or --key-file do not exist, e.g. before a dedicated client certificate was issued.")
+	fs.StringVar(&o.fallbackKeyFile, "fallback-key-file", "", "Health probe TLS client key file used together with --fallback-cert-file.")
+	fs.StringSliceVar(&o.singleTargetChecks, "single-target-checks", DefaultSingleTargetChecks, fmt.Sprintf("Comma separated list of checks run against each target. Parameters are appended as Name:param=value, supported params are interval, slow-request-duration, deadline and progress-timeout. Valid checks: %s", strings.Join(health.CheckNames(false), ", ")))
+	fs.StringSliceVar(&o.multiTargetChecks, "multi-target-checks", DefaultMultiTargetChecks, fmt.Sprintf("Comma separated list of checks run against all targets at once, in the same format as --single-target-checks. Valid checks: %s", strings.Join(health.CheckNames(true), ", ")))
+	fs.StringVar(&o.listenAddress, "listen-address", "", "Address to serve /metrics, /healthz and /status on. Disabled if empty.")
+	fs.StringVar(&o.servingCertFile, "serving-cert-file", "", "TLS certificate file used by the --listen-address server. Plain HTTP is used if empty.")
+	fs.StringVar(&o.servingKeyFile, "serving-key-file", "", "TLS key file used by the --listen-address server.")
+	fs.StringVar(&o.servingClientCAFile, "serving-client-ca-file", "", "CA file client certificates are verified against. If set, /metrics and /status require a verified client certificate, /healthz stays open for probes.")
 }
 
 var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
@@ -124,6 +184,21 @@ func (o *monitorOpts) Validate(_ context.Context) error {
 	if len(o.clientCACertFile) == 0 {
 		return errors.New("missing required flag: --cacert-file")
 	}
+	if (len(o.fallbackCertFile) == 0) != (len(o.fallbackKeyFile) == 0) {
+		return errors.New("--fallback-cert-file and --fallback-key-file must be set together")
+	}
+	if (len(o.servingCertFile) == 0) != (len(o.servingKeyFile) == 0) {
+		return errors.New("--serving-cert-file and --serving-key-file must be set together")
+	}
+	if len(o.servingClientCAFile) > 0 && len(o.servingCertFile) == 0 {
+		return errors.New("--serving-client-ca-file requires --serving-cert-file")
+	}
+	if _, err := health.ParseCheckSpecs(o.singleTargetChecks, false); err != nil {
+		return fmt.Errorf("invalid --single-target-checks: %w", err)
+	}
+	if _, err := health.ParseCheckSpecs(o.multiTargetChecks, true); err != nil {
+		return fmt.Errorf("invalid --multi-target-checks: %w", err)
+	}
 	return nil
 }
 
@@ -156,19 +231,34 @@ func (o *monitorOpts) Run(ctx context.Context) error {
 		return err
 	}
 
-	// TODO make health checks configurable by flag(s)
-	monitor, err := o.newMonitor(ctx, lg,
-		WithSingleTargetHealthCheck(
-			health.WithSerializedReadSingleTarget(),
-			health.WithGRPCReadySingleTarget(),
-			health.WithQuorumReadSingleTarget(),
-		),
-		WithMultiTargetHealthCheck(
-			health.WithQuorumRead(),
-			// TODO this is needed to isolate OVN type failures.
-			// health.WithNodeICMP(),
-		),
-	)
+	singleTargetChecks, err := health.ParseCheckSpecs(o.singleTargetChecks, false)
+	if err != nil {
+		return err
+	}
+	multiTargetChecks, err := health.ParseCheckSpecs(o.multiTargetChecks, true)
+	if err != nil {
+		return err
+	}
+	var sinks []health.DisruptionSink
+	if len(o.intervalsFile) > 0 {
+		intervalWriter, err := health.NewIntervalWriter(lg, o.intervalsFile, o.podName)
+		if err != nil {
+			return err
+		}
+		defer intervalWriter.Close()
+		sinks = append(sinks, intervalWriter)
+	}
+	if len(o.kubeconfig) > 0 {
+		// events are optional, the sink connects in the background so the monitor keeps working if the apiserver
+		// is not reachable.
+		eventSink, stop := newEventSink(ctx, lg, o.kubeconfig, o.podNamespace, o.podName)
+		defer stop()
+		sinks = append(sinks, eventSink)
+	}
+	monitor, err := o.newMonitor(ctx, lg, singleTargetChecks, multiTargetChecks, sinks)
+	if err != nil {
+		return err
+	}
 
 	lg.Info("health monitor is starting",
 		zap.String("pod", o.podName),
@@ -176,10 +266,28 @@ func (o *monitorOpts) Run(ctx context.Context) error {
 	)
 
 	var wg sync.WaitGroup
+	if len(o.listenAddress) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			o.serve(ctx, lg, monitor)
+		}()
+	}
+	reloadCh := make(chan struct{}, 1)
+	if err := o.observeFiles(ctx, reloadCh); err != nil {
+		return err
+	}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		monitor.Schedule(ctx, &wg)
+		monitor.Schedule(ctx, reloadCh, func() (*monitorUpdate, error) {
+			targets, err := o.getTargets()
+			if err != nil {
+				return nil, err
+			}
+			lg.Info("health monitor is reloading targets and certificates", zap.Strings("targets", targets))
+			return monitor.prepareUpdate(targets, o.tlsInfo())
+		})
 	}()
 	wg.Wait()
 	lg.Info("health monitor is shutting down",
@@ -190,75 +298,263 @@ func (o *monitorOpts) Run(ctx context.Context) error {
 }
 
 // newMonitor creates a series of health checks. Health check probes can be single or multi target.
-func (o *monitorOpts) newMonitor(ctx context.Context, lg *zap.Logger, singleTargetChecks []health.CheckFunc, multiTargetChecks []health.CheckFunc) (*Monitor, error) {
-	var healthChecks []*health.Check
+func (o *monitorOpts) newMonitor(ctx context.Context, lg *zap.Logger, singleTargetChecks []health.CheckSpec, multiTargetChecks []health.CheckSpec, sinks []health.DisruptionSink) (*Monitor, error) {
+	monitor := &Monitor{
+		lg:                 lg,
+		sinks:              sinks,
+		interval:           o.interval,
+		singleTargetChecks: singleTargetChecks,
+		multiTargetChecks:  multiTargetChecks,
+		checksByKey:        map[string]*health.Check{},
+		startTime:          time.Now(),
+	}
+	targets, err := o.getTargets()
+	if err != nil {
+		return nil, err
+	}
+	if err := monitor.update(targets, o.tlsInfo()); err != nil {
+		return nil, err
+	}
+	return monitor, nil
+}
 
-	tlsInfo := transport.TLSInfo{
-		CertFile:      o.clientCertFile,
-		KeyFile:       o.clientKeyFile,
-		TrustedCAFile: o.clientCACertFile,
+// monitorUpdate holds the clients of an update, created before the running checks are stopped so that dialing does not
+// cause a gap in the monitoring.
+type monitorUpdate struct {
+	targets []string
+	// singleTargetClients are pinned to the target at the same index.
+	singleTargetClients []*clientv3.Client
+	// multiTargetClient balances across all targets, nil for a single target.
+	multiTargetClient *clientv3.Client
+}
+
+func (u *monitorUpdate) clients() []*clientv3.Client {
+	clients := append([]*clientv3.Client(nil), u.singleTargetClients...)
+	if u.multiTargetClient != nil {
+		clients = append(clients, u.multiTargetClient)
 	}
+	return clients
+}
 
-	targets := strings.Split(o.Targets, ",")
+// update creates the clients for targets and tlsInfo and assigns them to the health checks. It must not be called
+// while the checks are scheduled. If a client can not be created the current clients are kept.
+func (m *Monitor) update(targets []string, tlsInfo transport.TLSInfo) error {
+	pending, err := m.prepareUpdate(targets, tlsInfo)
+	if err != nil {
+		return err
+	}
+	m.applyUpdate(pending)
+	return nil
+}
 
-	// Create single target checks one check per target
+// prepareUpdate creates the clients for targets and tlsInfo. It is safe to call while the checks are scheduled.
+func (m *Monitor) prepareUpdate(targets []string, tlsInfo transport.TLSInfo) (*monitorUpdate, error) {
+	pending := &monitorUpdate{targets: targets}
+	// Create single target clients one client per target to eliminate lock racing
 	for _, target := range targets {
-		// one client per target to eliminate lock racing
 		client, err := newETCD3Client(tlsInfo, targets)
 		if err != nil {
+			closeClients(pending.clients())
 			return nil, err
 		}
 		// pin endpoint for check
 		client.SetEndpoints(target)
-		for _, healthCheckFunc := range singleTargetChecks {
-			healthCheck := health.NewCheck(lg, client, []string{target})
-			healthCheck.Probe = healthCheckFunc
-			healthChecks = append(healthChecks, healthCheck)
-		}
+		pending.singleTargetClients = append(pending.singleTargetClients, client)
 	}
-
 	if len(targets) > 1 {
 		client, err := newETCD3Client(tlsInfo, targets)
 		if err != nil {
+			closeClients(pending.clients())
 			return nil, err
 		}
+		pending.multiTargetClient = client
+	}
+	return pending, nil
+}
 
-		for _, healthCheckFunc := range multiTargetChecks {
-			healthCheck := health.NewCheck(lg, client, targets)
-			healthCheck.Probe = healthCheckFunc
-			healthChecks = append(healthChecks, healthCheck)
+// applyUpdate assigns the clients of the update to the health checks, replaces the current clients and closes them.
+// Existing checks are kept so their disruption state survives, checks of removed targets are terminated and their
+// metrics are deleted. It must not be called while the checks are scheduled.
+func (m *Monitor) applyUpdate(pending *monitorUpdate) {
+	var healthChecks []*health.Check
+	checksByKey := map[string]*health.Check{}
+	addCheck := func(key string, checkSpec health.CheckSpec, client *clientv3.Client, checkTargets []string) {
+		healthCheck, ok := m.checksByKey[key]
+		if ok {
+			healthCheck.Reconfigure(client, checkTargets)
+		} else {
+			healthCheck = health.NewCheck(m.lg, client, checkTargets)
+			healthCheck.Probe = checkSpec.NewProbe()
+			healthCheck.Options = checkSpec.Options
+			healthCheck.Sinks = m.sinks
 		}
+		checksByKey[key] = healthCheck
+		healthChecks = append(healthChecks, healthCheck)
 	}
 
-	monitor := &Monitor{
-		interval:     o.interval,
-		healthChecks: healthChecks,
+	// Create single target checks one check per target
+	for i, target := range pending.targets {
+		for _, checkSpec := range m.singleTargetChecks {
+			addCheck(string(checkSpec.Name)+"/"+target, checkSpec, pending.singleTargetClients[i], []string{target})
+		}
+	}
+	if pending.multiTargetClient != nil {
+		for _, checkSpec := range m.multiTargetChecks {
+			addCheck(string(checkSpec.Name), checkSpec, pending.multiTargetClient, pending.targets)
+		}
 	}
 
-	return monitor, nil
+	for key, healthCheck := range m.checksByKey {
+		if _, ok := checksByKey[key]; !ok {
+			healthCheck.LogTermination()
+			healthCheck.DeleteMetrics()
+		}
+	}
+
+	m.lock.Lock()
+	oldClients := m.clients
+	m.clients = pending.clients()
+	m.checksByKey = checksByKey
+	m.healthChecks = healthChecks
+	m.lock.Unlock()
+	closeClients(oldClients)
 }
 
-func (m *Monitor) Schedule(ctx context.Context, wg *sync.WaitGroup) {
-	for _, healthCheck := range m.healthChecks {
-		wg.Add(1)
-		go func(healthCheck *health.Check) {
-			defer wg.Done()
-			defer healthCheck.LogTermination()
-			wait.UntilWithContext(ctx,
-				func(context.Context) {
-					healthCheck.Probe(ctx, healthCheck)
-				},
-				m.interval)
-		}(healthCheck)
+// closeClients closes the current clients, which aborts the probes in flight.
+func (m *Monitor) closeClients() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	closeClients(m.clients)
+	m.clients = nil
+}
+
+func closeClients(clients []*clientv3.Client) {
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// HealthChecks returns the current health checks.
+func (m *Monitor) HealthChecks() []*health.Check {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.healthChecks
+}
+
+// Schedule runs the health checks until ctx is done, then closes the clients and terminates the checks. On reload prepare is called while the
+// checks keep running, the checks are only stopped after their current probe to apply the prepared update and are
+// started again.
+func (m *Monitor) Schedule(ctx context.Context, reloadCh <-chan struct{}, prepare func() (*monitorUpdate, error)) {
+	for {
+		var wg sync.WaitGroup
+		scheduleCtx, cancel := context.WithCancel(ctx)
+		for _, healthCheck := range m.HealthChecks() {
+			wg.Add(1)
+			interval := m.interval
+			if healthCheck.Options.Interval > 0 {
+				interval = healthCheck.Options.Interval
+			}
+			go func(healthCheck *health.Check, interval time.Duration) {
+				defer wg.Done()
+				// probes use ctx so a reload does not abort requests in flight
+				wait.UntilWithContext(scheduleCtx,
+					func(context.Context) {
+						healthCheck.Probe(ctx, healthCheck)
+					},
+					interval)
+			}(healthCheck, interval)
+		}
+
+		var pending *monitorUpdate
+		for pending == nil {
+			select {
+			case <-ctx.Done():
+				cancel()
+				m.closeClients()
+				wg.Wait()
+				for _, healthCheck := range m.HealthChecks() {
+					healthCheck.LogTermination()
+				}
+				return
+			case <-reloadCh:
+				update, err := prepare()
+				if err != nil {
+					m.lg.Error("health monitor reload failed, keeping current targets and certificates", zap.Error(err))
+					continue
+				}
+				pending = update
+			}
+		}
+		cancel()
+		wg.Wait()
+		m.applyUpdate(pending)
+	}
+}
+
+// getTargets returns the targets from --targets-file if set, otherwise from --targets.
+func (o *monitorOpts) getTargets() ([]string, error) {
+	if len(o.targetsFile) == 0 {
+		return strings.Split(o.Targets, ","), nil
+	}
+	data, err := ioutil.ReadFile(o.targetsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets: %w", err)
+	}
+	targets := strings.FieldsFunc(string(data), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets found in %s", o.targetsFile)
+	}
+	return targets, nil
+}
+
+// tlsInfo returns the client TLS files. The fallback client cert is used while the client cert files do not exist.
+func (o *monitorOpts) tlsInfo() transport.TLSInfo {
+	tlsInfo := transport.TLSInfo{
+		CertFile:      o.clientCertFile,
+		KeyFile:       o.clientKeyFile,
+		TrustedCAFile: o.clientCACertFile,
 	}
+	if len(o.fallbackCertFile) > 0 && (!fileExists(o.clientCertFile) || !fileExists(o.clientKeyFile)) {
+		klog.Warningf("%s or %s does not exist, using %s", o.clientCertFile, o.clientKeyFile, o.fallbackCertFile)
+		tlsInfo.CertFile = o.fallbackCertFile
+		tlsInfo.KeyFile = o.fallbackKeyFile
+	}
+	return tlsInfo
 }
 
-func WithMultiTargetHealthCheck(checks ...health.CheckFunc) []health.CheckFunc {
-	return checks
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-func WithSingleTargetHealthCheck(checks ...health.CheckFunc) []health.CheckFunc {
-	return checks
+// observeFiles signals reloadCh when the targets file or the TLS files change. Certificates are rotated in place by
+// the cert syncer of the static pod.
+func (o *monitorOpts) observeFiles(ctx context.Context, reloadCh chan<- struct{}) error {
+	observer, err := fileobserver.NewObserver(DefaultFileObserverInterval)
+	if err != nil {
+		return err
+	}
+	files := []string{o.clientCertFile, o.clientKeyFile, o.clientCACertFile}
+	if len(o.fallbackCertFile) > 0 {
+		// the client cert files are observed as well, their creation switches from the fallback
+		files = append(files, o.fallbackCertFile, o.fallbackKeyFile)
+	}
+	if len(o.targetsFile) > 0 {
+		files = append(files, o.targetsFile)
+	}
+	observer.AddReactor(func(file string, action fileobserver.ActionType) error {
+		klog.Infof("%s, reloading", action.String(file))
+		select {
+		case reloadCh <- struct{}{}:
+		default:
+			// reload already pending
+		}
+		return nil
+	}, nil, files...)
+	go observer.Run(ctx.Done())
+	return nil
 }
 
 func newETCD3Client(tlsInfo transport.TLSInfo, endpoints []string) (*clientv3.Client, error) {
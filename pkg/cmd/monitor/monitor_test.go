@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.etcd.io/etcd/integration"
 	"go.etcd.io/etcd/pkg/transport"
+	"go.uber.org/zap"
 )
 
 var (
@@ -72,6 +74,16 @@ func newTestData(t *testing.T, testDuration time.Duration, pauseServer, resumeSe
 			clientKeyFile:    testTLSInfo.KeyFile,
 			clientCACertFile: testTLSInfo.TrustedCAFile,
 			podName:          "etcd-test",
+
+			singleTargetChecks: []string{
+				string(health.SerializedReadSingleTarget),
+				string(health.GRPCReadySingleTarget),
+				string(health.QuorumReadSingleTarget),
+				string(health.WatchProgress) + ":progress-timeout=2s",
+				string(health.LeaderTracking),
+				string(health.QuorumWrite),
+			},
+			multiTargetChecks: DefaultMultiTargetChecks,
 		},
 	}
 
@@ -80,7 +92,7 @@ func newTestData(t *testing.T, testDuration time.Duration, pauseServer, resumeSe
 
 func createAndStartEtcdTestServer(t *testing.T, size int) (*integration.ClusterV3, string) {
 	srvTLS := testTLSInfo
-	etcd := integration.NewClusterV3(t, &integration.ClusterConfig{Size: size, ClientTLS: &srvTLS})
+	etcd := integration.NewClusterV3(t, &integration.ClusterConfig{Size: size, ClientTLS: &srvTLS, WatchProgressNotifyInterval: 500 * time.Millisecond})
 	targets := fmt.Sprintf("%s,%s,%s", etcd.Members[0].GRPCAddr(), etcd.Members[1].GRPCAddr(), etcd.Members[2].GRPCAddr())
 
 	// populated expected default NS
@@ -137,6 +149,29 @@ func TestMonitor(t *testing.T) {
 			wantHealthCheck:          health.QuorumReadSingleTarget,
 			wantDuration:             6 * time.Second,
 		},
+		"healthy WatchProgress": {
+			duration:        4 * time.Second,
+			wantHealthCheck: health.WatchProgress,
+			wantDuration:    0 * time.Second,
+		},
+		"healthy LeaderTracking": {
+			duration:        3 * time.Second,
+			wantHealthCheck: health.LeaderTracking,
+			wantDuration:    0 * time.Second,
+		},
+		"healthy QuorumWrite": {
+			duration:        2 * time.Second,
+			wantHealthCheck: health.QuorumWrite,
+			wantDuration:    0 * time.Second,
+		},
+		"unhealthy QuorumWrite": {
+			duration:                 5 * time.Second,
+			pauseServer:              true,
+			pauseServerAfterDuration: 0 * time.Second, //instantly
+			pauseEtcdPeers:           2,
+			wantHealthCheck:          health.QuorumWrite,
+			wantDuration:             5 * time.Second,
+		},
 		"healthy SerializedReadSingleTarget 7s 3s disruption": {
 			duration:                10 * time.Second,
 			stopServer:              true,
@@ -228,8 +263,9 @@ func getDisruptionDurationFromLogs(t *testing.T, wantHealthCheck health.CheckNam
 	defer logFile.Close()
 
 	decoder := json.NewDecoder(logFile)
-	var log health.LogLine
 	for decoder.More() {
+		// decode every line into a new struct, fields missing in a line must not be kept from the previous one
+		var log health.LogLine
 		if err := decoder.Decode(&log); err != nil {
 			return dur, fmt.Errorf("parse error: %w", err)
 		}
@@ -260,3 +296,168 @@ func MustAbsPath(path string) string {
 	}
 	return abs
 }
+
+func TestMonitorUpdate(t *testing.T) {
+	testServer, targets := createAndStartEtcdTestServer(t, 3)
+	defer testServer.Terminate(t)
+	allTargets := strings.Split(targets, ",")
+
+	singleTargetChecks, err := health.ParseCheckSpecs([]string{string(health.SerializedReadSingleTarget)}, false)
+	require.NoError(t, err)
+	multiTargetChecks, err := health.ParseCheckSpecs([]string{string(health.QuorumRead)}, true)
+	require.NoError(t, err)
+	opts := &monitorOpts{
+		Targets:          strings.Join(allTargets[:2], ","),
+		clientCertFile:   testTLSInfo.CertFile,
+		clientKeyFile:    testTLSInfo.KeyFile,
+		clientCACertFile: testTLSInfo.TrustedCAFile,
+	}
+	monitor, err := opts.newMonitor(context.Background(), zap.NewNop(), singleTargetChecks, multiTargetChecks, nil)
+	require.NoError(t, err)
+	checks := monitor.HealthChecks()
+	require.Len(t, checks, 3)
+
+	// added target, existing checks are kept
+	require.NoError(t, monitor.update(allTargets, opts.tlsInfo()))
+	updated := monitor.HealthChecks()
+	require.Len(t, updated, 4)
+	require.Same(t, checks[0], updated[0])
+	require.Same(t, checks[1], updated[1])
+	require.Same(t, checks[2], updated[3])
+	require.Equal(t, allTargets, updated[3].Report().Targets)
+
+	// removed targets, the multi target check is removed with the second target
+	require.NoError(t, monitor.update(allTargets[:1], opts.tlsInfo()))
+	updated = monitor.HealthChecks()
+	require.Len(t, updated, 1)
+	require.Same(t, checks[0], updated[0])
+
+	// invalid certificates keep the current checks
+	tlsInfo := opts.tlsInfo()
+	tlsInfo.CertFile = MustAbsPath("testdata/missing.crt")
+	require.Error(t, monitor.update(allTargets, tlsInfo))
+	require.Len(t, monitor.HealthChecks(), 1)
+}
+
+func TestGetTargets(t *testing.T) {
+	targetsFile, err := ioutil.TempFile("", "targets-")
+	require.NoError(t, err)
+	defer os.Remove(targetsFile.Name())
+	_, err = targetsFile.WriteString("https://10.0.0.1:2379,https://10.0.0.2:2379\nhttps://10.0.0.3:2379\n")
+	require.NoError(t, err)
+	require.NoError(t, targetsFile.Close())
+
+	opts := &monitorOpts{Targets: "https://localhost:2379"}
+	targets, err := opts.getTargets()
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://localhost:2379"}, targets)
+
+	opts.targetsFile = targetsFile.Name()
+	targets, err = opts.getTargets()
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://10.0.0.1:2379", "https://10.0.0.2:2379", "https://10.0.0.3:2379"}, targets)
+
+	require.NoError(t, ioutil.WriteFile(targetsFile.Name(), []byte("\n"), 0600))
+	_, err = opts.getTargets()
+	require.Error(t, err)
+}
+
+// TestMonitorMultiTargetWatchProgress runs a stateful check against several targets at once, run it with -race to
+// detect probe state shared between the targets.
+func TestMonitorMultiTargetWatchProgress(t *testing.T) {
+	testServer, targets := createAndStartEtcdTestServer(t, 3)
+	defer testServer.Terminate(t)
+
+	singleTargetChecks, err := health.ParseCheckSpecs([]string{string(health.WatchProgress) + ":progress-timeout=2s"}, false)
+	require.NoError(t, err)
+	opts := &monitorOpts{
+		Targets:          targets,
+		interval:         100 * time.Millisecond,
+		clientCertFile:   testTLSInfo.CertFile,
+		clientKeyFile:    testTLSInfo.KeyFile,
+		clientCACertFile: testTLSInfo.TrustedCAFile,
+	}
+	monitor, err := opts.newMonitor(context.Background(), zap.NewNop(), singleTargetChecks, nil, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	monitor.Schedule(ctx, nil, nil)
+
+	checks := monitor.HealthChecks()
+	require.Len(t, checks, 3)
+	for _, check := range checks {
+		report := check.Report()
+		require.NotNil(t, report.LastCheck, "check of %v never completed", report.Targets)
+		require.True(t, report.Healthy, "check of %v: %s", report.Targets, report.LastError)
+	}
+}
+
+func TestMonitorScheduleReload(t *testing.T) {
+	testServer, targets := createAndStartEtcdTestServer(t, 3)
+	defer testServer.Terminate(t)
+	allTargets := strings.Split(targets, ",")
+
+	singleTargetChecks, err := health.ParseCheckSpecs([]string{string(health.SerializedReadSingleTarget)}, false)
+	require.NoError(t, err)
+	opts := &monitorOpts{
+		Targets:          allTargets[0],
+		interval:         100 * time.Millisecond,
+		clientCertFile:   testTLSInfo.CertFile,
+		clientKeyFile:    testTLSInfo.KeyFile,
+		clientCACertFile: testTLSInfo.TrustedCAFile,
+	}
+	monitor, err := opts.newMonitor(context.Background(), zap.NewNop(), singleTargetChecks, nil, nil)
+	require.NoError(t, err)
+	check := monitor.HealthChecks()[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloadCh := make(chan struct{}, 1)
+	reloaded := make(chan struct{})
+	done := make(chan struct{})
+	var keptRunning bool
+	go func() {
+		defer close(done)
+		monitor.Schedule(ctx, reloadCh, func() (*monitorUpdate, error) {
+			defer close(reloaded)
+			before := check.Report().LastCheck
+			pending, err := monitor.prepareUpdate(allTargets, opts.tlsInfo())
+			// the current checks keep running while the new clients are created
+			time.Sleep(500 * time.Millisecond)
+			keptRunning = before != check.Report().LastCheck
+			return pending, err
+		})
+	}()
+
+	reloadCh <- struct{}{}
+	<-reloaded
+	require.True(t, keptRunning, "checks were stopped while the new clients were created")
+	require.Eventually(t, func() bool {
+		return len(monitor.HealthChecks()) == 3
+	}, 5*time.Second, 100*time.Millisecond)
+	require.Same(t, check, monitor.HealthChecks()[0])
+
+	cancel()
+	<-done
+}
+
+func TestTLSInfoFallback(t *testing.T) {
+	opts := &monitorOpts{
+		clientCertFile:   MustAbsPath("testdata/etcd-client-monitor.crt"),
+		clientKeyFile:    MustAbsPath("testdata/etcd-client-monitor.key"),
+		clientCACertFile: testTLSInfo.TrustedCAFile,
+		fallbackCertFile: testTLSInfo.CertFile,
+		fallbackKeyFile:  testTLSInfo.KeyFile,
+	}
+	// the dedicated client cert was not issued yet
+	tlsInfo := opts.tlsInfo()
+	require.Equal(t, testTLSInfo.CertFile, tlsInfo.CertFile)
+	require.Equal(t, testTLSInfo.KeyFile, tlsInfo.KeyFile)
+
+	opts.clientCertFile, opts.clientKeyFile = testTLSInfo.CertFile, testTLSInfo.KeyFile
+	opts.fallbackCertFile, opts.fallbackKeyFile = MustAbsPath("testdata/ca.crt"), MustAbsPath("testdata/ca.crt")
+	tlsInfo = opts.tlsInfo()
+	require.Equal(t, testTLSInfo.CertFile, tlsInfo.CertFile)
+	require.Equal(t, testTLSInfo.KeyFile, tlsInfo.KeyFile)
+}
@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const serverShutdownTimeout = 5 * time.Second
+
+// monitorStatus is served on /status.
+type monitorStatus struct {
+	Pod              string               `json:"pod"`
+	StaticPodVersion int                  `json:"staticPodVersion"`
+	StartTime        time.Time            `json:"startTime"`
+	Uptime           string               `json:"uptime"`
+	Checks           []health.CheckReport `json:"checks"`
+}
+
+// newServeMux returns the handlers served on --listen-address.
+func (o *monitorOpts) newServeMux(monitor *Monitor) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", o.requireClientCert(promhttp.HandlerFor(health.MetricsRegistry, promhttp.HandlerOpts{})))
+	// healthz reports the monitor itself, not the result of the checks. Failing readiness on disruption would only
+	// duplicate the readiness of the etcd container.
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.Handle("/status", o.requireClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := monitorStatus{
+			Pod:              o.podName,
+			StaticPodVersion: o.staticPodVersion,
+			StartTime:        monitor.startTime,
+			Uptime:           time.Since(monitor.startTime).Round(time.Second).String(),
+			Checks:           []health.CheckReport{},
+		}
+		for _, healthCheck := range monitor.HealthChecks() {
+			status.Checks = append(status.Checks, healthCheck.Report())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})))
+	return mux
+}
+
+// requireClientCert rejects requests without a client cert verified against --serving-client-ca-file. The TLS
+// handshake only verifies client certs if given, so the kubelet can still probe /healthz without one.
+func (o *monitorOpts) requireClientCert(handler http.Handler) http.Handler {
+	if len(o.servingClientCAFile) == 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// servingTLSConfig returns the TLS config verifying client certs against --serving-client-ca-file, nil if unset.
+func (o *monitorOpts) servingTLSConfig() (*tls.Config, error) {
+	if len(o.servingClientCAFile) == 0 {
+		return nil, nil
+	}
+	caPEM, err := os.ReadFile(o.servingClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read serving client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in serving client CA %s", o.servingClientCAFile)
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+	}, nil
+}
+
+// serve listens on --listen-address until ctx is done. TLS is used if a serving cert and key are provided, client certs
+// are verified if --serving-client-ca-file is set.
+func (o *monitorOpts) serve(ctx context.Context, lg *zap.Logger, monitor *Monitor) {
+	tlsConfig, err := o.servingTLSConfig()
+	if err != nil {
+		lg.Error("health monitor server failed", zap.String("address", o.listenAddress), zap.Error(err))
+		return
+	}
+	server := &http.Server{
+		Addr:      o.listenAddress,
+		Handler:   o.newServeMux(monitor),
+		TLSConfig: tlsConfig,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	lg.Info("health monitor server is starting", zap.String("address", o.listenAddress))
+	if len(o.servingCertFile) > 0 {
+		err = server.ListenAndServeTLS(o.servingCertFile, o.servingKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		lg.Error("health monitor server failed", zap.String("address", o.listenAddress), zap.Error(err))
+	}
+}
@@ -0,0 +1,74 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/cmd/monitor/health"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestServeMux(t *testing.T) {
+	opts := &monitorOpts{podName: "etcd-test", staticPodVersion: 3}
+	monitor := &Monitor{
+		healthChecks: []*health.Check{health.NewCheck(zap.NewNop(), nil, []string{"https://10.0.0.1:2379"})},
+		startTime:    time.Now().Add(-time.Minute),
+	}
+	server := httptest.NewServer(opts.newServeMux(monitor))
+	defer server.Close()
+
+	for _, path := range []string{"/healthz", "/metrics"} {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+
+	resp, err := http.Get(server.URL + "/status")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	status := &monitorStatus{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(status))
+	require.Equal(t, "etcd-test", status.Pod)
+	require.Equal(t, 3, status.StaticPodVersion)
+	require.Equal(t, "1m0s", status.Uptime)
+	require.Len(t, status.Checks, 1)
+	require.Equal(t, []string{"https://10.0.0.1:2379"}, status.Checks[0].Targets)
+}
+
+func TestServeMuxRequiresClientCert(t *testing.T) {
+	opts := &monitorOpts{servingClientCAFile: "testdata/ca.crt"}
+	mux := opts.newServeMux(&Monitor{startTime: time.Now()})
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+	scenarios := []struct {
+		path     string
+		tls      *tls.ConnectionState
+		expected int
+	}{
+		{path: "/healthz", expected: http.StatusOK},
+		{path: "/metrics", expected: http.StatusUnauthorized},
+		{path: "/status", expected: http.StatusUnauthorized},
+		{path: "/metrics", tls: &tls.ConnectionState{}, expected: http.StatusUnauthorized},
+		{path: "/metrics", tls: verified, expected: http.StatusOK},
+		{path: "/status", tls: verified, expected: http.StatusOK},
+	}
+	for _, scenario := range scenarios {
+		req := httptest.NewRequest(http.MethodGet, scenario.path, nil)
+		req.TLS = scenario.tls
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, scenario.expected, rec.Code, scenario.path)
+	}
+
+	tlsConfig, err := opts.servingTLSConfig()
+	require.NoError(t, err)
+	require.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+}
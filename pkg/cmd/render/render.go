@@ -337,7 +337,7 @@ func (r *renderOpts) Run() error {
 	// Write the serving and peer certs for the bootstrap etcd member
 	caCertData := templateData.EtcdSignerCert
 	caKeyData := templateData.EtcdSignerKey
-	serverCertData, serverKeyData, err := tlshelpers.CreateServerCertKey(caCertData, caKeyData, []string{templateData.BootstrapIP})
+	serverCertData, serverKeyData, err := tlshelpers.CreateServerCertKey(caCertData, caKeyData, []string{templateData.BootstrapIP}, tlshelpers.DefaultCertOptions())
 	if err != nil {
 		return err
 	}
@@ -345,7 +345,7 @@ func (r *renderOpts) Run() error {
 	if err != nil {
 		return err
 	}
-	peerCertData, peerKeyData, err := tlshelpers.CreatePeerCertKey(caCertData, caKeyData, []string{templateData.BootstrapIP})
+	peerCertData, peerKeyData, err := tlshelpers.CreatePeerCertKey(caCertData, caKeyData, []string{templateData.BootstrapIP}, tlshelpers.DefaultCertOptions())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,149 @@
+package verifynodeip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+type verifyNodeIPOpts struct {
+	errOut    io.Writer
+	envPrefix string
+	podIP     string
+
+	// getenv, hostIPs and lookupHost are replaced in tests.
+	getenv     func(string) string
+	hostIPs    func() ([]net.IP, error)
+	lookupHost func(string) ([]string, error)
+}
+
+// NewVerifyNodeIPCommand checks that the etcd env vars of the node are set and that the node IP etcd is configured
+// with belongs to the host, before the etcd member is started with it.
+func NewVerifyNodeIPCommand(errOut io.Writer) *cobra.Command {
+	verifyNodeIPOpts := &verifyNodeIPOpts{
+		errOut:     errOut,
+		getenv:     os.Getenv,
+		hostIPs:    interfaceIPs,
+		lookupHost: net.LookupHost,
+	}
+	cmd := &cobra.Command{
+		Use:   "verify-node-ip --env-prefix=NODE_<node>_ --pod-ip=<ip>",
+		Short: "Verifies the etcd env vars and the node IP of the etcd member",
+		Long: `Verifies that the etcd env vars of the node are set and that the node IP is the pod IP, or assigned to a host
+interface if the node selects its etcd IP with an annotation. The etcd url host must be the node IP or resolve to it.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			must := func(fn func() error) {
+				if err := fn(); err != nil {
+					if cmd.HasParent() {
+						klog.Fatal(err)
+						fmt.Fprint(verifyNodeIPOpts.errOut, err.Error())
+					}
+				}
+			}
+			must(verifyNodeIPOpts.Validate)
+			must(verifyNodeIPOpts.Run)
+		},
+	}
+
+	verifyNodeIPOpts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func (o *verifyNodeIPOpts) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.envPrefix, "env-prefix", "", "Prefix of the etcd env vars of the node, e.g. NODE_master_0_. (required)")
+	fs.StringVar(&o.podIP, "pod-ip", "", "IP of the etcd pod, the node IP must match it unless the node selects its etcd IP. (required)")
+}
+
+func (o *verifyNodeIPOpts) Validate() error {
+	if len(o.envPrefix) == 0 {
+		return errors.New("missing required flag: --env-prefix")
+	}
+	if len(o.podIP) == 0 {
+		return errors.New("missing required flag: --pod-ip")
+	}
+	return nil
+}
+
+func (o *verifyNodeIPOpts) Run() error {
+	for _, suffix := range []string{"ETCD_URL_HOST", "ETCD_NAME", "IP"} {
+		if len(o.getenv(o.envPrefix+suffix)) == 0 {
+			return fmt.Errorf("%s%s not set", o.envPrefix, suffix)
+		}
+	}
+	nodeIP := trimBrackets(o.getenv(o.envPrefix + "IP"))
+
+	if selector := o.getenv(o.envPrefix + "IP_SELECTOR"); len(selector) > 0 {
+		// nodes selecting their etcd IP with an annotation may use another IP than the pod IP, it must be assigned to
+		// an interface of the host
+		hostIPs, err := o.hostIPs()
+		if err != nil {
+			return fmt.Errorf("failed to list host interface addresses: %w", err)
+		}
+		if !containsIP(hostIPs, nodeIP) {
+			return fmt.Errorf("expected node IP %s selected by %s to be assigned to a host interface", nodeIP, selector)
+		}
+	} else if !sameIP(nodeIP, o.podIP) {
+		return fmt.Errorf("expected node IP to be %s got %s", o.podIP, nodeIP)
+	}
+
+	// members using DNS member URLs have a DNS name resolving to the node IP instead
+	urlHost := trimBrackets(o.getenv(o.envPrefix + "ETCD_URL_HOST"))
+	if sameIP(urlHost, nodeIP) {
+		return nil
+	}
+	addresses, err := o.lookupHost(urlHost)
+	if err != nil {
+		return fmt.Errorf("expected etcd url host to be %s or a DNS name resolving to it got %s: %w", nodeIP, urlHost, err)
+	}
+	for _, address := range addresses {
+		if sameIP(address, nodeIP) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected etcd url host to be %s or a DNS name resolving to it got %s resolving to %v", nodeIP, urlHost, addresses)
+}
+
+// interfaceIPs returns the IPs assigned to the interfaces of the host, the etcd pod uses the host network.
+func interfaceIPs() ([]net.IP, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips, nil
+}
+
+// trimBrackets removes the square brackets of escaped ipv6 addresses.
+func trimBrackets(host string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+func sameIP(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipA.Equal(ipB)
+}
+
+func containsIP(ips []net.IP, ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, candidate := range ips {
+		if candidate.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package verifynodeip
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyNodeIP(t *testing.T) {
+	hostIPs := func() ([]net.IP, error) {
+		return []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.1"), net.ParseIP("192.168.0.1"), net.ParseIP("fd00::1")}, nil
+	}
+	lookupHost := func(host string) ([]string, error) {
+		switch host {
+		case "master-0.etcd.example.com":
+			return []string{"192.168.0.1"}, nil
+		case "master-0.example.com":
+			return []string{"10.0.0.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	testCases := map[string]struct {
+		env     map[string]string
+		podIP   string
+		wantErr bool
+	}{
+		"node IP is the pod IP": {
+			env:   map[string]string{"IP": "10.0.0.1", "ETCD_URL_HOST": "10.0.0.1"},
+			podIP: "10.0.0.1",
+		},
+		"escaped ipv6 node IP": {
+			env:   map[string]string{"IP": "[fd00::1]", "ETCD_URL_HOST": "[fd00::1]"},
+			podIP: "fd00::1",
+		},
+		"node IP is not the pod IP": {
+			env:     map[string]string{"IP": "192.168.0.1", "ETCD_URL_HOST": "192.168.0.1"},
+			podIP:   "10.0.0.1",
+			wantErr: true,
+		},
+		"selected node IP on a host interface": {
+			env:   map[string]string{"IP": "192.168.0.1", "IP_SELECTOR": "192.168.0.0/24", "ETCD_URL_HOST": "192.168.0.1"},
+			podIP: "10.0.0.1",
+		},
+		"selected node IP not on a host interface": {
+			env:     map[string]string{"IP": "192.168.1.1", "IP_SELECTOR": "192.168.1.0/24", "ETCD_URL_HOST": "192.168.1.1"},
+			podIP:   "10.0.0.1",
+			wantErr: true,
+		},
+		"url host resolving to the node IP": {
+			env:   map[string]string{"IP": "192.168.0.1", "IP_SELECTOR": "192.168.0.0/24", "ETCD_URL_HOST": "master-0.etcd.example.com"},
+			podIP: "10.0.0.1",
+		},
+		"url host resolving to another IP": {
+			env:     map[string]string{"IP": "192.168.0.1", "IP_SELECTOR": "192.168.0.0/24", "ETCD_URL_HOST": "master-0.example.com"},
+			podIP:   "10.0.0.1",
+			wantErr: true,
+		},
+		"url host not resolving": {
+			env:     map[string]string{"IP": "10.0.0.1", "ETCD_URL_HOST": "unknown.example.com"},
+			podIP:   "10.0.0.1",
+			wantErr: true,
+		},
+		"missing env var": {
+			env:     map[string]string{"IP": "10.0.0.1"},
+			podIP:   "10.0.0.1",
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			env := map[string]string{"NODE_master_0_ETCD_NAME": "master-0"}
+			for k, v := range tc.env {
+				env["NODE_master_0_"+k] = v
+			}
+			opts := &verifyNodeIPOpts{
+				envPrefix:  "NODE_master_0_",
+				podIP:      tc.podIP,
+				getenv:     func(key string) string { return env[key] },
+				hostIPs:    hostIPs,
+				lookupHost: lookupHost,
+			}
+			err := opts.Run()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
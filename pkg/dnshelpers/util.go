@@ -9,6 +9,12 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// NodeIPSelectorAnnotation selects the internal IP of a master node used for
+// etcd when the node has several internal IPs of the preferred family, e.g.
+// on multi-NIC masters. The value is one of the internal IPs of the node or a
+// subnet in CIDR notation containing it.
+const NodeIPSelectorAnnotation = "etcd-operator.alpha.openshift.io/node-ip-selector"
+
 // GetEscapedPreferredInternalIPAddressForNodeName returns the first internal ip address of the correct family with escaping
 // for ipv6.
 func GetEscapedPreferredInternalIPAddressForNodeName(network *configv1.Network, node *corev1.Node) (string, error) {
@@ -35,15 +41,25 @@ func GetURLHostForIP(ip string) (string, error) {
 	return "[" + ip + "]", nil
 }
 
-// GetPreferredInternalIPAddressForNodeName returns the first internal ip address of the correct family and the family
+// GetPreferredInternalIPAddressForNodeName returns the first internal ip address of the correct family and the family.
+// If the node is annotated with NodeIPSelectorAnnotation the first matching internal ip address is returned instead.
 func GetPreferredInternalIPAddressForNodeName(network *configv1.Network, node *corev1.Node) (string, string, error) {
 	ipFamily, err := GetPreferredIPFamily(network)
 	if err != nil {
 		return "", "", err
 	}
 
+	selector, hasSelector := node.Annotations[NodeIPSelectorAnnotation]
+	matchesSelector, err := newIPSelector(selector)
+	if hasSelector && err != nil {
+		return "", "", fmt.Errorf("node %q has an invalid %s annotation: %w", node.Name, NodeIPSelectorAnnotation, err)
+	}
+
 	for _, currAddress := range node.Status.Addresses {
 		if currAddress.Type == corev1.NodeInternalIP {
+			if hasSelector && !matchesSelector(currAddress.Address) {
+				continue
+			}
 			switch ipFamily {
 			case "tcp4":
 				isIPv4, err := IsIPv4(currAddress.Address)
@@ -67,9 +83,30 @@ func GetPreferredInternalIPAddressForNodeName(network *configv1.Network, node *c
 		}
 	}
 
+	if hasSelector {
+		return "", "", fmt.Errorf("no matches found for ip family %q and %s %q for node %q", ipFamily, NodeIPSelectorAnnotation, selector, node.Name)
+	}
 	return "", "", fmt.Errorf("no matches found for ip family %q for node %q", ipFamily, node.Name)
 }
 
+// newIPSelector returns a func matching the IP addresses selected by an IP
+// address or a subnet in CIDR notation.
+func newIPSelector(selector string) (func(address string) bool, error) {
+	if _, subnet, err := net.ParseCIDR(selector); err == nil {
+		return func(address string) bool {
+			ip := net.ParseIP(address)
+			return ip != nil && subnet.Contains(ip)
+		}, nil
+	}
+	selectedIP := net.ParseIP(selector)
+	if selectedIP == nil {
+		return nil, fmt.Errorf("%q is neither an IP address nor a CIDR", selector)
+	}
+	return func(address string) bool {
+		return selectedIP.Equal(net.ParseIP(address))
+	}, nil
+}
+
 // GetPreferredIPFamily checks network status for service CIDR to conclude IP family. If status is not yet populated
 // fallback to spec.
 func GetPreferredIPFamily(network *configv1.Network) (string, error) {
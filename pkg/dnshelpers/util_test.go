@@ -0,0 +1,80 @@
+package dnshelpers
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPreferredInternalIPAddressForNodeName(t *testing.T) {
+	network := &configv1.Network{
+		Spec: configv1.NetworkSpec{ServiceNetwork: []string{"172.30.0.0/16"}},
+	}
+	tests := []struct {
+		name     string
+		selector *string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "no selector",
+			want: "192.168.0.1",
+		},
+		{
+			name:     "ip selector",
+			selector: strPtr("10.0.0.1"),
+			want:     "10.0.0.1",
+		},
+		{
+			name:     "cidr selector",
+			selector: strPtr("10.0.0.0/24"),
+			want:     "10.0.0.1",
+		},
+		{
+			name:     "selector of another family",
+			selector: strPtr("fd00::1"),
+			wantErr:  true,
+		},
+		{
+			name:     "selector without match",
+			selector: strPtr("10.1.0.0/24"),
+			wantErr:  true,
+		},
+		{
+			name:     "invalid selector",
+			selector: strPtr("eth1"),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "master-0"},
+				Status: corev1.NodeStatus{
+					Addresses: []corev1.NodeAddress{
+						{Type: corev1.NodeInternalIP, Address: "192.168.0.1"},
+						{Type: corev1.NodeInternalIP, Address: "fd00::1"},
+						{Type: corev1.NodeExternalIP, Address: "10.1.0.1"},
+						{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+					},
+				},
+			}
+			if tt.selector != nil {
+				node.Annotations = map[string]string{NodeIPSelectorAnnotation: *tt.selector}
+			}
+			got, _, err := GetPreferredInternalIPAddressForNodeName(network, node)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPreferredInternalIPAddressForNodeName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("GetPreferredInternalIPAddressForNodeName() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
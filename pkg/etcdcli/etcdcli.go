@@ -131,7 +131,12 @@ func getEtcdClient(endpoints []string) (*clientv3.Client, error) {
 		KeyFile:       "/var/run/secrets/etcd-client/tls.key",
 		TrustedCAFile: "/var/run/configmaps/etcd-ca/ca-bundle.crt",
 	}
+	// the client cert is issued by the operator and missing until the etcd cert signer created it, until then the
+	// connection fails and the client is recreated on the next call.
 	tlsConfig, err := tlsInfo.ClientConfig()
+	if err != nil {
+		klog.Warningf("failed to load etcd client cert: %v", err)
+	}
 
 	cfg := &clientv3.Config{
 		DialOptions: dialOptions,
@@ -1,6 +1,8 @@
 package etcdcli
 
 import (
+	"fmt"
+
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -40,7 +42,13 @@ func (f *fakeEtcdClient) GetMember(name string) (*etcdserverpb.Member, error) {
 }
 
 func (f *fakeEtcdClient) MemberUpdatePeerURL(id uint64, peerURL []string) error {
-	panic("implement me")
+	for _, m := range f.members {
+		if m.ID == id {
+			m.PeerURLs = peerURL
+			return nil
+		}
+	}
+	return fmt.Errorf("member %d not found", id)
 }
 
 func NewFakeEtcdClient(members []*etcdserverpb.Member) EtcdClient {
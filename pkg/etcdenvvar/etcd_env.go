@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
 
 	configv1 "github.com/openshift/api/config/v1"
@@ -63,6 +65,7 @@ var envVarFns = []envVarFunc{
 //   ETCD_INITIAL_CLUSTER_STATE
 //   ETCD_UNSUPPORTED_ARCH
 //   NODE_%s_IP
+//   NODE_%s_IP_SELECTOR - only set for nodes selecting their etcd IP
 //   NODE_%s_ETCD_URL_HOST
 //   NODE_%s_ETCD_NAME
 func getEtcdEnvVars(envVarContext envVarContext) (map[string]string, error) {
@@ -181,11 +184,18 @@ func getEscapedIPAddress(envVarContext envVarContext) (map[string]string, error)
 			return nil, err
 		}
 		ret[fmt.Sprintf("NODE_%s_IP", envVarSafe(nodeInfo.NodeName))] = escapedIPAddress
+		// the selected IP can differ from the pod IP, which the static pod checks against
+		if selector, ok := node.Annotations[dnshelpers.NodeIPSelectorAnnotation]; ok {
+			ret[fmt.Sprintf("NODE_%s_IP_SELECTOR", envVarSafe(nodeInfo.NodeName))] = selector
+		}
 	}
 
 	return ret, nil
 }
 
+// getEtcdURLHost returns the host of the peer and client URLs advertised by
+// each member: the host recorded by the ClusterMemberController once the
+// member uses a DNS name, else the preferred internal IP of its node.
 func getEtcdURLHost(envVarContext envVarContext) (map[string]string, error) {
 	ret := map[string]string{}
 
@@ -193,8 +203,16 @@ func getEtcdURLHost(envVarContext envVarContext) (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	recordedHosts, err := ceohelpers.GetRecordedMemberURLHosts(envVarContext.configmapLister)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, nodeInfo := range envVarContext.status.NodeStatuses {
+		if recordedHost, ok := recordedHosts[nodeInfo.NodeName]; ok {
+			ret[fmt.Sprintf("NODE_%s_ETCD_URL_HOST", envVarSafe(nodeInfo.NodeName))] = recordedHost
+			continue
+		}
 		node, err := envVarContext.nodeLister.Get(nodeInfo.NodeName)
 		if err != nil {
 			return nil, err
@@ -211,43 +229,55 @@ func getEtcdURLHost(envVarContext envVarContext) (map[string]string, error) {
 }
 
 func getHeartbeatInterval(envVarContext envVarContext) (map[string]string, error) {
-	heartbeat := "100" // etcd default
-
-	infrastructure, err := envVarContext.infrastructureLister.Get("cluster")
+	heartbeatMillis, _, err := getHeartbeatAndElectionTimeout(envVarContext)
 	if err != nil {
 		return nil, err
 	}
+	return map[string]string{
+		"ETCD_HEARTBEAT_INTERVAL": strconv.Itoa(heartbeatMillis),
+	}, nil
+}
 
-	if status := infrastructure.Status.PlatformStatus; status != nil {
-		switch {
-		case status.Azure != nil:
-			heartbeat = "500"
-		}
+func getElectionTimeout(envVarContext envVarContext) (map[string]string, error) {
+	_, timeoutMillis, err := getHeartbeatAndElectionTimeout(envVarContext)
+	if err != nil {
+		return nil, err
 	}
-
 	return map[string]string{
-		"ETCD_HEARTBEAT_INTERVAL": heartbeat,
+		"ETCD_ELECTION_TIMEOUT": strconv.Itoa(timeoutMillis),
 	}, nil
 }
 
-func getElectionTimeout(envVarContext envVarContext) (map[string]string, error) {
-	timeout := "1000" // etcd default
+// getHeartbeatAndElectionTimeout returns the heartbeat interval and election
+// timeout in milliseconds of the tuning profile, or the defaults of the
+// platform.
+func getHeartbeatAndElectionTimeout(envVarContext envVarContext) (int, int, error) {
+	heartbeatMillis, timeoutMillis, ok, err := getTunedHeartbeatAndElectionTimeout(envVarContext)
+	if err != nil || ok {
+		return heartbeatMillis, timeoutMillis, err
+	}
 
 	infrastructure, err := envVarContext.infrastructureLister.Get("cluster")
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
+	heartbeatMillis, timeoutMillis = ceohelpers.PlatformDefaultHeartbeatAndElectionTimeout(infrastructure)
+	return heartbeatMillis, timeoutMillis, nil
+}
 
-	if status := infrastructure.Status.PlatformStatus; status != nil {
-		switch {
-		case status.Azure != nil:
-			timeout = "2500"
-		}
+// getTunedHeartbeatAndElectionTimeout returns the heartbeat interval and
+// election timeout in milliseconds of the tuning profile, or false if the
+// defaults of the platform apply.
+func getTunedHeartbeatAndElectionTimeout(envVarContext envVarContext) (int, int, bool, error) {
+	tuning, err := ceohelpers.GetTuningConfig(&envVarContext.spec.OperatorSpec)
+	if err != nil {
+		return 0, 0, false, err
 	}
-
-	return map[string]string{
-		"ETCD_ELECTION_TIMEOUT": timeout,
-	}, nil
+	if tuning.Profile == ceohelpers.AutoTuningProfile {
+		return ceohelpers.GetRecordedTuningRecommendation(envVarContext.configmapLister)
+	}
+	heartbeatMillis, timeoutMillis, ok := tuning.HeartbeatAndElectionTimeout()
+	return heartbeatMillis, timeoutMillis, ok, nil
 }
 
 func envVarSafe(nodeName string) string {
@@ -5,7 +5,16 @@ import (
 	"testing"
 
 	configv1 "github.com/openshift/api/config/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/library-go/pkg/crypto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
 )
 
 // TestWhitelistEtcdCipherSuites this test is intended to ensure the ciphers we support is explicitly understood overtime.
@@ -38,3 +47,96 @@ func TestWhitelistEtcdCipherSuites(t *testing.T) {
 		})
 	}
 }
+
+func TestHeartbeatAndElectionTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		platform       configv1.PlatformStatus
+		tuning         string
+		recommendation map[string]string
+		wantHeartbeat  string
+		wantTimeout    string
+	}{
+		{
+			name:          "default profile",
+			wantHeartbeat: "100",
+			wantTimeout:   "1000",
+		},
+		{
+			name:          "default profile on azure",
+			platform:      configv1.PlatformStatus{Azure: &configv1.AzurePlatformStatus{}},
+			wantHeartbeat: "500",
+			wantTimeout:   "2500",
+		},
+		{
+			name:          "slower profile",
+			tuning:        `{"tuning": {"profile": "Slower"}}`,
+			wantHeartbeat: "500",
+			wantTimeout:   "2500",
+		},
+		{
+			name:          "custom profile on azure",
+			platform:      configv1.PlatformStatus{Azure: &configv1.AzurePlatformStatus{}},
+			tuning:        `{"tuning": {"profile": "Custom", "heartbeatIntervalMillis": 200, "electionTimeoutMillis": 3000}}`,
+			wantHeartbeat: "200",
+			wantTimeout:   "3000",
+		},
+		{
+			name:          "auto profile without recommendation",
+			tuning:        `{"tuning": {"profile": "Auto"}}`,
+			wantHeartbeat: "100",
+			wantTimeout:   "1000",
+		},
+		{
+			name:   "auto profile",
+			tuning: `{"tuning": {"profile": "Auto"}}`,
+			recommendation: map[string]string{
+				ceohelpers.HeartbeatIntervalMillisKey: "300",
+				ceohelpers.ElectionTimeoutMillisKey:   "3000",
+			},
+			wantHeartbeat: "300",
+			wantTimeout:   "3000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(&configv1.Infrastructure{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Status:     configv1.InfrastructureStatus{PlatformStatus: &tt.platform},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.recommendation != nil {
+				if err := indexer.Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: ceohelpers.TuningRecommendationConfigMapName, Namespace: operatorclient.TargetNamespace},
+					Data:       tt.recommendation,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			envVarContext := envVarContext{
+				infrastructureLister: configv1listers.NewInfrastructureLister(indexer),
+				configmapLister:      corev1listers.NewConfigMapLister(indexer),
+			}
+			if len(tt.tuning) > 0 {
+				envVarContext.spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.tuning)}
+			}
+
+			heartbeat, err := getHeartbeatInterval(envVarContext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if heartbeat["ETCD_HEARTBEAT_INTERVAL"] != tt.wantHeartbeat {
+				t.Fatalf("expected heartbeat interval %s, got %s", tt.wantHeartbeat, heartbeat["ETCD_HEARTBEAT_INTERVAL"])
+			}
+			timeout, err := getElectionTimeout(envVarContext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if timeout["ETCD_ELECTION_TIMEOUT"] != tt.wantTimeout {
+				t.Fatalf("expected election timeout %s, got %s", tt.wantTimeout, timeout["ETCD_ELECTION_TIMEOUT"])
+			}
+		})
+	}
+}
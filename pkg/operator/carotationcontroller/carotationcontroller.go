@@ -0,0 +1,685 @@
+package carotationcontroller
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	operatorv1informers "github.com/openshift/client-go/operator/informers/externalversions/operator/v1"
+	operatorv1listers "github.com/openshift/client-go/operator/listers/operator/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+)
+
+const (
+	// Annotation on a signer secret requesting its rotation, e.g. after a
+	// compromise of the signer key. The value is ignored.
+	RotationRequestedAnnotation = "etcd-operator.alpha.openshift.io/signer-rotation-requested"
+
+	// The minimum percentage duration of a signer. If a signer has less than
+	// this percentage of its duration remaining, it will be rotated.
+	defaultMinDurationPercent = 0.20
+
+	// Matches the lifetime of the signers created by render.
+	signerExpiryDays = 10 * 365
+
+	caBundleKey = "ca-bundle.crt"
+	// Key of the next signer secret holding the signer being replaced.
+	previousCertKey = "previous.crt"
+)
+
+// signerConfig defines the resources depending on a signer.
+type signerConfig struct {
+	// Name of the secret in namespace openshift-config that contains the CA
+	signerSecretName string
+	// Name of the client cert secret in namespace openshift-config issued by
+	// the CA and reissued by this controller. Empty if the client certs of
+	// the CA are issued by EtcdCertSignerController.
+	clientSecretName string
+	// Secrets outside of etcd-all-certs holding certs issued by the CA that
+	// are reissued by EtcdCertSignerController
+	issuedSecrets []secretRef
+	// Names of the revisioned secrets in namespace openshift-kube-apiserver
+	// holding certs issued by the CA that the kube-apiserver operator syncs
+	// from openshift-config. The old signer is only dropped once every
+	// kube-apiserver runs a revision with certs issued by the next signer.
+	kubeAPIServerIssuedSecretNames []string
+	// CA bundles trusting the CA
+	bundles []caBundle
+}
+
+type secretRef struct {
+	namespace string
+	name      string
+}
+
+type caBundle struct {
+	// Name of the configmap in namespace openshift-config
+	name string
+	// Names of the revisioned configmaps in namespace openshift-etcd the bundle is synced to
+	revisionedNames []string
+	// Names of the revisioned configmaps in namespace openshift-kube-apiserver
+	// the kube-apiserver operator syncs the bundle to. The signer is only
+	// replaced once every kube-apiserver runs a revision trusting the next
+	// signer.
+	kubeAPIServerRevisionedNames []string
+}
+
+// Define the signers maintained by the controller.
+var signerConfigs = []signerConfig{
+	{
+		signerSecretName: "etcd-signer",
+		// The dedicated client certs of the consumers outside of the etcd static pods
+		issuedSecrets: []secretRef{
+			{namespace: operatorclient.GlobalUserSpecifiedConfigNamespace, name: "etcd-client"},
+			{namespace: operatorclient.OperatorNamespace, name: "etcd-client"},
+			{namespace: operatorclient.TargetNamespace, name: "etcd-client"},
+		},
+		// etcd verifies the client cert of the kube-apiserver with the signer
+		kubeAPIServerIssuedSecretNames: []string{"etcd-client"},
+		bundles: []caBundle{
+			{name: "etcd-ca-bundle", revisionedNames: []string{"etcd-peer-client-ca"}},
+			{
+				name:            "etcd-serving-ca",
+				revisionedNames: []string{"etcd-serving-ca"},
+				// The kube-apiserver verifies the etcd serving certs with its copy
+				kubeAPIServerRevisionedNames: []string{"etcd-serving-ca"},
+			},
+		},
+	},
+	{
+		signerSecretName: "etcd-metric-signer",
+		clientSecretName: "etcd-metric-client",
+		bundles: []caBundle{
+			{name: "etcd-metric-serving-ca", revisionedNames: []string{"etcd-metrics-proxy-serving-ca", "etcd-metrics-proxy-client-ca"}},
+		},
+	},
+}
+
+// nextSignerSecretName is the name of the secret holding the signer that is
+// replacing signerSecretName. The secret exists for the duration of a rotation.
+func nextSignerSecretName(signerSecretName string) string {
+	return fmt.Sprintf("%s-next", signerSecretName)
+}
+
+type CARotationController struct {
+	operatorClient      v1helpers.StaticPodOperatorClient
+	kubeAPIServerLister operatorv1listers.KubeAPIServerLister
+	secretLister        corev1listers.SecretLister
+	configMapLister     corev1listers.ConfigMapLister
+	secretClient        corev1client.SecretsGetter
+	configMapClient     corev1client.ConfigMapsGetter
+}
+
+// NewCARotationController rotates the etcd signers without disruption. A
+// rotation starts when a signer is close to expiry or was annotated with
+// RotationRequestedAnnotation and proceeds in stages, each of which waits for
+// the static pod revisions to roll out:
+//
+//  1. A new signer is generated and trusted in addition to the old one by
+//     all CA bundles of the signer.
+//  2. Once all members and kube-apiservers run revisions trusting both
+//     signers, the new signer replaces the old one and the client
+//     cert is reissued. EtcdCertSignerController reissues the
+//     peer, serving, metrics and dedicated client certs not issued by the
+//     current signer.
+//  3. Once all members and kube-apiservers use certs issued by the new
+//     signer, the old signer is dropped from the CA bundles.
+//
+// Consumers outside of the etcd static pods and the kube-apiserver are not
+// tracked and are expected to pick up bundle changes within a rollout.
+func NewCARotationController(
+	operatorClient v1helpers.StaticPodOperatorClient,
+	kubeClient corev1client.CoreV1Interface,
+	kubeInformers v1helpers.KubeInformersForNamespaces,
+	kubeAPIServerInformer operatorv1informers.KubeAPIServerInformer,
+	eventRecorder events.Recorder,
+) factory.Controller {
+	c := &CARotationController{
+		operatorClient:      operatorClient,
+		kubeAPIServerLister: kubeAPIServerInformer.Lister(),
+		secretLister:        kubeInformers.SecretLister(),
+		configMapLister:     kubeInformers.ConfigMapLister(),
+		secretClient:        v1helpers.CachedSecretGetter(kubeClient, kubeInformers),
+		configMapClient:     v1helpers.CachedConfigMapGetter(kubeClient, kubeInformers),
+	}
+	return factory.New().ResyncEvery(time.Minute).WithInformers(
+		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Secrets().Informer(),
+		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().ConfigMaps().Informer(),
+		kubeInformers.InformersFor(operatorclient.GlobalUserSpecifiedConfigNamespace).Core().V1().Secrets().Informer(),
+		kubeInformers.InformersFor(operatorclient.GlobalUserSpecifiedConfigNamespace).Core().V1().ConfigMaps().Informer(),
+		kubeInformers.InformersFor(operatorclient.KubeAPIServerNamespace).Core().V1().ConfigMaps().Informer(),
+		kubeInformers.InformersFor(operatorclient.KubeAPIServerNamespace).Core().V1().Secrets().Informer(),
+		kubeAPIServerInformer.Informer(),
+		operatorClient.Informer(),
+	).WithSync(c.sync).ToController("CARotationController", eventRecorder.WithComponentSuffix("ca-rotation-controller"))
+}
+
+func (c *CARotationController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	progress := []string{}
+	for _, config := range signerConfigs {
+		msg, err := c.syncSigner(ctx, syncCtx.Recorder(), config)
+		if err != nil {
+			_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+				Type:    "CARotationControllerDegraded",
+				Status:  operatorv1.ConditionTrue,
+				Reason:  "Error",
+				Message: fmt.Sprintf("%s: %v", config.signerSecretName, err),
+			}))
+			if updateErr != nil {
+				syncCtx.Recorder().Warning("CARotationControllerUpdatingStatus", updateErr.Error())
+			}
+			return err
+		}
+		if len(msg) > 0 {
+			progress = append(progress, fmt.Sprintf("%s: %s", config.signerSecretName, msg))
+		}
+	}
+
+	progressingCondition := operatorv1.OperatorCondition{
+		Type:   "CARotationControllerProgressing",
+		Status: operatorv1.ConditionFalse,
+		Reason: "AsExpected",
+	}
+	if len(progress) > 0 {
+		progressingCondition.Status = operatorv1.ConditionTrue
+		progressingCondition.Reason = "RotatingSigner"
+		progressingCondition.Message = strings.Join(progress, "\n")
+	}
+	_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient,
+		v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+			Type:   "CARotationControllerDegraded",
+			Status: operatorv1.ConditionFalse,
+			Reason: "AsExpected",
+		}),
+		v1helpers.UpdateConditionFn(progressingCondition))
+	return updateErr
+}
+
+// syncSigner advances the rotation of a signer by at most one stage. A message
+// describing the rotation is returned while it is in progress.
+func (c *CARotationController) syncSigner(ctx context.Context, recorder events.Recorder, config signerConfig) (string, error) {
+	signer, err := c.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(config.signerSecretName)
+	if err != nil {
+		return "", err
+	}
+	next, err := c.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(nextSignerSecretName(config.signerSecretName))
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	if next == nil {
+		reason, err := rotationReason(signer, time.Now())
+		if err != nil {
+			return "", err
+		}
+		if len(reason) == 0 {
+			return "", nil
+		}
+		next, err = c.createNextSigner(ctx, recorder, config, signer)
+		if err != nil {
+			return "", err
+		}
+		recorder.Eventf("SignerRotationStarted", "Rotating signer %s: %s", config.signerSecretName, reason)
+	}
+	nextCert := next.Data["tls.crt"]
+	previousCert := next.Data[previousCertKey]
+
+	// Stage 1: trust both signers
+	if err := c.ensureBundles(ctx, recorder, config, nextCert, nil); err != nil {
+		return "", err
+	}
+	if string(signer.Data["tls.crt"]) != string(nextCert) {
+		rolledOut, err := c.bundlesRolledOut(config, nextCert)
+		if err != nil {
+			return "", err
+		}
+		if !rolledOut {
+			return "waiting for the CA bundles trusting the new signer to roll out", nil
+		}
+		notRolledOut, err := c.kubeAPIServerBundlesNotRolledOut(config, nextCert)
+		if err != nil {
+			return "", err
+		}
+		if len(notRolledOut) > 0 {
+			return fmt.Sprintf("waiting for %s to trust the new signer", strings.Join(notRolledOut, ", ")), nil
+		}
+
+		// Stage 2: issue certs with the new signer
+		if err := c.replaceSigner(ctx, recorder, signer, next); err != nil {
+			return "", err
+		}
+		recorder.Eventf("SignerRotated", "Signer %s replaced, reissuing certs", config.signerSecretName)
+		return "reissuing certs with the new signer", nil
+	}
+	if err := c.ensureClientCert(ctx, recorder, config, next); err != nil {
+		return "", err
+	}
+	reissued, err := c.certsReissued(config, previousCert)
+	if err != nil {
+		return "", err
+	}
+	if !reissued {
+		return "waiting for the certs issued by the new signer to roll out", nil
+	}
+	notReissued, err := c.kubeAPIServerCertsNotReissued(config, previousCert)
+	if err != nil {
+		return "", err
+	}
+	if len(notReissued) > 0 {
+		return fmt.Sprintf("waiting for %s to be issued by the new signer", strings.Join(notReissued, ", ")), nil
+	}
+
+	// Stage 3: drop the old signer
+	if err := c.ensureBundles(ctx, recorder, config, nextCert, previousCert); err != nil {
+		return "", err
+	}
+	err = c.secretClient.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Delete(ctx, next.Name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	recorder.Eventf("SignerRotationCompleted", "Signer %s rotated", config.signerSecretName)
+	return "", nil
+}
+
+// rotationReason returns why a signer needs to be rotated, or an empty string
+// if it does not.
+func rotationReason(signer *corev1.Secret, now time.Time) (string, error) {
+	if _, ok := signer.Annotations[RotationRequestedAnnotation]; ok {
+		return "rotation requested", nil
+	}
+	certs, err := crypto.CertsFromPEM(signer.Data["tls.crt"])
+	if err != nil {
+		return "", fmt.Errorf("unable to parse signer: %w", err)
+	}
+	cert := certs[0]
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	minDuration := time.Duration(float64(lifetime.Nanoseconds()) * defaultMinDurationPercent)
+	if now.After(cert.NotAfter.Add(-minDuration)) {
+		return fmt.Sprintf("less than %d%% duration remaining", int64(defaultMinDurationPercent*100)), nil
+	}
+	return "", nil
+}
+
+// createNextSigner generates a signer with the subject of the current signer
+// and stores it along with the current signer in the next signer secret.
+func (c *CARotationController) createNextSigner(ctx context.Context, recorder events.Recorder, config signerConfig, signer *corev1.Secret) (*corev1.Secret, error) {
+	certs, err := crypto.CertsFromPEM(signer.Data["tls.crt"])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse signer: %w", err)
+	}
+	caConfig, err := crypto.MakeSelfSignedCAConfigForSubject(pkix.Name{
+		CommonName:         certs[0].Subject.CommonName,
+		OrganizationalUnit: certs[0].Subject.OrganizationalUnit,
+	}, signerExpiryDays)
+	if err != nil {
+		return nil, err
+	}
+	caCert, caKey, err := caConfig.GetPEMBytes()
+	if err != nil {
+		return nil, err
+	}
+	next := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operatorclient.GlobalUserSpecifiedConfigNamespace,
+			Name:      nextSignerSecretName(config.signerSecretName),
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			"tls.crt":       caCert,
+			"tls.key":       caKey,
+			previousCertKey: signer.Data["tls.crt"],
+		},
+	}
+	next, _, err = resourceapply.ApplySecret(ctx, c.secretClient, recorder, next)
+	return next, err
+}
+
+// ensureBundles ensures the CA bundles of a signer trust the next signer and
+// no longer trust the previous signer if provided.
+func (c *CARotationController) ensureBundles(ctx context.Context, recorder events.Recorder, config signerConfig, nextCert, previousCert []byte) error {
+	add, err := crypto.CertsFromPEM(nextCert)
+	if err != nil {
+		return err
+	}
+	var remove []*x509.Certificate
+	if previousCert != nil {
+		remove, err = crypto.CertsFromPEM(previousCert)
+		if err != nil {
+			return err
+		}
+	}
+	for _, bundle := range config.bundles {
+		configMap, err := c.configMapLister.ConfigMaps(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(bundle.name)
+		if err != nil {
+			return err
+		}
+		certs, err := crypto.CertsFromPEM([]byte(configMap.Data[caBundleKey]))
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %w", bundle.name, err)
+		}
+		certs = mergeCerts(certs, add, remove)
+		bundleBytes, err := crypto.EncodeCertificates(certs...)
+		if err != nil {
+			return err
+		}
+		if string(bundleBytes) == configMap.Data[caBundleKey] {
+			continue
+		}
+		required := configMap.DeepCopy()
+		required.Data[caBundleKey] = string(bundleBytes)
+		if _, _, err := resourceapply.ApplyConfigMap(ctx, c.configMapClient, recorder, required); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeCerts returns certs with add appended and remove dropped, preserving
+// the order of certs.
+func mergeCerts(certs, add, remove []*x509.Certificate) []*x509.Certificate {
+	merged := []*x509.Certificate{}
+	for _, cert := range certs {
+		if !containsCert(remove, cert) && !containsCert(merged, cert) {
+			merged = append(merged, cert)
+		}
+	}
+	for _, cert := range add {
+		if !containsCert(remove, cert) && !containsCert(merged, cert) {
+			merged = append(merged, cert)
+		}
+	}
+	return merged
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// bundlesRolledOut indicates whether every member runs a revision whose CA
+// bundles trust the next signer.
+func (c *CARotationController) bundlesRolledOut(config signerConfig, nextCert []byte) (bool, error) {
+	revisions, err := c.currentRevisions()
+	if err != nil || len(revisions) == 0 {
+		return false, err
+	}
+	next, err := crypto.CertsFromPEM(nextCert)
+	if err != nil {
+		return false, err
+	}
+	for _, revision := range revisions.List() {
+		for _, bundle := range config.bundles {
+			for _, name := range bundle.revisionedNames {
+				revisionedName := fmt.Sprintf("%s-%d", name, revision)
+				trusted, err := c.bundleTrusts(operatorclient.TargetNamespace, revisionedName, next[0])
+				if err != nil || !trusted {
+					return false, err
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+// kubeAPIServerBundlesNotRolledOut returns the revisioned copies of the CA
+// bundles the kube-apiservers are running that do not trust the next signer
+// yet. Missing copies have not been synced yet.
+func (c *CARotationController) kubeAPIServerBundlesNotRolledOut(config signerConfig, nextCert []byte) ([]string, error) {
+	names := []string{}
+	for _, bundle := range config.bundles {
+		names = append(names, bundle.kubeAPIServerRevisionedNames...)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	kubeAPIServer, err := c.kubeAPIServerLister.Get("cluster")
+	if err != nil {
+		return nil, err
+	}
+	revisions := staticPodCurrentRevisions(kubeAPIServer.Status.StaticPodOperatorStatus)
+	if len(revisions) == 0 {
+		return []string{"kube-apiserver revisions"}, nil
+	}
+	next, err := crypto.CertsFromPEM(nextCert)
+	if err != nil {
+		return nil, err
+	}
+	notRolledOut := []string{}
+	for _, revision := range revisions.List() {
+		for _, name := range names {
+			revisionedName := fmt.Sprintf("%s-%d", name, revision)
+			trusted, err := c.bundleTrusts(operatorclient.KubeAPIServerNamespace, revisionedName, next[0])
+			if err != nil {
+				return nil, err
+			}
+			if !trusted {
+				notRolledOut = append(notRolledOut, fmt.Sprintf("%s/%s", operatorclient.KubeAPIServerNamespace, revisionedName))
+			}
+		}
+	}
+	return notRolledOut, nil
+}
+
+// bundleTrusts indicates whether the CA bundle configmap trusts cert. A
+// missing configmap does not trust cert.
+func (c *CARotationController) bundleTrusts(namespace, name string, cert *x509.Certificate) (bool, error) {
+	configMap, err := c.configMapLister.ConfigMaps(namespace).Get(name)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	certs, err := crypto.CertsFromPEM([]byte(configMap.Data[caBundleKey]))
+	if err != nil {
+		return false, fmt.Errorf("unable to parse %s/%s: %w", namespace, name, err)
+	}
+	if !containsCert(certs, cert) {
+		klog.V(4).Infof("%s/%s does not trust %s", namespace, name, cert.Subject)
+		return false, nil
+	}
+	return true, nil
+}
+
+// certsReissued indicates whether every member runs a revision without certs
+// issued by the previous signer and the issued secrets of the signer were
+// reissued. Missing issued secrets are ignored.
+func (c *CARotationController) certsReissued(config signerConfig, previousCert []byte) (bool, error) {
+	for _, ref := range config.issuedSecrets {
+		secret, err := c.secretLister.Secrets(ref.namespace).Get(ref.name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		issued, err := tlshelpers.IsIssuedBy(secret.Data["tls.crt"], previousCert)
+		if err != nil {
+			return false, fmt.Errorf("unable to verify %s/%s: %w", ref.namespace, ref.name, err)
+		}
+		if issued {
+			klog.V(4).Infof("%s/%s is issued by the previous signer", ref.namespace, ref.name)
+			return false, nil
+		}
+	}
+
+	revisions, err := c.currentRevisions()
+	if err != nil || len(revisions) == 0 {
+		return false, err
+	}
+	for _, revision := range revisions.List() {
+		revisionedName := fmt.Sprintf("%s-%d", tlshelpers.EtcdAllCertsSecretName, revision)
+		secret, err := c.secretLister.Secrets(operatorclient.TargetNamespace).Get(revisionedName)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		for name, data := range secret.Data {
+			if !strings.HasSuffix(name, ".crt") {
+				continue
+			}
+			issued, err := tlshelpers.IsIssuedBy(data, previousCert)
+			if err != nil {
+				return false, fmt.Errorf("unable to verify %s/%s: %w", revisionedName, name, err)
+			}
+			if issued {
+				klog.V(4).Infof("%s/%s is issued by the previous signer", revisionedName, name)
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// kubeAPIServerCertsNotReissued returns the revisioned secrets the
+// kube-apiservers are running that hold certs issued by the previous signer.
+// Missing secrets have not been synced yet.
+func (c *CARotationController) kubeAPIServerCertsNotReissued(config signerConfig, previousCert []byte) ([]string, error) {
+	if len(config.kubeAPIServerIssuedSecretNames) == 0 {
+		return nil, nil
+	}
+	kubeAPIServer, err := c.kubeAPIServerLister.Get("cluster")
+	if err != nil {
+		return nil, err
+	}
+	revisions := staticPodCurrentRevisions(kubeAPIServer.Status.StaticPodOperatorStatus)
+	if len(revisions) == 0 {
+		return []string{"kube-apiserver revisions"}, nil
+	}
+	notReissued := []string{}
+	for _, revision := range revisions.List() {
+		for _, name := range config.kubeAPIServerIssuedSecretNames {
+			revisionedName := fmt.Sprintf("%s-%d", name, revision)
+			secret, err := c.secretLister.Secrets(operatorclient.KubeAPIServerNamespace).Get(revisionedName)
+			if errors.IsNotFound(err) {
+				notReissued = append(notReissued, fmt.Sprintf("%s/%s", operatorclient.KubeAPIServerNamespace, revisionedName))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			issued, err := tlshelpers.IsIssuedBy(secret.Data["tls.crt"], previousCert)
+			if err != nil {
+				return nil, fmt.Errorf("unable to verify %s/%s: %w", operatorclient.KubeAPIServerNamespace, revisionedName, err)
+			}
+			if issued {
+				notReissued = append(notReissued, fmt.Sprintf("%s/%s", operatorclient.KubeAPIServerNamespace, revisionedName))
+			}
+		}
+	}
+	return notReissued, nil
+}
+
+// currentRevisions returns the revisions the members are running. An empty
+// set is returned while a member has not yet reached a revision.
+func (c *CARotationController) currentRevisions() (sets.Int32, error) {
+	_, status, _, err := c.operatorClient.GetStaticPodOperatorState()
+	if err != nil {
+		return nil, err
+	}
+	return staticPodCurrentRevisions(*status), nil
+}
+
+// staticPodCurrentRevisions returns the revisions the nodes of a static pod
+// operator are running. An empty set is returned while a node has not yet
+// reached a revision.
+func staticPodCurrentRevisions(status operatorv1.StaticPodOperatorStatus) sets.Int32 {
+	revisions := sets.NewInt32()
+	for _, nodeStatus := range status.NodeStatuses {
+		if nodeStatus.CurrentRevision == 0 {
+			return sets.NewInt32()
+		}
+		revisions.Insert(nodeStatus.CurrentRevision)
+	}
+	return revisions
+}
+
+// replaceSigner replaces the signer with the next signer and clears a rotation
+// request.
+func (c *CARotationController) replaceSigner(ctx context.Context, recorder events.Recorder, signer, next *corev1.Secret) error {
+	required := signer.DeepCopy()
+	delete(required.Annotations, RotationRequestedAnnotation)
+	required.Data = map[string][]byte{
+		"tls.crt": next.Data["tls.crt"],
+		"tls.key": next.Data["tls.key"],
+	}
+	_, err := c.secretClient.Secrets(required.Namespace).Update(ctx, required, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	recorder.Eventf("SecretUpdated", "Updated Secret/%s -n %s because it was replaced by the next signer", required.Name, required.Namespace)
+	return nil
+}
+
+// ensureClientCert reissues the client cert of a signer with the next signer,
+// preserving the user and lifetime of the current client cert.
+func (c *CARotationController) ensureClientCert(ctx context.Context, recorder events.Recorder, config signerConfig, next *corev1.Secret) error {
+	if len(config.clientSecretName) == 0 {
+		return nil
+	}
+	clientSecret, err := c.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(config.clientSecretName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	issued, err := tlshelpers.IsIssuedBy(clientSecret.Data["tls.crt"], next.Data["tls.crt"])
+	if err != nil || issued {
+		return err
+	}
+
+	certs, err := crypto.CertsFromPEM(clientSecret.Data["tls.crt"])
+	if err != nil {
+		return err
+	}
+	ca, err := crypto.GetCAFromBytes(next.Data["tls.crt"], next.Data["tls.key"])
+	if err != nil {
+		return err
+	}
+	clientUser := &user.DefaultInfo{
+		Name:   certs[0].Subject.CommonName,
+		Groups: certs[0].Subject.Organization,
+	}
+	clientConfig, err := ca.MakeClientCertificateForDuration(clientUser, certs[0].NotAfter.Sub(certs[0].NotBefore))
+	if err != nil {
+		return err
+	}
+	clientCert, clientKey, err := clientConfig.GetPEMBytes()
+	if err != nil {
+		return err
+	}
+	required := clientSecret.DeepCopy()
+	required.Data = map[string][]byte{
+		"tls.crt": clientCert,
+		"tls.key": clientKey,
+	}
+	_, _, err = resourceapply.ApplySecret(ctx, c.secretClient, recorder, required)
+	return err
+}
@@ -0,0 +1,605 @@
+package carotationcontroller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	operatorv1listers "github.com/openshift/client-go/operator/listers/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	u "github.com/openshift/cluster-etcd-operator/pkg/testutils"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+)
+
+// The metric signer has a single bundle and is simpler to set up.
+var testConfig = signerConfigs[1]
+
+type testSigner struct {
+	cert []byte
+	key  []byte
+}
+
+func newTestSigner(t *testing.T, notBefore, notAfter time.Time) testSigner {
+	caConfig, err := crypto.MakeSelfSignedCAConfigForSubject(pkix.Name{CommonName: testConfig.signerSecretName}, 100)
+	if err != nil {
+		t.Fatalf("Failed to create ca config: %v", err)
+	}
+	if !notBefore.IsZero() {
+		caConfig = resign(t, caConfig, notBefore, notAfter)
+	}
+	cert, key, err := caConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatalf("Error converting ca to bytes: %v", err)
+	}
+	return testSigner{cert: cert, key: key}
+}
+
+// resign returns the self-signed ca with a different validity period.
+func resign(t *testing.T, caConfig *crypto.TLSCertificateConfig, notBefore, notAfter time.Time) *crypto.TLSCertificateConfig {
+	caCert := caConfig.Certs[0]
+	caCert.NotBefore = notBefore
+	caCert.NotAfter = notAfter
+	rawCert, err := x509.CreateCertificate(rand.Reader, caCert, caCert, caCert.PublicKey, caConfig.Key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	parsedCerts, err := x509.ParseCertificates(rawCert)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+	return &crypto.TLSCertificateConfig{
+		Certs: []*x509.Certificate{parsedCerts[0]},
+		Key:   caConfig.Key,
+	}
+}
+
+func (s testSigner) issue(t *testing.T) []byte {
+	ca, err := crypto.GetCAFromBytes(s.cert, s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certConfig, err := ca.MakeServerCert(sets.NewString("127.0.0.1"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _, err := certConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (s testSigner) bundle(t *testing.T, others ...testSigner) string {
+	bundle := string(s.cert)
+	for _, other := range others {
+		bundle += string(other.cert)
+	}
+	return bundle
+}
+
+func signerSecret(name string, signer testSigner, annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   operatorclient.GlobalUserSpecifiedConfigNamespace,
+			Name:        name,
+			Annotations: annotations,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{"tls.crt": signer.cert, "tls.key": signer.key},
+	}
+}
+
+func nextSecret(next, previous testSigner) *corev1.Secret {
+	secret := signerSecret(nextSignerSecretName(testConfig.signerSecretName), next, nil)
+	secret.Data[previousCertKey] = previous.cert
+	return secret
+}
+
+func bundleConfigMap(namespace, name, bundle string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Data:       map[string]string{caBundleKey: bundle},
+	}
+}
+
+// revisionedBundles returns the revisioned configmaps of the test signer bundles.
+func revisionedBundles(revision, bundle string) []runtime.Object {
+	objects := []runtime.Object{}
+	for _, caBundle := range testConfig.bundles {
+		for _, name := range caBundle.revisionedNames {
+			objects = append(objects, bundleConfigMap(operatorclient.TargetNamespace, name+"-"+revision, bundle))
+		}
+	}
+	return objects
+}
+
+func allCertsSecret(revision string, certs ...[]byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: operatorclient.TargetNamespace,
+			Name:      tlshelpers.EtcdAllCertsSecretName + "-" + revision,
+		},
+		Data: map[string][]byte{},
+	}
+	for i, cert := range certs {
+		secret.Data[fmt.Sprintf("etcd-serving-master-%d.crt", i)] = cert
+	}
+	return secret
+}
+
+func TestRotationReason(t *testing.T) {
+	now := time.Now()
+	testCases := map[string]struct {
+		signer      testSigner
+		annotations map[string]string
+		expectedMsg string
+	}{
+		"valid": {
+			signer: newTestSigner(t, time.Time{}, time.Time{}),
+		},
+		"rotation requested": {
+			signer:      newTestSigner(t, time.Time{}, time.Time{}),
+			annotations: map[string]string{RotationRequestedAnnotation: ""},
+			expectedMsg: "rotation requested",
+		},
+		"less than minimum duration remaining": {
+			// Issued 9 hours ago, 1 hour remaining: < 20% duration remaining
+			signer:      newTestSigner(t, now.Add(-9*time.Hour), now.Add(time.Hour)),
+			expectedMsg: "less than 20% duration remaining",
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			msg, err := rotationReason(signerSecret(testConfig.signerSecretName, tc.signer, tc.annotations), now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg != tc.expectedMsg {
+				t.Fatalf("expected %q, got %q", tc.expectedMsg, msg)
+			}
+		})
+	}
+}
+
+func TestSyncSigner(t *testing.T) {
+	previous := newTestSigner(t, time.Time{}, time.Time{})
+	next := newTestSigner(t, time.Time{}, time.Time{})
+	bundleName := testConfig.bundles[0].name
+	clientSecret := signerSecret(testConfig.clientSecretName, testSigner{cert: previous.issue(t), key: []byte("key")}, nil)
+
+	testCases := map[string]struct {
+		objects      []runtime.Object
+		expectedMsg  string
+		validateFunc func(t *testing.T, actions []clientgotesting.Action)
+	}{
+		"signer not due for rotation": {
+			objects: []runtime.Object{
+				signerSecret(testConfig.signerSecretName, previous, nil),
+				bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t)),
+			},
+			validateFunc: func(t *testing.T, actions []clientgotesting.Action) {
+				if len(actions) > 0 {
+					t.Fatalf("unexpected actions: %v", actions)
+				}
+			},
+		},
+		"rotation requested creates the next signer and trusts it": {
+			objects: []runtime.Object{
+				signerSecret(testConfig.signerSecretName, previous, map[string]string{RotationRequestedAnnotation: ""}),
+				bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t)),
+			},
+			expectedMsg: "waiting for the CA bundles trusting the new signer to roll out",
+			validateFunc: func(t *testing.T, actions []clientgotesting.Action) {
+				created := createdSecret(t, actions, nextSignerSecretName(testConfig.signerSecretName))
+				if string(created.Data[previousCertKey]) != string(previous.cert) {
+					t.Fatalf("next signer does not record the previous signer")
+				}
+				bundle := updatedBundle(t, actions, bundleName)
+				requireBundle(t, bundle, previous.cert, created.Data["tls.crt"])
+			},
+		},
+		"signer is not replaced before the bundles roll out": {
+			objects: append([]runtime.Object{
+				signerSecret(testConfig.signerSecretName, previous, nil),
+				nextSecret(next, previous),
+				bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t, next)),
+			}, revisionedBundles("1", previous.bundle(t))...),
+			expectedMsg: "waiting for the CA bundles trusting the new signer to roll out",
+			validateFunc: func(t *testing.T, actions []clientgotesting.Action) {
+				if len(actions) > 0 {
+					t.Fatalf("unexpected actions: %v", actions)
+				}
+			},
+		},
+		"signer is replaced once the bundles rolled out": {
+			objects: append([]runtime.Object{
+				signerSecret(testConfig.signerSecretName, previous, map[string]string{RotationRequestedAnnotation: ""}),
+				nextSecret(next, previous),
+				bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t, next)),
+			}, revisionedBundles("1", previous.bundle(t, next))...),
+			expectedMsg: "reissuing certs with the new signer",
+			validateFunc: func(t *testing.T, actions []clientgotesting.Action) {
+				updated := updatedSecret(t, actions, testConfig.signerSecretName)
+				if string(updated.Data["tls.crt"]) != string(next.cert) || string(updated.Data["tls.key"]) != string(next.key) {
+					t.Fatalf("signer was not replaced")
+				}
+				if _, ok := updated.Annotations[RotationRequestedAnnotation]; ok {
+					t.Fatalf("rotation request was not cleared")
+				}
+			},
+		},
+		"client cert is reissued while waiting for certs to roll out": {
+			objects: []runtime.Object{
+				signerSecret(testConfig.signerSecretName, next, nil),
+				nextSecret(next, previous),
+				clientSecret,
+				bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t, next)),
+				allCertsSecret("1", previous.issue(t), next.issue(t)),
+			},
+			expectedMsg: "waiting for the certs issued by the new signer to roll out",
+			validateFunc: func(t *testing.T, actions []clientgotesting.Action) {
+				updated := updatedSecret(t, actions, testConfig.clientSecretName)
+				issued, err := tlshelpers.IsIssuedBy(updated.Data["tls.crt"], next.cert)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !issued {
+					t.Fatalf("client cert was not reissued by the new signer")
+				}
+			},
+		},
+		"previous signer is dropped once certs rolled out": {
+			objects: []runtime.Object{
+				signerSecret(testConfig.signerSecretName, next, nil),
+				nextSecret(next, previous),
+				bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t, next)),
+				allCertsSecret("1", next.issue(t), next.issue(t)),
+			},
+			validateFunc: func(t *testing.T, actions []clientgotesting.Action) {
+				requireBundle(t, updatedBundle(t, actions, bundleName), next.cert)
+				for _, action := range actions {
+					if action.Matches("delete", "secrets") && action.(clientgotesting.DeleteAction).GetName() == nextSignerSecretName(testConfig.signerSecretName) {
+						return
+					}
+				}
+				t.Fatalf("next signer secret was not deleted")
+			},
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			fakeKubeClient, controller := setupController(t, tc.objects)
+			msg, err := controller.syncSigner(context.TODO(), events.NewInMemoryRecorder("test"), testConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg != tc.expectedMsg {
+				t.Fatalf("expected %q, got %q", tc.expectedMsg, msg)
+			}
+			tc.validateFunc(t, writeActions(fakeKubeClient.Actions()))
+		})
+	}
+}
+
+func TestSignerIsNotReplacedBeforeKubeAPIServerRollsOut(t *testing.T) {
+	previous := newTestSigner(t, time.Time{}, time.Time{})
+	next := newTestSigner(t, time.Time{}, time.Time{})
+	config := testConfig
+	config.bundles = []caBundle{testConfig.bundles[0]}
+	config.bundles[0].kubeAPIServerRevisionedNames = []string{"etcd-serving-ca"}
+	objects := append([]runtime.Object{
+		signerSecret(testConfig.signerSecretName, previous, nil),
+		nextSecret(next, previous),
+		bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, config.bundles[0].name, previous.bundle(t, next)),
+		// the unrevisioned copy is not read by the kube-apiserver pods
+		bundleConfigMap(operatorclient.KubeAPIServerNamespace, "etcd-serving-ca", previous.bundle(t, next)),
+	}, revisionedBundles("1", previous.bundle(t, next))...)
+
+	testCases := map[string]struct {
+		revisions      []int32
+		copies         []runtime.Object
+		expectedMsg    string
+		expectReplaced bool
+	}{
+		"revisioned copy missing": {
+			revisions:   []int32{3, 3, 3},
+			expectedMsg: "waiting for openshift-kube-apiserver/etcd-serving-ca-3 to trust the new signer",
+		},
+		"node not yet at a revision": {
+			revisions:   []int32{3, 3, 0},
+			copies:      []runtime.Object{bundleConfigMap(operatorclient.KubeAPIServerNamespace, "etcd-serving-ca-3", previous.bundle(t, next))},
+			expectedMsg: "waiting for kube-apiserver revisions to trust the new signer",
+		},
+		"old revision does not trust the next signer": {
+			revisions: []int32{2, 3, 3},
+			copies: []runtime.Object{
+				bundleConfigMap(operatorclient.KubeAPIServerNamespace, "etcd-serving-ca-2", previous.bundle(t)),
+				bundleConfigMap(operatorclient.KubeAPIServerNamespace, "etcd-serving-ca-3", previous.bundle(t, next)),
+			},
+			expectedMsg: "waiting for openshift-kube-apiserver/etcd-serving-ca-2 to trust the new signer",
+		},
+		"all revisions trust the next signer": {
+			revisions: []int32{3, 3, 3},
+			copies: []runtime.Object{
+				bundleConfigMap(operatorclient.KubeAPIServerNamespace, "etcd-serving-ca-2", previous.bundle(t)),
+				bundleConfigMap(operatorclient.KubeAPIServerNamespace, "etcd-serving-ca-3", previous.bundle(t, next)),
+			},
+			expectedMsg:    "reissuing certs with the new signer",
+			expectReplaced: true,
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			testObjects := append(append([]runtime.Object{kubeAPIServer(tc.revisions...)}, objects...), tc.copies...)
+			fakeKubeClient, controller := setupController(t, testObjects)
+			msg, err := controller.syncSigner(context.TODO(), events.NewInMemoryRecorder("test"), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg != tc.expectedMsg {
+				t.Fatalf("expected %q, got %q", tc.expectedMsg, msg)
+			}
+			replaced := false
+			for _, action := range writeActions(fakeKubeClient.Actions()) {
+				if action.Matches("update", "secrets") && action.(clientgotesting.UpdateAction).GetObject().(*corev1.Secret).Name == testConfig.signerSecretName {
+					replaced = true
+				}
+			}
+			if replaced != tc.expectReplaced {
+				t.Fatalf("expected signer replaced %v, got %v", tc.expectReplaced, replaced)
+			}
+		})
+	}
+}
+
+func TestPreviousSignerIsNotDroppedBeforeKubeAPIServerRollsOut(t *testing.T) {
+	previous := newTestSigner(t, time.Time{}, time.Time{})
+	next := newTestSigner(t, time.Time{}, time.Time{})
+	config := testConfig
+	config.kubeAPIServerIssuedSecretNames = []string{"etcd-client"}
+	bundleName := config.bundles[0].name
+	objects := []runtime.Object{
+		signerSecret(testConfig.signerSecretName, next, nil),
+		nextSecret(next, previous),
+		bundleConfigMap(operatorclient.GlobalUserSpecifiedConfigNamespace, bundleName, previous.bundle(t, next)),
+		allCertsSecret("1", next.issue(t), next.issue(t)),
+	}
+	clientSecret := func(revision string, signer testSigner) *corev1.Secret {
+		secret := signerSecret("etcd-client-"+revision, testSigner{cert: signer.issue(t), key: []byte("key")}, nil)
+		secret.Namespace = operatorclient.KubeAPIServerNamespace
+		return secret
+	}
+
+	testCases := map[string]struct {
+		revisions     []int32
+		secrets       []runtime.Object
+		expectedMsg   string
+		expectDropped bool
+	}{
+		"revisioned secret missing": {
+			revisions:   []int32{3, 3, 3},
+			expectedMsg: "waiting for openshift-kube-apiserver/etcd-client-3 to be issued by the new signer",
+		},
+		"node not yet at a revision": {
+			revisions:   []int32{3, 3, 0},
+			secrets:     []runtime.Object{clientSecret("3", next)},
+			expectedMsg: "waiting for kube-apiserver revisions to be issued by the new signer",
+		},
+		"old revision presents a client cert of the previous signer": {
+			revisions:   []int32{2, 3, 3},
+			secrets:     []runtime.Object{clientSecret("2", previous), clientSecret("3", next)},
+			expectedMsg: "waiting for openshift-kube-apiserver/etcd-client-2 to be issued by the new signer",
+		},
+		"all revisions present a client cert of the next signer": {
+			revisions:     []int32{3, 3, 3},
+			secrets:       []runtime.Object{clientSecret("2", previous), clientSecret("3", next)},
+			expectDropped: true,
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			testObjects := append(append([]runtime.Object{kubeAPIServer(tc.revisions...)}, objects...), tc.secrets...)
+			fakeKubeClient, controller := setupController(t, testObjects)
+			msg, err := controller.syncSigner(context.TODO(), events.NewInMemoryRecorder("test"), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg != tc.expectedMsg {
+				t.Fatalf("expected %q, got %q", tc.expectedMsg, msg)
+			}
+			dropped := false
+			for _, action := range writeActions(fakeKubeClient.Actions()) {
+				if action.Matches("delete", "secrets") && action.(clientgotesting.DeleteAction).GetName() == nextSignerSecretName(testConfig.signerSecretName) {
+					dropped = true
+				}
+			}
+			if dropped != tc.expectDropped {
+				t.Fatalf("expected previous signer dropped %v, got %v", tc.expectDropped, dropped)
+			}
+		})
+	}
+}
+
+// kubeAPIServer returns the kube-apiserver operator with nodes at the provided
+// current revisions.
+func kubeAPIServer(revisions ...int32) *operatorv1.KubeAPIServer {
+	kas := &operatorv1.KubeAPIServer{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+	for i, revision := range revisions {
+		kas.Status.NodeStatuses = append(kas.Status.NodeStatuses, operatorv1.NodeStatus{
+			NodeName:        fmt.Sprintf("master-%d", i),
+			CurrentRevision: revision,
+		})
+	}
+	return kas
+}
+
+func TestCertsReissuedIssuedSecrets(t *testing.T) {
+	previous := newTestSigner(t, time.Time{}, time.Time{})
+	next := newTestSigner(t, time.Time{}, time.Time{})
+	config := testConfig
+	config.issuedSecrets = []secretRef{
+		{namespace: operatorclient.TargetNamespace, name: "etcd-client"},
+		{namespace: operatorclient.OperatorNamespace, name: "missing"},
+	}
+	issuedSecret := func(signer testSigner) *corev1.Secret {
+		secret := signerSecret("etcd-client", testSigner{cert: signer.issue(t), key: []byte("key")}, nil)
+		secret.Namespace = operatorclient.TargetNamespace
+		return secret
+	}
+
+	testCases := map[string]struct {
+		objects  []runtime.Object
+		expected bool
+	}{
+		"issued secret not yet reissued": {
+			objects:  []runtime.Object{allCertsSecret("1", next.issue(t)), issuedSecret(previous)},
+			expected: false,
+		},
+		"issued secret reissued": {
+			objects:  []runtime.Object{allCertsSecret("1", next.issue(t)), issuedSecret(next)},
+			expected: true,
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			_, controller := setupController(t, tc.objects)
+			reissued, err := controller.certsReissued(config, previous.cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reissued != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, reissued)
+			}
+		})
+	}
+}
+
+func setupController(t *testing.T, objects []runtime.Object) (*fake.Clientset, *CARotationController) {
+	kubeObjects := []runtime.Object{}
+	for _, obj := range objects {
+		if _, ok := obj.(*operatorv1.KubeAPIServer); !ok {
+			kubeObjects = append(kubeObjects, obj)
+		}
+	}
+	fakeKubeClient := fake.NewSimpleClientset(kubeObjects...)
+	indexer := cache.NewIndexer(
+		cache.MetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	for _, obj := range objects {
+		if err := indexer.Add(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fakeOperatorClient := v1helpers.NewFakeStaticPodOperatorClient(
+		&operatorv1.StaticPodOperatorSpec{},
+		u.StaticPodOperatorStatus(
+			u.WithLatestRevision(1),
+			u.WithNodeStatusAtCurrentRevision(1),
+			u.WithNodeStatusAtCurrentRevision(1),
+			u.WithNodeStatusAtCurrentRevision(1),
+		),
+		nil,
+		nil,
+	)
+	controller := &CARotationController{
+		operatorClient:      fakeOperatorClient,
+		kubeAPIServerLister: operatorv1listers.NewKubeAPIServerLister(indexer),
+		secretLister:        corev1listers.NewSecretLister(indexer),
+		configMapLister:     corev1listers.NewConfigMapLister(indexer),
+		secretClient:        fakeKubeClient.CoreV1(),
+		configMapClient:     fakeKubeClient.CoreV1(),
+	}
+	return fakeKubeClient, controller
+}
+
+func writeActions(actions []clientgotesting.Action) []clientgotesting.Action {
+	writes := []clientgotesting.Action{}
+	for _, action := range actions {
+		if action.GetVerb() != "get" && action.GetVerb() != "list" && action.GetVerb() != "watch" {
+			writes = append(writes, action)
+		}
+	}
+	return writes
+}
+
+func createdSecret(t *testing.T, actions []clientgotesting.Action, name string) *corev1.Secret {
+	for _, action := range actions {
+		if action.Matches("create", "secrets") {
+			secret := action.(clientgotesting.CreateAction).GetObject().(*corev1.Secret)
+			if secret.Name == name {
+				return secret
+			}
+		}
+	}
+	t.Fatalf("secret %s was not created", name)
+	return nil
+}
+
+func updatedSecret(t *testing.T, actions []clientgotesting.Action, name string) *corev1.Secret {
+	for _, action := range actions {
+		if action.Matches("update", "secrets") {
+			secret := action.(clientgotesting.UpdateAction).GetObject().(*corev1.Secret)
+			if secret.Name == name {
+				return secret
+			}
+		}
+	}
+	t.Fatalf("secret %s was not updated", name)
+	return nil
+}
+
+func updatedBundle(t *testing.T, actions []clientgotesting.Action, name string) string {
+	for _, action := range actions {
+		if action.Matches("update", "configmaps") {
+			configMap := action.(clientgotesting.UpdateAction).GetObject().(*corev1.ConfigMap)
+			if configMap.Name == name {
+				return configMap.Data[caBundleKey]
+			}
+		}
+	}
+	t.Fatalf("configmap %s was not updated", name)
+	return ""
+}
+
+// requireBundle checks that bundle contains exactly the provided certs.
+func requireBundle(t *testing.T, bundle string, expected ...[]byte) {
+	certs, err := crypto.CertsFromPEM([]byte(bundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedCerts := []*x509.Certificate{}
+	for _, pem := range expected {
+		parsed, err := crypto.CertsFromPEM(pem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedCerts = append(expectedCerts, parsed...)
+	}
+	if len(certs) != len(expectedCerts) {
+		t.Fatalf("expected %d certs in bundle, got %d:\n%s", len(expectedCerts), len(certs), strings.TrimSpace(bundle))
+	}
+	for _, cert := range expectedCerts {
+		if !containsCert(certs, cert) {
+			t.Fatalf("bundle is missing %s", cert.Subject)
+		}
+	}
+}
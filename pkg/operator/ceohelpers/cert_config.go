@@ -0,0 +1,96 @@
+package ceohelpers
+
+import (
+	"fmt"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+)
+
+// CertConfig overrides the defaults of a class of etcd member certs. Zero
+// values keep the default.
+type CertConfig struct {
+	Validity time.Duration
+	// Percentage of the validity remaining at which a cert is regenerated.
+	RotationThresholdPercent int
+	KeyAlgorithm             tlshelpers.KeyAlgorithm
+}
+
+type certConfigOverride struct {
+	Validity                 string `json:"validity,omitempty"`
+	RotationThresholdPercent int    `json:"rotationThresholdPercent,omitempty"`
+	KeyAlgorithm             string `json:"keyAlgorithm,omitempty"`
+}
+
+// GetCertConfigs returns the cert configuration by class from the
+// certificates key of the unsupported config overrides, e.g.
+//
+//	certificates:
+//	  peer:
+//	    validity: 2160h
+//	    rotationThresholdPercent: 30
+//	    keyAlgorithm: ECDSA-P256
+//
+// Unknown classes or fields and invalid values are an error.
+func GetCertConfigs(spec *operatorv1.OperatorSpec, classes []string) (map[string]CertConfig, error) {
+	overrides := map[string]certConfigOverride{}
+	found, err := decodeUnsupportedConfigKey(spec, "certificates", &overrides)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	validClasses := sets.NewString(classes...)
+	configs := map[string]CertConfig{}
+	for class, override := range overrides {
+		if !validClasses.Has(class) {
+			return nil, fmt.Errorf("invalid certificates config: unknown cert class %q, must be one of %v", class, validClasses.List())
+		}
+		config, err := override.toCertConfig()
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificates config for %s: %w", class, err)
+		}
+		configs[class] = config
+	}
+	return configs, nil
+}
+
+func (o certConfigOverride) toCertConfig() (CertConfig, error) {
+	config := CertConfig{
+		RotationThresholdPercent: o.RotationThresholdPercent,
+		KeyAlgorithm:             tlshelpers.KeyAlgorithm(o.KeyAlgorithm),
+	}
+	// the overrides are validated as the cert options they result in, unset
+	// fields keep the default.
+	opts := tlshelpers.DefaultCertOptions()
+	if len(o.Validity) > 0 {
+		validity, err := time.ParseDuration(o.Validity)
+		if err != nil {
+			return config, fmt.Errorf("invalid validity: %w", err)
+		}
+		config.Validity = validity
+		opts.Validity = validity
+	}
+	if len(config.KeyAlgorithm) > 0 {
+		opts.KeyAlgorithm = config.KeyAlgorithm
+	}
+	if err := opts.Validate(); err != nil {
+		return config, err
+	}
+	if config.RotationThresholdPercent < 0 || config.RotationThresholdPercent > 99 {
+		return config, fmt.Errorf("rotationThresholdPercent %d must be between 1 and 99, or 0 to keep the default", config.RotationThresholdPercent)
+	}
+	// every regeneration rolls out a static pod revision, a cert must be in
+	// use for at least the minimum validity before it is regenerated.
+	thresholdPercent := tlshelpers.DefaultRotationThresholdPercent
+	if config.RotationThresholdPercent > 0 {
+		thresholdPercent = config.RotationThresholdPercent
+	}
+	if interval := opts.Validity * time.Duration(100-thresholdPercent) / 100; interval < tlshelpers.MinCertValidity {
+		return config, fmt.Errorf("validity %s with rotationThresholdPercent %d regenerates certs every %s, less than the minimum of %s",
+			opts.Validity, thresholdPercent, interval, tlshelpers.MinCertValidity)
+	}
+	return config, nil
+}
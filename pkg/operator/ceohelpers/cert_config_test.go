@@ -0,0 +1,134 @@
+package ceohelpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+)
+
+func TestGetCertConfigs(t *testing.T) {
+	classes := []string{"peer", "serving", "serving-metrics"}
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]CertConfig
+		wantErr bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "unrelated overrides",
+			raw:  "useUnsupportedUnsafeNonHANonProductionUnstableEtcd: true",
+		},
+		{
+			name: "all fields",
+			raw: `
+certificates:
+  peer:
+    validity: 720h
+    rotationThresholdPercent: 30
+    keyAlgorithm: ECDSA-P256
+  serving-metrics:
+    keyAlgorithm: RSA
+`,
+			want: map[string]CertConfig{
+				"peer": {
+					Validity:                 720 * time.Hour,
+					RotationThresholdPercent: 30,
+					KeyAlgorithm:             tlshelpers.ECDSAP256KeyAlgorithm,
+				},
+				"serving-metrics": {
+					KeyAlgorithm: tlshelpers.RSAKeyAlgorithm,
+				},
+			},
+		},
+		{
+			name: "json",
+			raw:  `{"certificates": {"serving": {"validity": "48h"}}}`,
+			want: map[string]CertConfig{
+				"serving": {Validity: 48 * time.Hour},
+			},
+		},
+		{
+			name:    "unknown class",
+			raw:     `{"certificates": {"client": {"validity": "48h"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			raw:     `{"certificates": {"peer": {"lifetime": "48h"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid validity",
+			raw:     `{"certificates": {"peer": {"validity": "2 days"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "validity below minimum",
+			raw:     `{"certificates": {"peer": {"validity": "1h"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "zero validity",
+			raw:     `{"certificates": {"peer": {"validity": "0s"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "rotation threshold out of range",
+			raw:     `{"certificates": {"peer": {"rotationThresholdPercent": 100}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative rotation threshold",
+			raw:     `{"certificates": {"peer": {"rotationThresholdPercent": -1}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "regeneration interval below minimum",
+			raw:     `{"certificates": {"peer": {"validity": "24h", "rotationThresholdPercent": 99}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "regeneration interval below minimum with default threshold",
+			raw:     `{"certificates": {"peer": {"validity": "24h"}}}`,
+			wantErr: true,
+		},
+		{
+			name: "regeneration interval at minimum",
+			raw:  `{"certificates": {"peer": {"validity": "48h", "rotationThresholdPercent": 50}}}`,
+			want: map[string]CertConfig{
+				"peer": {Validity: 48 * time.Hour, RotationThresholdPercent: 50},
+			},
+		},
+		{
+			name:    "unsupported key algorithm",
+			raw:     `{"certificates": {"peer": {"keyAlgorithm": "ECDSA-P384"}}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &operatorv1.OperatorSpec{}
+			if len(tt.raw) > 0 {
+				spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.raw)}
+			}
+			got, err := GetCertConfigs(spec, classes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCertConfigs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCertConfigs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
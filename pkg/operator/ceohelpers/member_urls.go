@@ -0,0 +1,102 @@
+package ceohelpers
+
+import (
+	"fmt"
+	"strings"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+)
+
+// MemberURLMode selects the host of the peer and client URLs advertised by
+// the etcd members.
+type MemberURLMode string
+
+const (
+	// IPMemberURLMode advertises the preferred internal IP of the node.
+	IPMemberURLMode MemberURLMode = "IP"
+	// DNSMemberURLMode advertises a DNS name of the node that stays stable
+	// when the IP of the node changes.
+	DNSMemberURLMode MemberURLMode = "DNS"
+
+	// MemberURLHostsConfigMapName is the name of the configmap recording the
+	// peer URL host of each etcd member by node name. Members without an
+	// entry use the preferred internal IP of their node.
+	MemberURLHostsConfigMapName = "etcd-member-url-hosts"
+)
+
+// MemberURLConfig configures the host of the etcd member URLs.
+type MemberURLConfig struct {
+	Mode MemberURLMode `json:"mode,omitempty"`
+	// Domain appended to the node name to form the DNS name of a member. The
+	// node name is used as is if empty.
+	Domain string `json:"domain,omitempty"`
+}
+
+// GetMemberURLConfig returns the member URL config from the memberURLs key of
+// the unsupported config overrides, e.g.
+//
+//	memberURLs:
+//	  mode: DNS
+//	  domain: example.com
+//
+// The mode defaults to IP. Unknown fields and invalid values are an error.
+func GetMemberURLConfig(spec *operatorv1.OperatorSpec) (MemberURLConfig, error) {
+	config := MemberURLConfig{Mode: IPMemberURLMode}
+	found, err := decodeUnsupportedConfigKey(spec, "memberURLs", &config)
+	if err != nil || !found {
+		return config, err
+	}
+
+	switch config.Mode {
+	case "":
+		config.Mode = IPMemberURLMode
+	case IPMemberURLMode, DNSMemberURLMode:
+	default:
+		return config, fmt.Errorf("invalid memberURLs config: unsupported mode %q, must be %s or %s", config.Mode, IPMemberURLMode, DNSMemberURLMode)
+	}
+	if len(config.Domain) > 0 {
+		if errs := validation.IsDNS1123Subdomain(config.Domain); len(errs) > 0 {
+			return config, fmt.Errorf("invalid memberURLs config: invalid domain %q: %s", config.Domain, strings.Join(errs, ", "))
+		}
+	}
+	return config, nil
+}
+
+// DNSName returns the DNS name of the member on the given node.
+func (c MemberURLConfig) DNSName(nodeName string) string {
+	if len(c.Domain) == 0 {
+		return nodeName
+	}
+	return nodeName + "." + c.Domain
+}
+
+// GetMemberURLHost returns the host the member on the given node should
+// advertise in its URLs: its DNS name in DNS mode, else the escaped preferred
+// internal IP of the node.
+func (c MemberURLConfig) GetMemberURLHost(network *configv1.Network, node *corev1.Node) (string, error) {
+	if c.Mode == DNSMemberURLMode {
+		return c.DNSName(node.Name), nil
+	}
+	return dnshelpers.GetEscapedPreferredInternalIPAddressForNodeName(network, node)
+}
+
+// GetRecordedMemberURLHosts returns the peer URL hosts of the members by node
+// name as recorded in the MemberURLHostsConfigMapName configmap.
+func GetRecordedMemberURLHosts(configmapLister corev1listers.ConfigMapLister) (map[string]string, error) {
+	configMap, err := configmapLister.ConfigMaps(operatorclient.TargetNamespace).Get(MemberURLHostsConfigMapName)
+	if errors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configMap.Data, nil
+}
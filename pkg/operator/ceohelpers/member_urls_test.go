@@ -0,0 +1,75 @@
+package ceohelpers
+
+import (
+	"testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGetMemberURLConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    MemberURLConfig
+		wantErr bool
+	}{
+		{
+			name: "no overrides",
+			want: MemberURLConfig{Mode: IPMemberURLMode},
+		},
+		{
+			name: "empty mode",
+			raw:  `{"memberURLs": {}}`,
+			want: MemberURLConfig{Mode: IPMemberURLMode},
+		},
+		{
+			name: "dns with domain",
+			raw: `
+memberURLs:
+  mode: DNS
+  domain: cluster.example.com
+`,
+			want: MemberURLConfig{Mode: DNSMemberURLMode, Domain: "cluster.example.com"},
+		},
+		{
+			name:    "unknown mode",
+			raw:     `{"memberURLs": {"mode": "Hostname"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			raw:     `{"memberURLs": {"mode": "DNS", "suffix": "example.com"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid domain",
+			raw:     `{"memberURLs": {"mode": "DNS", "domain": "-example.com"}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &operatorv1.OperatorSpec{}
+			if len(tt.raw) > 0 {
+				spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.raw)}
+			}
+			got, err := GetMemberURLConfig(spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMemberURLConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("GetMemberURLConfig() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemberURLConfigDNSName(t *testing.T) {
+	if got := (MemberURLConfig{}).DNSName("master-0"); got != "master-0" {
+		t.Errorf("DNSName() without domain got = %q", got)
+	}
+	if got := (MemberURLConfig{Domain: "example.com"}).DNSName("master-0"); got != "master-0.example.com" {
+		t.Errorf("DNSName() with domain got = %q", got)
+	}
+}
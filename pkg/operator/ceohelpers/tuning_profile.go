@@ -0,0 +1,168 @@
+package ceohelpers
+
+import (
+	"fmt"
+	"strconv"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+)
+
+// TuningProfile selects the heartbeat interval and election timeout of etcd.
+type TuningProfile string
+
+const (
+	// DefaultTuningProfile keeps the defaults of the platform, the etcd
+	// defaults except on Azure.
+	DefaultTuningProfile TuningProfile = "Default"
+	// SlowerTuningProfile tolerates slow disks and high peer latencies, e.g.
+	// of stretched clusters, at the cost of a slower leader failure detection.
+	SlowerTuningProfile TuningProfile = "Slower"
+	// CustomTuningProfile uses the configured heartbeat interval and election
+	// timeout.
+	CustomTuningProfile TuningProfile = "Custom"
+	// AutoTuningProfile uses the heartbeat interval and election timeout
+	// recommended from the observed etcd latencies, and the defaults of the
+	// platform until a recommendation is recorded.
+	AutoTuningProfile TuningProfile = "Auto"
+
+	// TuningRecommendationConfigMapName is the name of the configmap recording
+	// the recommended heartbeat interval and election timeout in milliseconds.
+	TuningRecommendationConfigMapName = "etcd-tuning-recommendation"
+	HeartbeatIntervalMillisKey        = "heartbeatIntervalMillis"
+	ElectionTimeoutMillisKey          = "electionTimeoutMillis"
+
+	slowerHeartbeatIntervalMillis = 500
+	slowerElectionTimeoutMillis   = 2500
+
+	// etcd defaults
+	defaultHeartbeatIntervalMillis = 100
+	defaultElectionTimeoutMillis   = 1000
+	azureHeartbeatIntervalMillis   = 500
+	azureElectionTimeoutMillis     = 2500
+
+	// etcd refuses to start with an election timeout shorter than 5
+	// heartbeat intervals or longer than 50s.
+	minElectionTimeoutHeartbeatRatio = 5
+	maxElectionTimeoutMillis         = 50000
+)
+
+// TuningConfig configures the heartbeat interval and election timeout of etcd.
+type TuningConfig struct {
+	Profile TuningProfile `json:"profile,omitempty"`
+	// HeartbeatIntervalMillis and ElectionTimeoutMillis are only set, and
+	// required, with the Custom profile.
+	HeartbeatIntervalMillis int `json:"heartbeatIntervalMillis,omitempty"`
+	ElectionTimeoutMillis   int `json:"electionTimeoutMillis,omitempty"`
+}
+
+// GetTuningConfig returns the tuning config from the tuning key of the
+// unsupported config overrides, e.g.
+//
+//	tuning:
+//	  profile: Custom
+//	  heartbeatIntervalMillis: 200
+//	  electionTimeoutMillis: 2000
+//
+// The profile defaults to Default. Unknown fields and invalid values are an
+// error.
+func GetTuningConfig(spec *operatorv1.OperatorSpec) (TuningConfig, error) {
+	config := TuningConfig{Profile: DefaultTuningProfile}
+	found, err := decodeUnsupportedConfigKey(spec, "tuning", &config)
+	if err != nil || !found {
+		return config, err
+	}
+	if len(config.Profile) == 0 {
+		config.Profile = DefaultTuningProfile
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("invalid tuning config: %w", err)
+	}
+	return config, nil
+}
+
+func (c TuningConfig) validate() error {
+	switch c.Profile {
+	case DefaultTuningProfile, SlowerTuningProfile, AutoTuningProfile:
+		if c.HeartbeatIntervalMillis != 0 || c.ElectionTimeoutMillis != 0 {
+			return fmt.Errorf("heartbeatIntervalMillis and electionTimeoutMillis are only supported with the %s profile", CustomTuningProfile)
+		}
+		return nil
+	case CustomTuningProfile:
+		return ValidateHeartbeatAndElectionTimeout(c.HeartbeatIntervalMillis, c.ElectionTimeoutMillis)
+	default:
+		return fmt.Errorf("unsupported profile %q, must be %s, %s, %s or %s", c.Profile, DefaultTuningProfile, SlowerTuningProfile, CustomTuningProfile, AutoTuningProfile)
+	}
+}
+
+// ValidateHeartbeatAndElectionTimeout returns an error unless etcd accepts the
+// heartbeat interval and election timeout in milliseconds.
+func ValidateHeartbeatAndElectionTimeout(heartbeatIntervalMillis, electionTimeoutMillis int) error {
+	if heartbeatIntervalMillis <= 0 {
+		return fmt.Errorf("heartbeatIntervalMillis must be positive, got %d", heartbeatIntervalMillis)
+	}
+	if electionTimeoutMillis < minElectionTimeoutHeartbeatRatio*heartbeatIntervalMillis {
+		return fmt.Errorf("electionTimeoutMillis must be at least %d times heartbeatIntervalMillis, got %d for a heartbeat interval of %d", minElectionTimeoutHeartbeatRatio, electionTimeoutMillis, heartbeatIntervalMillis)
+	}
+	if electionTimeoutMillis > maxElectionTimeoutMillis {
+		return fmt.Errorf("electionTimeoutMillis must be at most %d, got %d", maxElectionTimeoutMillis, electionTimeoutMillis)
+	}
+	return nil
+}
+
+// HeartbeatAndElectionTimeout returns the heartbeat interval and election
+// timeout in milliseconds of the profile. It returns false for the Default
+// profile, which keeps the defaults of the platform, and for the Auto profile,
+// whose values are recorded in the TuningRecommendationConfigMapName configmap.
+func (c TuningConfig) HeartbeatAndElectionTimeout() (int, int, bool) {
+	switch c.Profile {
+	case SlowerTuningProfile:
+		return slowerHeartbeatIntervalMillis, slowerElectionTimeoutMillis, true
+	case CustomTuningProfile:
+		return c.HeartbeatIntervalMillis, c.ElectionTimeoutMillis, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// PlatformDefaultHeartbeatAndElectionTimeout returns the heartbeat interval
+// and election timeout in milliseconds of the Default profile on the platform
+// of the infrastructure, the etcd defaults except on Azure.
+func PlatformDefaultHeartbeatAndElectionTimeout(infrastructure *configv1.Infrastructure) (int, int) {
+	if status := infrastructure.Status.PlatformStatus; status != nil {
+		switch {
+		case status.Azure != nil:
+			return azureHeartbeatIntervalMillis, azureElectionTimeoutMillis
+		}
+	}
+	return defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis
+}
+
+// GetRecordedTuningRecommendation returns the heartbeat interval and election
+// timeout in milliseconds recorded in the TuningRecommendationConfigMapName
+// configmap, or false if none is recorded.
+func GetRecordedTuningRecommendation(configmapLister corev1listers.ConfigMapLister) (int, int, bool, error) {
+	configMap, err := configmapLister.ConfigMaps(operatorclient.TargetNamespace).Get(TuningRecommendationConfigMapName)
+	if errors.IsNotFound(err) {
+		return 0, 0, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	heartbeatIntervalMillis, err := strconv.Atoi(configMap.Data[HeartbeatIntervalMillisKey])
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid %s in configmap %s: %w", HeartbeatIntervalMillisKey, TuningRecommendationConfigMapName, err)
+	}
+	electionTimeoutMillis, err := strconv.Atoi(configMap.Data[ElectionTimeoutMillisKey])
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid %s in configmap %s: %w", ElectionTimeoutMillisKey, TuningRecommendationConfigMapName, err)
+	}
+	if err := ValidateHeartbeatAndElectionTimeout(heartbeatIntervalMillis, electionTimeoutMillis); err != nil {
+		return 0, 0, false, fmt.Errorf("invalid recommendation in configmap %s: %w", TuningRecommendationConfigMapName, err)
+	}
+	return heartbeatIntervalMillis, electionTimeoutMillis, true, nil
+}
@@ -0,0 +1,99 @@
+package ceohelpers
+
+import (
+	"testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestGetTuningConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		wantHeartbeat int
+		wantTimeout   int
+		wantOverride  bool
+		wantErr       bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "default profile",
+			raw:  `{"tuning": {"profile": "Default"}}`,
+		},
+		{
+			name:          "slower profile",
+			raw:           `{"tuning": {"profile": "Slower"}}`,
+			wantHeartbeat: 500,
+			wantTimeout:   2500,
+			wantOverride:  true,
+		},
+		{
+			name: "custom profile",
+			raw: `
+tuning:
+  profile: Custom
+  heartbeatIntervalMillis: 200
+  electionTimeoutMillis: 2000
+`,
+			wantHeartbeat: 200,
+			wantTimeout:   2000,
+			wantOverride:  true,
+		},
+		{
+			name: "auto profile",
+			raw:  `{"tuning": {"profile": "Auto"}}`,
+		},
+		{
+			name:    "custom profile without values",
+			raw:     `{"tuning": {"profile": "Custom"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "custom profile with a too short election timeout",
+			raw:     `{"tuning": {"profile": "Custom", "heartbeatIntervalMillis": 500, "electionTimeoutMillis": 2000}}`,
+			wantErr: true,
+		},
+		{
+			name:    "custom profile with a too long election timeout",
+			raw:     `{"tuning": {"profile": "Custom", "heartbeatIntervalMillis": 5000, "electionTimeoutMillis": 60000}}`,
+			wantErr: true,
+		},
+		{
+			name:    "values with the slower profile",
+			raw:     `{"tuning": {"profile": "Slower", "heartbeatIntervalMillis": 200}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown profile",
+			raw:     `{"tuning": {"profile": "Fastest"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			raw:     `{"tuning": {"profile": "Slower", "snapshotCount": 1000}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &operatorv1.OperatorSpec{}
+			if len(tt.raw) > 0 {
+				spec.UnsupportedConfigOverrides = runtime.RawExtension{Raw: []byte(tt.raw)}
+			}
+			got, err := GetTuningConfig(spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTuningConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			heartbeat, timeout, override := got.HeartbeatAndElectionTimeout()
+			if heartbeat != tt.wantHeartbeat || timeout != tt.wantTimeout || override != tt.wantOverride {
+				t.Errorf("HeartbeatAndElectionTimeout() got = %d, %d, %v, want %d, %d, %v", heartbeat, timeout, override, tt.wantHeartbeat, tt.wantTimeout, tt.wantOverride)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package ceohelpers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"strconv"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
@@ -15,20 +16,8 @@ import (
 // useUnsupportedUnsafeNonHANonProductionUnstableEtcd key is set
 // to any parsable value
 func isUnsupportedUnsafeEtcd(spec *operatorv1.StaticPodOperatorSpec) (bool, error) {
-	unsupportedConfig := map[string]interface{}{}
-	if spec.UnsupportedConfigOverrides.Raw == nil {
-		return false, nil
-	}
-
-	configJson, err := kyaml.ToJSON(spec.UnsupportedConfigOverrides.Raw)
+	unsupportedConfig, err := decodeUnsupportedConfig(&spec.OperatorSpec)
 	if err != nil {
-		klog.Warning(err)
-		// maybe it's just json
-		configJson = spec.UnsupportedConfigOverrides.Raw
-	}
-
-	if err := json.NewDecoder(bytes.NewBuffer(configJson)).Decode(&unsupportedConfig); err != nil {
-		klog.V(4).Infof("decode of unsupported config failed with error: %v", err)
 		return false, err
 	}
 
@@ -56,3 +45,49 @@ func isUnsupportedUnsafeEtcd(spec *operatorv1.StaticPodOperatorSpec) (bool, erro
 		return false, nil
 	}
 }
+
+// decodeUnsupportedConfig decodes the yaml or json unsupported config
+// overrides. An empty map is returned if no overrides are set.
+func decodeUnsupportedConfig(spec *operatorv1.OperatorSpec) (map[string]interface{}, error) {
+	unsupportedConfig := map[string]interface{}{}
+	if spec.UnsupportedConfigOverrides.Raw == nil {
+		return unsupportedConfig, nil
+	}
+
+	configJson, err := kyaml.ToJSON(spec.UnsupportedConfigOverrides.Raw)
+	if err != nil {
+		klog.Warning(err)
+		// maybe it's just json
+		configJson = spec.UnsupportedConfigOverrides.Raw
+	}
+
+	if err := json.NewDecoder(bytes.NewBuffer(configJson)).Decode(&unsupportedConfig); err != nil {
+		klog.V(4).Infof("decode of unsupported config failed with error: %v", err)
+		return nil, err
+	}
+	return unsupportedConfig, nil
+}
+
+// decodeUnsupportedConfigKey decodes the value of the key of the unsupported
+// config overrides into the value pointed to by into. It returns false if the
+// key is not set. Unknown fields are an error.
+func decodeUnsupportedConfigKey(spec *operatorv1.OperatorSpec, key string, into interface{}) (bool, error) {
+	unsupportedConfig, err := decodeUnsupportedConfig(spec)
+	if err != nil {
+		return false, err
+	}
+	value, found, err := unstructured.NestedFieldNoCopy(unsupportedConfig, key)
+	if err != nil || !found {
+		return false, err
+	}
+	valueJson, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	decoder := json.NewDecoder(bytes.NewBuffer(valueJson))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(into); err != nil {
+		return false, fmt.Errorf("invalid %s config: %w", key, err)
+	}
+	return true, nil
+}
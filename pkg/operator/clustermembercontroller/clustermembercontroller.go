@@ -3,6 +3,7 @@ package clustermembercontroller
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 
@@ -21,38 +24,52 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 
-	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
 )
 
 // watches the etcd static pods, picks one unready pod and adds
 // to etcd membership only if all existing members are running healthy
 // skips if any one member is unhealthy.
+// Once all pods are members it migrates the peer URLs of the members to the
+// configured member URL mode one member at a time.
 type ClusterMemberController struct {
-	operatorClient v1helpers.OperatorClient
-	etcdClient     etcdcli.EtcdClient
-	podLister      corev1listers.PodLister
-	nodeLister     corev1listers.NodeLister
-	networkLister  configv1listers.NetworkLister
+	operatorClient  v1helpers.StaticPodOperatorClient
+	etcdClient      etcdcli.EtcdClient
+	podLister       corev1listers.PodLister
+	nodeLister      corev1listers.NodeLister
+	networkLister   configv1listers.NetworkLister
+	configmapLister corev1listers.ConfigMapLister
+	secretLister    corev1listers.SecretLister
+	configmapClient corev1client.ConfigMapsGetter
+	// lookupHost resolves the DNS names of the members
+	lookupHost func(host string) ([]string, error)
 }
 
 func NewClusterMemberController(
-	operatorClient v1helpers.OperatorClient,
+	operatorClient v1helpers.StaticPodOperatorClient,
+	kubeClient kubernetes.Interface,
 	kubeInformers v1helpers.KubeInformersForNamespaces,
 	networkInformer configv1informers.NetworkInformer,
 	etcdClient etcdcli.EtcdClient,
 	eventRecorder events.Recorder,
 ) factory.Controller {
 	c := &ClusterMemberController{
-		operatorClient: operatorClient,
-		etcdClient:     etcdClient,
-		podLister:      kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Pods().Lister(),
-		nodeLister:     kubeInformers.InformersFor("").Core().V1().Nodes().Lister(),
-		networkLister:  networkInformer.Lister(),
+		operatorClient:  operatorClient,
+		etcdClient:      etcdClient,
+		podLister:       kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Pods().Lister(),
+		nodeLister:      kubeInformers.InformersFor("").Core().V1().Nodes().Lister(),
+		networkLister:   networkInformer.Lister(),
+		configmapLister: kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().ConfigMaps().Lister(),
+		secretLister:    kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Secrets().Lister(),
+		configmapClient: kubeClient.CoreV1(),
+		lookupHost:      net.LookupHost,
 	}
 	return factory.New().ResyncEvery(time.Minute).WithInformers(
 		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Pods().Informer(),
+		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().ConfigMaps().Informer(),
+		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Secrets().Informer(),
 		kubeInformers.InformersFor("").Core().V1().Nodes().Informer(),
 		networkInformer.Informer(),
 		operatorClient.Informer(),
@@ -60,7 +77,7 @@ func NewClusterMemberController(
 }
 
 func (c *ClusterMemberController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	err := c.reconcileMembers(syncCtx.Recorder())
+	err := c.reconcileMembers(ctx, syncCtx.Recorder())
 	if err != nil {
 		_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
 			Type:    "ClusterMemberControllerDegraded",
@@ -83,7 +100,7 @@ func (c *ClusterMemberController) sync(ctx context.Context, syncCtx factory.Sync
 	return updateErr
 }
 
-func (c *ClusterMemberController) reconcileMembers(recorder events.Recorder) error {
+func (c *ClusterMemberController) reconcileMembers(ctx context.Context, recorder events.Recorder) error {
 	unhealthyMembers, err := c.etcdClient.UnhealthyMembers()
 	if err != nil {
 		return err
@@ -93,22 +110,40 @@ func (c *ClusterMemberController) reconcileMembers(recorder events.Recorder) err
 		return nil
 	}
 
+	operatorSpec, operatorStatus, _, err := c.operatorClient.GetStaticPodOperatorState()
+	if err != nil {
+		return err
+	}
+	memberURLs, err := ceohelpers.GetMemberURLConfig(&operatorSpec.OperatorSpec)
+	if err != nil {
+		return err
+	}
+
 	// etcd is healthy, decide if we need to scale
 	podToAdd, err := c.getEtcdPodToAddToMembership()
 	switch {
 	case err != nil:
 		return err
 	case podToAdd == nil:
-		// no more work left to do
-		return nil
+		// membership is complete, move the existing members to the
+		// configured member URLs
+		return c.migrateMemberURLs(ctx, recorder, memberURLs, operatorStatus)
 	default:
 		recorder.Eventf("FoundPodToScale", "found pod to add to etcd membership: %v", podToAdd.Name)
 	}
 
-	etcdHost, err := c.getEtcdPeerHostToScale(podToAdd)
+	etcdHost, err := c.getEtcdPeerHostToScale(podToAdd, memberURLs)
 	if err != nil {
 		return err
 	}
+	// The pod looks up its member by the recorded host, record it first
+	recordedHost := ""
+	if memberURLs.Mode == ceohelpers.DNSMemberURLMode {
+		recordedHost = etcdHost
+	}
+	if err := c.recordMemberURLHost(ctx, recorder, podToAdd.Spec.NodeName, recordedHost); err != nil {
+		return err
+	}
 	err = c.etcdClient.MemberAdd(fmt.Sprintf("https://%s:2380", etcdHost))
 	if err != nil {
 		return err
@@ -158,10 +193,11 @@ func (c *ClusterMemberController) getEtcdPodToAddToMembership() (*corev1.Pod, er
 	return nil, nil
 }
 
-// getValidPodFQDNToScale goes through the list on unready pods and
-// returns a resolvable  podFQDN. If none of the DNSes are available
-// yet it will return collected errors.
-func (c *ClusterMemberController) getEtcdPeerHostToScale(podToAdd *corev1.Pod) (string, error) {
+// getEtcdPeerHostToScale returns the peer URL host of the member to add for
+// the pod: the DNS name of the member in DNS member URL mode, else the
+// escaped preferred internal IP of its node. A DNS name must resolve to the
+// IP of the node.
+func (c *ClusterMemberController) getEtcdPeerHostToScale(podToAdd *corev1.Pod, memberURLs ceohelpers.MemberURLConfig) (string, error) {
 	network, err := c.networkLister.Get("cluster")
 	if err != nil {
 		return "", err
@@ -171,5 +207,14 @@ func (c *ClusterMemberController) getEtcdPeerHostToScale(podToAdd *corev1.Pod) (
 		return "", err
 	}
 
-	return dnshelpers.GetEscapedPreferredInternalIPAddressForNodeName(network, node)
+	host, err := memberURLs.GetMemberURLHost(network, node)
+	if err != nil {
+		return "", err
+	}
+	if memberURLs.Mode == ceohelpers.DNSMemberURLMode {
+		if err := c.checkDNSName(network, node, host); err != nil {
+			return "", err
+		}
+	}
+	return host, nil
 }
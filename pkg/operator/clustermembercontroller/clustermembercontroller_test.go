@@ -1,14 +1,28 @@
 package clustermembercontroller
 
 import (
+	"bytes"
 	"context"
 	"reflect"
 
-	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	u "github.com/openshift/cluster-etcd-operator/pkg/testutils"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
 
 	"testing"
 
@@ -266,3 +280,147 @@ func TestClusterMemberController_getEtcdPodToAddToMembership(t *testing.T) {
 		})
 	}
 }
+
+func TestClusterMemberController_migrateMemberURLs(t *testing.T) {
+	caConfig, err := crypto.MakeSelfSignedCAConfig("etcd-signer", 365)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, caKey, err := caConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisionCerts := func(hostNames ...string) *corev1.Secret {
+		data := map[string][]byte{}
+		for name, newCertFunc := range map[string]func([]byte, []byte, []string, tlshelpers.CertOptions) (*bytes.Buffer, *bytes.Buffer, error){
+			tlshelpers.GetPeerClientSecretNameForNode("master-0"): tlshelpers.CreatePeerCertKey,
+			tlshelpers.GetServingSecretNameForNode("master-0"):    tlshelpers.CreateServerCertKey,
+		} {
+			cert, _, err := newCertFunc(caCert, caKey, hostNames, tlshelpers.DefaultCertOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[name+".crt"] = cert.Bytes()
+		}
+		return u.FakeSecret(operatorclient.TargetNamespace, tlshelpers.EtcdAllCertsSecretName+"-3", data)
+	}
+	recordedHosts := func(hosts map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ceohelpers.MemberURLHostsConfigMapName, Namespace: operatorclient.TargetNamespace},
+			Data:       hosts,
+		}
+	}
+	dnsMode := ceohelpers.MemberURLConfig{Mode: ceohelpers.DNSMemberURLMode, Domain: "example.com"}
+	ipMode := ceohelpers.MemberURLConfig{Mode: ceohelpers.IPMemberURLMode}
+
+	tests := []struct {
+		name              string
+		memberURLs        ceohelpers.MemberURLConfig
+		peerURL           string
+		resolvedIP        string
+		objects           []runtime.Object
+		wantPeerURL       string
+		wantRecordedHosts map[string]string
+		wantErr           bool
+	}{
+		{
+			name:              "migrate to the dns name",
+			memberURLs:        dnsMode,
+			peerURL:           "https://10.0.0.1:2380",
+			resolvedIP:        "10.0.0.1",
+			objects:           []runtime.Object{revisionCerts("10.0.0.1", "master-0.example.com")},
+			wantPeerURL:       "https://master-0.example.com:2380",
+			wantRecordedHosts: map[string]string{"master-0": "master-0.example.com"},
+		},
+		{
+			name:        "wait for certs valid for the dns name",
+			memberURLs:  dnsMode,
+			peerURL:     "https://10.0.0.1:2380",
+			resolvedIP:  "10.0.0.1",
+			objects:     []runtime.Object{revisionCerts("10.0.0.1")},
+			wantPeerURL: "https://10.0.0.1:2380",
+		},
+		{
+			name:        "dns name resolves to a different ip",
+			memberURLs:  dnsMode,
+			peerURL:     "https://10.0.0.1:2380",
+			resolvedIP:  "10.0.0.9",
+			objects:     []runtime.Object{revisionCerts("10.0.0.1", "master-0.example.com")},
+			wantPeerURL: "https://10.0.0.1:2380",
+			wantErr:     true,
+		},
+		{
+			name:              "record a migrated member",
+			memberURLs:        dnsMode,
+			peerURL:           "https://master-0.example.com:2380",
+			wantPeerURL:       "https://master-0.example.com:2380",
+			wantRecordedHosts: map[string]string{"master-0": "master-0.example.com"},
+		},
+		{
+			name:              "migrate back to the ip",
+			memberURLs:        ipMode,
+			peerURL:           "https://master-0.example.com:2380",
+			objects:           []runtime.Object{recordedHosts(map[string]string{"master-0": "master-0.example.com"})},
+			wantPeerURL:       "https://10.0.0.1:2380",
+			wantRecordedHosts: map[string]string{},
+		},
+		{
+			name:        "leave an outdated ip to the drift repair",
+			memberURLs:  ipMode,
+			peerURL:     "https://10.0.0.7:2380",
+			wantPeerURL: "https://10.0.0.7:2380",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{
+				u.FakeNode("master-0", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.1")),
+			}, tt.objects...)
+			fakeKubeClient := fake.NewSimpleClientset(objects...)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, obj := range append(objects, &configv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec:       configv1.NetworkSpec{ServiceNetwork: []string{"172.30.0.0/16"}},
+			}) {
+				if err := indexer.Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+			member := &etcdserverpb.Member{Name: "master-0", ID: 1, PeerURLs: []string{tt.peerURL}}
+			c := &ClusterMemberController{
+				etcdClient:      etcdcli.NewFakeEtcdClient([]*etcdserverpb.Member{member}),
+				nodeLister:      corev1lister.NewNodeLister(indexer),
+				networkLister:   configv1listers.NewNetworkLister(indexer),
+				configmapLister: corev1lister.NewConfigMapLister(indexer),
+				secretLister:    corev1lister.NewSecretLister(indexer),
+				configmapClient: fakeKubeClient.CoreV1(),
+				lookupHost: func(host string) ([]string, error) {
+					return []string{tt.resolvedIP}, nil
+				},
+			}
+			status := &operatorv1.StaticPodOperatorStatus{
+				NodeStatuses: []operatorv1.NodeStatus{{NodeName: "master-0", CurrentRevision: 3}},
+			}
+			err := c.migrateMemberURLs(context.TODO(), events.NewInMemoryRecorder("test"), tt.memberURLs, status)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("migrateMemberURLs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if member.PeerURLs[0] != tt.wantPeerURL {
+				t.Fatalf("expected peer URL %s, got %s", tt.wantPeerURL, member.PeerURLs[0])
+			}
+			configMap, err := fakeKubeClient.CoreV1().ConfigMaps(operatorclient.TargetNamespace).Get(context.TODO(), ceohelpers.MemberURLHostsConfigMapName, metav1.GetOptions{})
+			if tt.wantRecordedHosts == nil {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected no recorded hosts, got %v, %v", configMap, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(configMap.Data) > 0 || len(tt.wantRecordedHosts) > 0) && !reflect.DeepEqual(configMap.Data, tt.wantRecordedHosts) {
+				t.Fatalf("expected recorded hosts %v, got %v", tt.wantRecordedHosts, configMap.Data)
+			}
+		})
+	}
+}
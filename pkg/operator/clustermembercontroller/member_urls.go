@@ -0,0 +1,203 @@
+package clustermembercontroller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+)
+
+// migrateMemberURLs moves the peer URL of at most one member per sync to the
+// host of the configured member URL mode: its DNS name in DNS mode, its node
+// IP when a member that uses a DNS name goes back to IP mode. Members whose
+// peer URL is an outdated IP are left alone.
+//
+// A member only moves to its DNS name once the name resolves to the IP of its
+// node and the certs of the current revision of its node are valid for it.
+// The peer URL host of each member is recorded for the env var controller,
+// which rolls out a revision advertising the new host.
+func (c *ClusterMemberController) migrateMemberURLs(ctx context.Context, recorder events.Recorder, memberURLs ceohelpers.MemberURLConfig, operatorStatus *operatorv1.StaticPodOperatorStatus) error {
+	members, err := c.etcdClient.MemberList()
+	if err != nil {
+		return err
+	}
+	recordedHosts, err := ceohelpers.GetRecordedMemberURLHosts(c.configmapLister)
+	if err != nil {
+		return err
+	}
+	network, err := c.networkLister.Get("cluster")
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		// unstarted members are migrated once they started
+		if len(member.Name) == 0 || len(member.PeerURLs) == 0 {
+			continue
+		}
+		node, err := c.nodeLister.Get(member.Name)
+		if apierrors.IsNotFound(err) {
+			// e.g. the bootstrap member
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		peerURL, err := url.Parse(member.PeerURLs[0])
+		if err != nil {
+			return fmt.Errorf("member %q has an invalid peer URL: %w", member.Name, err)
+		}
+		currentHost := peerURL.Hostname()
+		usesDNSName := net.ParseIP(currentHost) == nil
+
+		// Catch up on a host that failed to be recorded after a migration.
+		// The record is read from the cache, so record one host per sync.
+		recordedHost := ""
+		if usesDNSName {
+			recordedHost = currentHost
+		}
+		if recordedHosts[node.Name] != recordedHost {
+			return c.recordMemberURLHost(ctx, recorder, node.Name, recordedHost)
+		}
+
+		targetHost := ""
+		switch {
+		case memberURLs.Mode == ceohelpers.DNSMemberURLMode && currentHost != memberURLs.DNSName(node.Name):
+			targetHost = memberURLs.DNSName(node.Name)
+			if err := c.checkDNSName(network, node, targetHost); err != nil {
+				return err
+			}
+			if ready, err := c.revisionCertsValidFor(node.Name, targetHost, operatorStatus); err != nil || !ready {
+				return err
+			}
+		case memberURLs.Mode == ceohelpers.IPMemberURLMode && usesDNSName:
+			targetHost, err = dnshelpers.GetEscapedPreferredInternalIPAddressForNodeName(network, node)
+			if err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+
+		return c.migrateMember(ctx, recorder, member, targetHost, memberURLs)
+	}
+	return nil
+}
+
+// migrateMember updates the peer URL of the member to the target host and
+// records the host.
+func (c *ClusterMemberController) migrateMember(ctx context.Context, recorder events.Recorder, member *etcdserverpb.Member, targetHost string, memberURLs ceohelpers.MemberURLConfig) error {
+	peerURL := fmt.Sprintf("https://%s:2380", targetHost)
+	if err := c.etcdClient.MemberUpdatePeerURL(member.ID, []string{peerURL}); err != nil {
+		return fmt.Errorf("failed to migrate member %q to peer URL %s: %w", member.Name, peerURL, err)
+	}
+	recorder.Eventf("MemberURLMigrated", "migrated member %q to peer URL %s", member.Name, peerURL)
+
+	recordedHost := ""
+	if memberURLs.Mode == ceohelpers.DNSMemberURLMode {
+		recordedHost = targetHost
+	}
+	return c.recordMemberURLHost(ctx, recorder, member.Name, recordedHost)
+}
+
+// checkDNSName returns an error unless the DNS name resolves to the preferred
+// internal IP of the node.
+func (c *ClusterMemberController) checkDNSName(network *configv1.Network, node *corev1.Node, dnsName string) error {
+	nodeIP, _, err := dnshelpers.GetPreferredInternalIPAddressForNodeName(network, node)
+	if err != nil {
+		return err
+	}
+	addresses, err := c.lookupHost(dnsName)
+	if err != nil {
+		return fmt.Errorf("unable to resolve DNS name %s of member %q: %w", dnsName, node.Name, err)
+	}
+	for _, address := range addresses {
+		if net.ParseIP(address).Equal(net.ParseIP(nodeIP)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("DNS name %s of member %q resolves to %v, not to the node IP %s", dnsName, node.Name, addresses, nodeIP)
+}
+
+// revisionCertsValidFor returns whether the peer and serving certs of the
+// current revision of the node are valid for the host, i.e. whether certs
+// issued for it have been rolled out to the node.
+func (c *ClusterMemberController) revisionCertsValidFor(nodeName, host string, operatorStatus *operatorv1.StaticPodOperatorStatus) (bool, error) {
+	currentRevision := int32(0)
+	for _, nodeStatus := range operatorStatus.NodeStatuses {
+		if nodeStatus.NodeName == nodeName {
+			currentRevision = nodeStatus.CurrentRevision
+		}
+	}
+	if currentRevision == 0 {
+		klog.V(2).Infof("waiting for a revision of node %s to migrate its member to %s", nodeName, host)
+		return false, nil
+	}
+
+	secretName := fmt.Sprintf("%s-%d", tlshelpers.EtcdAllCertsSecretName, currentRevision)
+	secret, err := c.secretLister.Secrets(operatorclient.TargetNamespace).Get(secretName)
+	if err != nil {
+		return false, err
+	}
+	for _, certName := range []string{
+		tlshelpers.GetPeerClientSecretNameForNode(nodeName),
+		tlshelpers.GetServingSecretNameForNode(nodeName),
+	} {
+		certs, err := crypto.CertsFromPEM(secret.Data[certName+".crt"])
+		if err != nil {
+			return false, fmt.Errorf("invalid cert %s.crt in secret %s: %w", certName, secretName, err)
+		}
+		if err := certs[0].VerifyHostname(host); err != nil {
+			klog.V(2).Infof("waiting for certs valid for %s to be rolled out to node %s: %v", host, nodeName, err)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// recordMemberURLHost records the peer URL host of the member of the node. An
+// empty host removes the record, the member then uses the IP of its node.
+func (c *ClusterMemberController) recordMemberURLHost(ctx context.Context, recorder events.Recorder, nodeName, host string) error {
+	recordedHosts, err := ceohelpers.GetRecordedMemberURLHosts(c.configmapLister)
+	if err != nil {
+		return err
+	}
+	if recordedHosts[nodeName] == host {
+		return nil
+	}
+
+	data := map[string]string{}
+	for name, recordedHost := range recordedHosts {
+		data[name] = recordedHost
+	}
+	if len(host) > 0 {
+		data[nodeName] = host
+	} else {
+		delete(data, nodeName)
+	}
+	required := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ceohelpers.MemberURLHostsConfigMapName,
+			Namespace: operatorclient.TargetNamespace,
+		},
+		Data: data,
+	}
+	_, _, err = resourceapply.ApplyConfigMap(ctx, c.configmapClient, recorder, required)
+	return err
+}
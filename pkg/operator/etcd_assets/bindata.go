@@ -179,6 +179,13 @@ fi
 
 backup_latest_kube_static_resources "${BACKUP_TAR_FILE}"
 
+# Authenticate with the dedicated backup client cert if the certs of the node include it
+BACKUP_CERT="${CONFIG_FILE_DIR}/static-pod-certs/secrets/etcd-all-certs/etcd-client-backup.crt"
+if [ -f "${BACKUP_CERT}" ]; then
+  export ETCDCTL_CERT="${BACKUP_CERT}"
+  export ETCDCTL_KEY="${CONFIG_FILE_DIR}/static-pod-certs/secrets/etcd-all-certs/etcd-client-backup.key"
+fi
+
 # Download etcdctl and get the etcd snapshot
 dl_etcdctl
 ETCDCTL_ENDPOINTS="https://${NODE_NODE_ENVVAR_NAME_IP}:2379" etcdctl snapshot save "${SNAPSHOT_FILE}"
@@ -325,7 +332,8 @@ fi
 # Restore static pod resources
 tar -C "${CONFIG_FILE_DIR}" -xzf "${BACKUP_FILE}" static-pod-resources
 
-# Copy snapshot to backupdir
+# Copy snapshot to backupdir, the marker of a previous restore would make the restore pod skip the new snapshot
+rm -f "${ETCD_DATA_DIR_BACKUP}"/snapshot-restored
 cp -p "${SNAPSHOT_FILE}" "${ETCD_DATA_DIR_BACKUP}"/snapshot.db
 
 echo "starting restore-etcd static pod"
@@ -522,34 +530,14 @@ spec:
         - mountPath: /var/log/etcd
           name: log-dir
     - name: etcd-ensure-env-vars
-      image: ${IMAGE}
+      image: ${OPERATOR_IMAGE}
       imagePullPolicy: IfNotPresent
       terminationMessagePolicy: FallbackToLogsOnError
-      command:
-        - /bin/sh
-        - -c
-        - |
-          #!/bin/sh
-          set -euo pipefail
-
-          : "${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST?not set}"
-          : "${NODE_NODE_ENVVAR_NAME_ETCD_NAME?not set}"
-          : "${NODE_NODE_ENVVAR_NAME_IP?not set}"
-
-          # check for ipv4 addresses as well as ipv6 addresses with extra square brackets
-          if [[ "${NODE_NODE_ENVVAR_NAME_IP}" != "${NODE_IP}" && "${NODE_NODE_ENVVAR_NAME_IP}" != "[${NODE_IP}]" ]]; then
-            # echo the error message to stderr
-            echo "Expected node IP to be ${NODE_IP} got ${NODE_NODE_ENVVAR_NAME_IP}" >&2
-            exit 1
-          fi
-
-          # check for ipv4 addresses as well as ipv6 addresses with extra square brackets
-          if [[ "${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}" != "${NODE_IP}" && "${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}" != "[${NODE_IP}]" ]]; then
-            # echo the error message to stderr
-            echo "Expected etcd url host to be ${NODE_IP} got ${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}" >&2
-            exit 1
-          fi
-
+      # checks the etcd env vars of the node and that the node IP belongs to the host, see verify-node-ip --help
+      command: [ "cluster-etcd-operator", "verify-node-ip" ]
+      args:
+        - --env-prefix=NODE_NODE_ENVVAR_NAME_
+        - --pod-ip=$(NODE_IP)
       resources:
         requests:
           memory: 60Mi
@@ -658,7 +646,7 @@ ${COMPUTED_ENV_VARS}
         exec ionice -c2 -n0 etcd \
           --logger=zap \
           --log-level=${VERBOSITY} \
-          --initial-advertise-peer-urls=https://${NODE_NODE_ENVVAR_NAME_IP}:2380 \
+          --initial-advertise-peer-urls=https://${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}:2380 \
           --cert-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-serving-NODE_NAME.crt \
           --key-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-serving-NODE_NAME.key \
           --trusted-ca-file=/etc/kubernetes/static-pod-certs/configmaps/etcd-serving-ca/ca-bundle.crt \
@@ -667,7 +655,7 @@ ${COMPUTED_ENV_VARS}
           --peer-key-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-peer-NODE_NAME.key \
           --peer-trusted-ca-file=/etc/kubernetes/static-pod-certs/configmaps/etcd-peer-client-ca/ca-bundle.crt \
           --peer-client-cert-auth=true \
-          --advertise-client-urls=https://${NODE_NODE_ENVVAR_NAME_IP}:2379,unixs://${NODE_NODE_ENVVAR_NAME_IP}:0 \
+          --advertise-client-urls=https://${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}:2379,unixs://${NODE_NODE_ENVVAR_NAME_IP}:0 \
           --listen-client-urls=https://${LISTEN_ON_ALL_IPS}:2379,unixs://${NODE_NODE_ENVVAR_NAME_IP}:0 \
           --listen-peer-urls=https://${LISTEN_ON_ALL_IPS}:2380 \
           --listen-metrics-urls=https://${LISTEN_ON_ALL_IPS}:9978 ||  mv /etc/kubernetes/etcd-backup-dir/etcd-member.yaml /etc/kubernetes/manifests
@@ -764,11 +752,29 @@ ${COMPUTED_ENV_VARS}
       - --log-outputs=stderr
       - --log-outputs=/var/log/etcd/etcd-health-probe.log
       - --enable-log-rotation
+      - --intervals-file=/var/log/etcd/etcd-health-intervals.jsonl
       - --pod-name=$(POD_NAME)
       - --static-pod-version=$(ETCD_STATIC_POD_VERSION)
-      - --cert-file=$(ETCDCTL_CERT)
-      - --key-file=$(ETCDCTL_KEY)
+      - --cert-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-client-monitor.crt
+      - --key-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-client-monitor.key
+      - --fallback-cert-file=$(ETCDCTL_CERT)
+      - --fallback-key-file=$(ETCDCTL_KEY)
       - --cacert-file=$(ETCDCTL_CACERT)
+      - --listen-address=${LISTEN_ON_ALL_IPS}:9980
+      - --serving-cert-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-serving-metrics-NODE_NAME.crt
+      - --serving-key-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-serving-metrics-NODE_NAME.key
+      - --serving-client-ca-file=/etc/kubernetes/static-pod-certs/configmaps/etcd-metrics-proxy-client-ca/ca-bundle.crt
+      - --kubeconfig=/etc/kubernetes/static-pod-resources/kube-apiserver-certs/secrets/node-kubeconfigs/localhost.kubeconfig
+    readinessProbe:
+      httpGet:
+        scheme: HTTPS
+        port: 9980
+        path: healthz
+      failureThreshold: 3
+      initialDelaySeconds: 3
+      periodSeconds: 5
+      successThreshold: 1
+      timeoutSeconds: 5
     env:
 ${COMPUTED_ENV_VARS}
       - name: "ETCD_STATIC_POD_VERSION"
@@ -784,6 +790,9 @@ ${COMPUTED_ENV_VARS}
         name: log-dir
       - mountPath: /etc/kubernetes/static-pod-certs
         name: cert-dir
+      - mountPath: /etc/kubernetes/static-pod-resources/kube-apiserver-certs/secrets/node-kubeconfigs
+        name: node-kubeconfigs-dir
+        readOnly: true
     resources:
       requests:
         memory: 70Mi
@@ -812,6 +821,9 @@ ${COMPUTED_ENV_VARS}
     - hostPath:
         path: /var/log/etcd
       name: log-dir
+    - hostPath:
+        path: /etc/kubernetes/static-pod-resources/kube-apiserver-certs/secrets/node-kubeconfigs
+      name: node-kubeconfigs-dir
 `)
 
 func etcdPodYamlBytes() ([]byte, error) {
@@ -921,9 +933,11 @@ spec:
           securityContext:
             privileged: true
       volumes:
+        # issued by the operator, the guard is not ready until it exists
         - name: etcd-client
           secret:
             secretName: etcd-client
+            optional: true
         - name: etcd-ca
           configMap:
             name: etcd-ca-bundle
@@ -996,46 +1010,68 @@ spec:
         env | grep ETCD | grep -v NODE
         export ETCD_NODE_PEER_URL=https://${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}:2380
 
-        # checking if data directory is empty, if not etcdctl restore will fail
-        if [ ! -z $(ls -A "/var/lib/etcd") ]; then
-          echo "please delete the contents of data directory before restoring, running the restore script will do this for you"
-          exit 1
-        fi
-
-        # check if we have backup file to be restored
-        # if the file exist, check if it has not changed size in last 5 seconds
-        if [ ! -f /var/lib/etcd-backup/snapshot.db ]; then
-          echo "please make a copy of the snapshot db file, then move that copy to /var/lib/etcd-backup/snapshot.db"
-          exit 1
+        FORCE_NEW_CLUSTER=""
+        # quorum-restore starts the member from the existing data directory instead of a snapshot
+        if [ -f /var/lib/etcd-backup/quorum-restore ]; then
+          if [ ! -d /var/lib/etcd/member ]; then
+            echo "quorum restore requires an existing data directory /var/lib/etcd/member"
+            exit 1
+          fi
+          # the marker is removed by quorum-restore once the single member cluster is healthy, until then a restart
+          # forces the new cluster again
+          echo "restoring to a single node cluster from the existing data directory"
+          FORCE_NEW_CLUSTER="--force-new-cluster"
+        elif [ -f /var/lib/etcd-backup/snapshot-restored ]; then
+          # the snapshot was restored before and the container restarted, the marker is removed by cluster-restore.sh
+          # before it copies the next snapshot
+          if [ ! -d /var/lib/etcd/member ]; then
+            echo "the snapshot was restored but the data directory /var/lib/etcd/member is missing, run the restore script again"
+            exit 1
+          fi
+          echo "restarting the restored member from the existing data directory"
         else
-          filesize=$(stat --format=%s "/var/lib/etcd-backup/snapshot.db")
-          sleep 5
-          newfilesize=$(stat --format=%s "/var/lib/etcd-backup/snapshot.db")
-          if [ "$filesize" != "$newfilesize" ]; then
-            echo "file size has changed since last 5 seconds, retry sometime after copying is complete"
+          # checking if data directory is empty, if not etcdctl restore will fail
+          if [ ! -z $(ls -A "/var/lib/etcd") ]; then
+            echo "please delete the contents of data directory before restoring, running the restore script will do this for you"
             exit 1
           fi
-        fi
 
-        UUID=$(uuidgen)
-        echo "restoring to a single node cluster"
-        ETCDCTL_API=3 /usr/bin/etcdctl snapshot restore /var/lib/etcd-backup/snapshot.db \
-         --name  $ETCD_NAME \
-         --initial-cluster=$ETCD_INITIAL_CLUSTER \
-         --initial-cluster-token "openshift-etcd-${UUID}" \
-         --initial-advertise-peer-urls $ETCD_NODE_PEER_URL \
-         --data-dir="/var/lib/etcd/restore-${UUID}"
+          # check if we have backup file to be restored
+          # if the file exist, check if it has not changed size in last 5 seconds
+          if [ ! -f /var/lib/etcd-backup/snapshot.db ]; then
+            echo "please make a copy of the snapshot db file, then move that copy to /var/lib/etcd-backup/snapshot.db"
+            exit 1
+          else
+            filesize=$(stat --format=%s "/var/lib/etcd-backup/snapshot.db")
+            sleep 5
+            newfilesize=$(stat --format=%s "/var/lib/etcd-backup/snapshot.db")
+            if [ "$filesize" != "$newfilesize" ]; then
+              echo "file size has changed since last 5 seconds, retry sometime after copying is complete"
+              exit 1
+            fi
+          fi
+
+          UUID=$(uuidgen)
+          echo "restoring to a single node cluster"
+          ETCDCTL_API=3 /usr/bin/etcdctl snapshot restore /var/lib/etcd-backup/snapshot.db \
+           --name  $ETCD_NAME \
+           --initial-cluster=$ETCD_INITIAL_CLUSTER \
+           --initial-cluster-token "openshift-etcd-${UUID}" \
+           --initial-advertise-peer-urls $ETCD_NODE_PEER_URL \
+           --data-dir="/var/lib/etcd/restore-${UUID}"
 
-        mv /var/lib/etcd/restore-${UUID}/* /var/lib/etcd/
+          mv /var/lib/etcd/restore-${UUID}/* /var/lib/etcd/
 
-        rmdir /var/lib/etcd/restore-${UUID}
-        rm /var/lib/etcd-backup/snapshot.db
+          rmdir /var/lib/etcd/restore-${UUID}
+          touch /var/lib/etcd-backup/snapshot-restored
+          rm /var/lib/etcd-backup/snapshot.db
+        fi
 
         set -x
         exec etcd \
           --logger=zap \
           --log-level=${VERBOSITY} \
-          --initial-advertise-peer-urls=https://${NODE_NODE_ENVVAR_NAME_IP}:2380 \
+          --initial-advertise-peer-urls=https://${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}:2380 \
           --cert-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-serving-NODE_NAME.crt \
           --key-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-serving-NODE_NAME.key \
           --trusted-ca-file=/etc/kubernetes/static-pod-certs/configmaps/etcd-serving-ca/ca-bundle.crt \
@@ -1044,10 +1080,10 @@ spec:
           --peer-key-file=/etc/kubernetes/static-pod-certs/secrets/etcd-all-certs/etcd-peer-NODE_NAME.key \
           --peer-trusted-ca-file=/etc/kubernetes/static-pod-certs/configmaps/etcd-peer-client-ca/ca-bundle.crt \
           --peer-client-cert-auth=true \
-          --advertise-client-urls=https://${NODE_NODE_ENVVAR_NAME_IP}:2379 \
+          --advertise-client-urls=https://${NODE_NODE_ENVVAR_NAME_ETCD_URL_HOST}:2379 \
           --listen-client-urls=https://${LISTEN_ON_ALL_IPS}:2379 \
           --listen-peer-urls=https://${LISTEN_ON_ALL_IPS}:2380 \
-          --listen-metrics-urls=https://${LISTEN_ON_ALL_IPS}:9978
+          --listen-metrics-urls=https://${LISTEN_ON_ALL_IPS}:9978 ${FORCE_NEW_CLUSTER}
     env:
 ${COMPUTED_ENV_VARS}
       - name: "ETCD_STATIC_POD_REV"
@@ -1182,6 +1218,9 @@ spec:
     - name: etcd-metrics
       port: 9979
       protocol: TCP
+    - name: etcd-health-monitor
+      port: 9980
+      protocol: TCP
 `)
 
 func etcdSvcYamlBytes() ([]byte, error) {
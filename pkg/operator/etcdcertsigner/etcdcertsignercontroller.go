@@ -6,6 +6,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
@@ -21,11 +24,13 @@ import (
 
 	operatorv1 "github.com/openshift/api/operator/v1"
 	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/crypto"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/v1helpers"
 
 	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
 	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
 )
@@ -35,9 +40,36 @@ const (
 	// cert regeneration if a node was deleted and added with the same name.
 	nodeUIDAnnotation = "etcd-operator.alpha.openshift.io/cert-secret-node-uid"
 
+	// Annotation requesting regeneration of certs. On the operator resource
+	// the value identifies the request and all member certs are regenerated
+	// once per value. On a cert secret the value is ignored and the annotation
+	// is removed once the cert is regenerated.
+	CertRegenerationRequestedAnnotation = "etcd-operator.alpha.openshift.io/cert-regeneration-requested"
+
+	// Annotation key used to record the operator regeneration request a cert
+	// secret was last regenerated for.
+	certRegenerationCompletedAnnotation = "etcd-operator.alpha.openshift.io/cert-regeneration-completed"
+
+	// Annotation key marking a client cert secret issued by the operator, the
+	// value is the common name of the cert. Client certs without it, e.g. the
+	// etcd-client cert created by the installer, keep their identity until
+	// they are regenerated for another reason.
+	issuedClientCertAnnotation = "etcd-operator.alpha.openshift.io/issued-client-cert"
+
 	// The minimum percentage duration of a certificate. If a cert has less than
 	// this percentage of its duration remaining, it will be regenerated.
-	defaultMinDurationPercent = 0.20
+	defaultMinDurationPercent = float64(tlshelpers.DefaultRotationThresholdPercent) / 100
+
+	// Condition reported when certs are past the point they should have been
+	// regenerated and regeneration failed.
+	certificatesExpiringCondition = "EtcdCertificatesExpiring"
+
+	// Condition reporting the progress of cert regeneration requests.
+	certRegenerationCondition = "EtcdCertRegenerationProgressing"
+
+	// Class of the client certs of clientCertConsumers. Unlike the other
+	// classes its certs are not issued per node.
+	clientCertClass = "client"
 )
 
 // etcdCertConfig defines the configuration required to maintain a cert secret for an etcd member.
@@ -47,26 +79,79 @@ type etcdCertConfig struct {
 	// Function that derives the name of the cert secret from the node name
 	secretNameFunc func(nodeName string) string
 	// Function that creates the key material for a new cert
-	newCertFunc func(caCert, caKey []byte, ipAddresses []string) (*bytes.Buffer, *bytes.Buffer, error)
+	newCertFunc func(caCert, caKey []byte, ipAddresses []string, opts tlshelpers.CertOptions) (*bytes.Buffer, *bytes.Buffer, error)
+	// Validity and key algorithm of new certs
+	certOptions tlshelpers.CertOptions
+	// Percentage duration of a cert remaining at which it will be regenerated
+	minDurationPercent float64
+	// Common name of the cert, certs issued by the operator with a different
+	// common name are regenerated. Not checked if empty.
+	commonName string
 }
 
 // Define configuration for creating etcd cert secrets.
 var certConfigs = map[string]etcdCertConfig{
 	"peer": {
-		caSecretName:   "etcd-signer",
-		secretNameFunc: tlshelpers.GetPeerClientSecretNameForNode,
-		newCertFunc:    tlshelpers.CreatePeerCertKey,
+		caSecretName:       "etcd-signer",
+		secretNameFunc:     tlshelpers.GetPeerClientSecretNameForNode,
+		newCertFunc:        tlshelpers.CreatePeerCertKey,
+		certOptions:        tlshelpers.DefaultCertOptions(),
+		minDurationPercent: defaultMinDurationPercent,
 	},
 	"serving": {
-		caSecretName:   "etcd-signer",
-		secretNameFunc: tlshelpers.GetServingSecretNameForNode,
-		newCertFunc:    tlshelpers.CreateServerCertKey,
+		caSecretName:       "etcd-signer",
+		secretNameFunc:     tlshelpers.GetServingSecretNameForNode,
+		newCertFunc:        tlshelpers.CreateServerCertKey,
+		certOptions:        tlshelpers.DefaultCertOptions(),
+		minDurationPercent: defaultMinDurationPercent,
 	},
 	"serving-metrics": {
-		caSecretName:   "etcd-metric-signer",
-		secretNameFunc: tlshelpers.GetServingMetricsSecretNameForNode,
-		newCertFunc:    tlshelpers.CreateMetricCertKey,
+		caSecretName:       "etcd-metric-signer",
+		secretNameFunc:     tlshelpers.GetServingMetricsSecretNameForNode,
+		newCertFunc:        tlshelpers.CreateMetricCertKey,
+		certOptions:        tlshelpers.DefaultCertOptions(),
+		minDurationPercent: defaultMinDurationPercent,
 	},
+	// The secret name and cert func are defined per consumer, see clientCertConsumer.certConfig.
+	clientCertClass: {
+		caSecretName:       "etcd-signer",
+		certOptions:        tlshelpers.DefaultCertOptions(),
+		minDurationPercent: defaultMinDurationPercent,
+	},
+}
+
+// clientCertConsumer defines a client of etcd that is issued a dedicated
+// client cert identifying it.
+type clientCertConsumer struct {
+	// Name of the consumer used in the common name of its cert
+	name string
+	// Namespace and name of the secret the cert is stored in
+	namespace  string
+	secretName string
+	// Whether the consumer runs in the etcd static pods. Its cert is then
+	// aggregated in the etcd-all-certs secret as $secretName.crt and .key.
+	staticPod bool
+}
+
+// Define the consumers of the etcd-signer issued client certs. The
+// etcd-client secret of openshift-config is also synced by other operators,
+// e.g. for the openshift-apiserver, which share the kube-apiserver identity.
+var clientCertConsumers = []clientCertConsumer{
+	{name: "kube-apiserver", namespace: operatorclient.GlobalUserSpecifiedConfigNamespace, secretName: "etcd-client"},
+	{name: "etcd-operator", namespace: operatorclient.OperatorNamespace, secretName: "etcd-client"},
+	{name: "quorum-guard", namespace: operatorclient.TargetNamespace, secretName: "etcd-client"},
+	{name: "backup", namespace: operatorclient.TargetNamespace, secretName: "etcd-client-backup", staticPod: true},
+	{name: "monitor", namespace: operatorclient.TargetNamespace, secretName: "etcd-client-monitor", staticPod: true},
+}
+
+// certConfig returns the config of the consumer's cert based on the config
+// of the client cert class.
+func (consumer clientCertConsumer) certConfig(classConfig etcdCertConfig) etcdCertConfig {
+	classConfig.newCertFunc = func(caCert, caKey []byte, _ []string, opts tlshelpers.CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
+		return tlshelpers.CreateClientCertKey(caCert, caKey, consumer.name, opts)
+	}
+	classConfig.commonName = tlshelpers.GetClientCommonNameForConsumer(consumer.name)
+	return classConfig
 }
 
 type EtcdCertSignerController struct {
@@ -101,40 +186,85 @@ func NewEtcdCertSignerController(
 		kubeInformers.InformersFor("").Core().V1().Nodes().Informer(),
 		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Secrets().Informer(),
 		kubeInformers.InformersFor(operatorclient.GlobalUserSpecifiedConfigNamespace).Core().V1().Secrets().Informer(),
+		// the dedicated client cert of the operator
+		kubeInformers.InformersFor(operatorclient.OperatorNamespace).Core().V1().Secrets().Informer(),
 		operatorClient.Informer(),
 	).WithSync(c.sync).ToController("EtcdCertSignerController", eventRecorder.WithComponentSuffix("etcd-cert-signer-controller"))
 }
 
 func (c *EtcdCertSignerController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
-	err := c.syncAllMasters(ctx, syncCtx.Recorder())
+	operatorMeta, err := c.operatorClient.GetObjectMeta()
+	if err != nil {
+		return err
+	}
+	requestID := operatorMeta.Annotations[CertRegenerationRequestedAnnotation]
+	requested, err := c.pendingRegenerationRequests(requestID)
 	if err != nil {
-		_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+		return err
+	}
+
+	configs, memberURLs, err := c.configuredCertConfigs()
+	if err == nil {
+		err = c.syncAllMasters(ctx, syncCtx.Recorder(), configs, memberURLs, requestID)
+	}
+
+	// the certs are checked against the effective regeneration thresholds,
+	// or the defaults if the overrides are invalid.
+	observedConfigs := configs
+	if observedConfigs == nil {
+		observedConfigs = certConfigs
+	}
+	certs, observeErr := c.observeCertExpiry(observedConfigs)
+	if observeErr != nil {
+		klog.Warningf("Failed to observe etcd certificate expiry: %v", observeErr)
+	}
+	certExpiry.Set(certs)
+	conditionFns := []v1helpers.UpdateStatusFunc{
+		v1helpers.UpdateConditionFn(newExpiringCondition(certs, err, time.Now())),
+	}
+	if regenerationCondition := newRegenerationCondition(requestID, requested, err); regenerationCondition != nil {
+		conditionFns = append(conditionFns, v1helpers.UpdateConditionFn(*regenerationCondition))
+	}
+
+	if err != nil {
+		_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient, append(conditionFns, v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
 			Type:    "EtcdCertSignerControllerDegraded",
 			Status:  operatorv1.ConditionTrue,
 			Reason:  "Error",
 			Message: err.Error(),
-		}))
+		}))...)
 		if updateErr != nil {
 			syncCtx.Recorder().Warning("EtcdCertSignerControllerUpdatingStatus", updateErr.Error())
 		}
 		return err
 	}
 
-	_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient,
+	_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient, append(conditionFns,
 		v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
 			Type:   "EtcdCertSignerControllerDegraded",
 			Status: operatorv1.ConditionFalse,
 			Reason: "AsExpected",
-		}))
+		}))...)
 	return updateErr
 
 }
 
-func (c *EtcdCertSignerController) syncAllMasters(ctx context.Context, recorder events.Recorder) error {
-	certs, err := c.ensureCerts(ctx, recorder)
+// syncAllMasters ensures the cert secrets of all master nodes and of the
+// client cert consumers and aggregates the certs used by the etcd static pods.
+// Certs regenerated on request are regenerated in a single sync so that they
+// are rolled out in a single revision.
+func (c *EtcdCertSignerController) syncAllMasters(ctx context.Context, recorder events.Recorder, configs map[string]etcdCertConfig, memberURLs ceohelpers.MemberURLConfig, requestID string) error {
+	certs, err := c.ensureCerts(ctx, recorder, configs, memberURLs, requestID)
 	if err != nil {
 		return err
 	}
+	clientCerts, err := c.ensureClientCerts(ctx, recorder, configs[clientCertClass], requestID)
+	if err != nil {
+		return err
+	}
+	for key, value := range clientCerts {
+		certs[key] = value
+	}
 
 	// Write a secret that aggregates all certs for all nodes for the static
 	// pod controller to watch. A single secret ensures that a cert change
@@ -165,7 +295,7 @@ func (c *EtcdCertSignerController) syncAllMasters(ctx context.Context, recorder
 //   "etcd-serving-master-0.key": []byte{...},
 //   ...
 // }
-func (c *EtcdCertSignerController) ensureCerts(ctx context.Context, recorder events.Recorder) (map[string][]byte, error) {
+func (c *EtcdCertSignerController) ensureCerts(ctx context.Context, recorder events.Recorder, configs map[string]etcdCertConfig, memberURLs ceohelpers.MemberURLConfig, requestID string) (map[string][]byte, error) {
 	nodes, err := c.nodeLister.List(labels.Set{"node-role.kubernetes.io/master": ""}.AsSelector())
 	if err != nil {
 		return nil, err
@@ -174,7 +304,7 @@ func (c *EtcdCertSignerController) ensureCerts(ctx context.Context, recorder eve
 	errs := []error{}
 	certs := map[string][]byte{}
 	for _, node := range nodes {
-		certsForNode, certErrs := c.ensureCertsForNode(ctx, node, recorder)
+		certsForNode, certErrs := c.ensureCertsForNode(ctx, node, recorder, configs, memberURLs, requestID)
 		if certErrs != nil {
 			errs = append(errs, certErrs...)
 		}
@@ -195,18 +325,25 @@ func (c *EtcdCertSignerController) ensureCerts(ctx context.Context, recorder eve
 }
 
 // ensureCertsForNode attempts to ensure the existence of secrets containing the
-// etcd cert (cert+key) pairs needed for an etcd member.
-func (c *EtcdCertSignerController) ensureCertsForNode(ctx context.Context, node *corev1.Node, recorder events.Recorder) (map[string][]byte, []error) {
-	ipAddresses, err := dnshelpers.GetInternalIPAddressesForNodeName(node)
+// etcd cert (cert+key) pairs needed for an etcd member. In DNS member URL mode
+// the certs are also valid for the DNS name of the member.
+func (c *EtcdCertSignerController) ensureCertsForNode(ctx context.Context, node *corev1.Node, recorder events.Recorder, configs map[string]etcdCertConfig, memberURLs ceohelpers.MemberURLConfig, requestID string) (map[string][]byte, []error) {
+	hostNames, err := dnshelpers.GetInternalIPAddressesForNodeName(node)
 	if err != nil {
 		return nil, []error{err}
 	}
+	if memberURLs.Mode == ceohelpers.DNSMemberURLMode {
+		hostNames = append(hostNames, memberURLs.DNSName(node.Name))
+	}
 
 	errs := []error{}
 	certs := map[string][]byte{}
-	for _, certConfig := range certConfigs {
+	for class, certConfig := range configs {
+		if class == clientCertClass {
+			continue
+		}
 		secretName := certConfig.secretNameFunc(node.Name)
-		cert, key, err := c.ensureCertSecret(ctx, secretName, string(node.UID), ipAddresses, certConfig, recorder)
+		cert, key, err := c.ensureCertSecret(ctx, operatorclient.TargetNamespace, secretName, string(node.UID), requestID, hostNames, certConfig, recorder)
 		if err != nil {
 			errs = append(errs, err)
 			continue
@@ -220,23 +357,64 @@ func (c *EtcdCertSignerController) ensureCertsForNode(ctx context.Context, node
 	return certs, nil
 }
 
+// ensureClientCerts attempts to ensure the existence of the cert secrets of
+// all client cert consumers and if successful returns the cert pairs of the
+// consumers running in the etcd static pods.
+func (c *EtcdCertSignerController) ensureClientCerts(ctx context.Context, recorder events.Recorder, classConfig etcdCertConfig, requestID string) (map[string][]byte, error) {
+	errs := []error{}
+	certs := map[string][]byte{}
+	for _, consumer := range clientCertConsumers {
+		cert, key, err := c.ensureCertSecret(ctx, consumer.namespace, consumer.secretName, "", requestID, nil, consumer.certConfig(classConfig), recorder)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", consumer.namespace, consumer.secretName, err))
+			continue
+		}
+		if consumer.staticPod {
+			certs[fmt.Sprintf("%s.crt", consumer.secretName)] = cert
+			certs[fmt.Sprintf("%s.key", consumer.secretName)] = key
+		}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	return certs, nil
+}
+
 // ensureCertSecret attempts to ensure the existence of a secret containing an
 // etcd cert (cert+key) pair. The secret will be created if it does not
 // exist. If the secret exists but contains an invalid cert pair, it will be
 // updated with a new cert pair. If the secret is ensured to have a valid
-// cert pair, the bytes of the cert and key will be returned.
-func (c *EtcdCertSignerController) ensureCertSecret(ctx context.Context, secretName, nodeUID string, ipAddresses []string, certConfig etcdCertConfig, recorder events.Recorder) ([]byte, []byte, error) {
-	secret, err := c.secretLister.Secrets(operatorclient.TargetNamespace).Get(secretName)
+// cert pair, the bytes of the cert and key will be returned. A valid cert pair
+// is regenerated if requested by requestID or an annotation of the secret.
+func (c *EtcdCertSignerController) ensureCertSecret(ctx context.Context, namespace, secretName, nodeUID, requestID string, hostNames []string, certConfig etcdCertConfig, recorder events.Recorder) ([]byte, []byte, error) {
+	secret, err := c.secretLister.Secrets(namespace).Get(secretName)
 	if err != nil && !errors.IsNotFound(err) {
 		return nil, nil, err
 	}
 
+	caSecret, err := c.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(certConfig.caSecretName)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	storedUID := ""
 	if secret != nil {
 		storedUID := secret.Annotations[nodeUIDAnnotation]
-		invalidMsg, err := checkCertValidity(secret.Data["tls.crt"], secret.Data["tls.key"], ipAddresses, nodeUID, storedUID)
+		invalidMsg, err := checkCertValidity(secret.Data["tls.crt"], secret.Data["tls.key"], caSecret.Data["tls.crt"], hostNames, nodeUID, storedUID, certConfig.minDurationPercent)
 		if err != nil {
-			return nil, nil, err
+			// A requested regeneration also replaces a cert that is not
+			// valid for the current IPs of its node, e.g. once the peer URL
+			// of the member was repaired by the PeerURLDriftController.
+			if _, requested := secret.Annotations[CertRegenerationRequestedAnnotation]; !requested {
+				return nil, nil, err
+			}
+			invalidMsg = fmt.Sprintf("%v", err)
+		}
+		if _, issued := secret.Annotations[issuedClientCertAnnotation]; issued && len(invalidMsg) == 0 {
+			invalidMsg = checkCommonName(secret.Data["tls.crt"], certConfig.commonName)
+		}
+		if len(invalidMsg) == 0 {
+			invalidMsg = regenerationRequested(secret, requestID)
 		}
 		if len(invalidMsg) > 0 {
 			klog.V(4).Infof("TLS cert %s is invalid and will be regenerated: %v", secretName, invalidMsg)
@@ -256,11 +434,7 @@ func (c *EtcdCertSignerController) ensureCertSecret(ctx context.Context, secretN
 		}
 	} else {
 		// Generate a new cert pair. The secret is missing or its contents are invalid.
-		caSecret, err := c.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(certConfig.caSecretName)
-		if err != nil {
-			return nil, nil, err
-		}
-		certBuffer, keyBuffer, err := certConfig.newCertFunc(caSecret.Data["tls.crt"], caSecret.Data["tls.key"], ipAddresses)
+		certBuffer, keyBuffer, err := certConfig.newCertFunc(caSecret.Data["tls.crt"], caSecret.Data["tls.key"], hostNames, certConfig.certOptions)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -269,7 +443,15 @@ func (c *EtcdCertSignerController) ensureCertSecret(ctx context.Context, secretN
 	}
 
 	//TODO: Update annotations Not Before and Not After for Cert Rotation
-	newSecret := newCertSecret(secretName, nodeUID, cert, key)
+	newSecret := newCertSecret(namespace, secretName, nodeUID, cert, key)
+	if len(certConfig.commonName) > 0 {
+		newSecret.Annotations[issuedClientCertAnnotation] = certConfig.commonName
+	}
+	if len(requestID) > 0 {
+		newSecret.Annotations[certRegenerationCompletedAnnotation] = requestID
+	}
+	// A trailing dash removes the annotation from an existing secret
+	newSecret.Annotations[CertRegenerationRequestedAnnotation+"-"] = ""
 	_, _, err = resourceapply.ApplySecret(ctx, c.secretClient, recorder, newSecret)
 	if err != nil {
 		return nil, nil, err
@@ -278,16 +460,143 @@ func (c *EtcdCertSignerController) ensureCertSecret(ctx context.Context, secretN
 	return cert, key, nil
 }
 
+// configuredCertConfigs returns the cert configs with the overrides of the
+// operator config applied and the member URL config. Overrides apply to certs
+// issued after the change, existing certs are not regenerated.
+func (c *EtcdCertSignerController) configuredCertConfigs() (map[string]etcdCertConfig, ceohelpers.MemberURLConfig, error) {
+	spec, _, _, err := c.operatorClient.GetOperatorState()
+	if err != nil {
+		return nil, ceohelpers.MemberURLConfig{}, err
+	}
+	classes := []string{}
+	for class := range certConfigs {
+		classes = append(classes, class)
+	}
+	overrides, err := ceohelpers.GetCertConfigs(spec, classes)
+	if err != nil {
+		return nil, ceohelpers.MemberURLConfig{}, err
+	}
+	memberURLs, err := ceohelpers.GetMemberURLConfig(spec)
+	if err != nil {
+		return nil, ceohelpers.MemberURLConfig{}, err
+	}
+	return applyCertConfigOverrides(certConfigs, overrides), memberURLs, nil
+}
+
+func applyCertConfigOverrides(defaults map[string]etcdCertConfig, overrides map[string]ceohelpers.CertConfig) map[string]etcdCertConfig {
+	configs := map[string]etcdCertConfig{}
+	for class, config := range defaults {
+		override := overrides[class]
+		if override.Validity > 0 {
+			config.certOptions.Validity = override.Validity
+		}
+		if len(override.KeyAlgorithm) > 0 {
+			config.certOptions.KeyAlgorithm = override.KeyAlgorithm
+		}
+		if override.RotationThresholdPercent > 0 {
+			config.minDurationPercent = float64(override.RotationThresholdPercent) / 100
+		}
+		configs[class] = config
+	}
+	return configs
+}
+
+// regenerationRequested returns why regeneration of a cert secret was
+// requested, or an empty string if it was not.
+func regenerationRequested(secret *corev1.Secret, requestID string) string {
+	if _, ok := secret.Annotations[CertRegenerationRequestedAnnotation]; ok {
+		return "regeneration requested"
+	}
+	if len(requestID) > 0 && secret.Annotations[certRegenerationCompletedAnnotation] != requestID {
+		return fmt.Sprintf("regeneration requested by operator request %q", requestID)
+	}
+	return ""
+}
+
+// pendingRegenerationRequests returns the names of the existing cert secrets
+// of master nodes and client cert consumers whose regeneration was requested.
+// Client cert secrets outside of the etcd namespace are prefixed with their
+// namespace.
+func (c *EtcdCertSignerController) pendingRegenerationRequests(requestID string) ([]string, error) {
+	nodes, err := c.nodeLister.List(labels.Set{"node-role.kubernetes.io/master": ""}.AsSelector())
+	if err != nil {
+		return nil, err
+	}
+	type secretRef struct {
+		namespace string
+		name      string
+	}
+	refs := []secretRef{}
+	for _, node := range nodes {
+		for class, certConfig := range certConfigs {
+			if class == clientCertClass {
+				continue
+			}
+			refs = append(refs, secretRef{namespace: operatorclient.TargetNamespace, name: certConfig.secretNameFunc(node.Name)})
+		}
+	}
+	for _, consumer := range clientCertConsumers {
+		refs = append(refs, secretRef{namespace: consumer.namespace, name: consumer.secretName})
+	}
+
+	requested := []string{}
+	for _, ref := range refs {
+		secret, err := c.secretLister.Secrets(ref.namespace).Get(ref.name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(regenerationRequested(secret, requestID)) == 0 {
+			continue
+		}
+		if ref.namespace == operatorclient.TargetNamespace {
+			requested = append(requested, ref.name)
+		} else {
+			requested = append(requested, ref.namespace+"/"+ref.name)
+		}
+	}
+	sort.Strings(requested)
+	return requested, nil
+}
+
+// newRegenerationCondition reports the outcome of the regeneration requests
+// pending at the start of a sync. Without requests nil is returned so that
+// the last completed request remains recorded.
+func newRegenerationCondition(requestID string, requested []string, syncErr error) *operatorv1.OperatorCondition {
+	if len(requested) == 0 {
+		return nil
+	}
+	request := ""
+	if len(requestID) > 0 {
+		request = fmt.Sprintf(" for request %q", requestID)
+	}
+	if syncErr != nil {
+		return &operatorv1.OperatorCondition{
+			Type:    certRegenerationCondition,
+			Status:  operatorv1.ConditionTrue,
+			Reason:  "RegenerationPending",
+			Message: fmt.Sprintf("regeneration of %s%s pending: %v", strings.Join(requested, ", "), request, syncErr),
+		}
+	}
+	return &operatorv1.OperatorCondition{
+		Type:    certRegenerationCondition,
+		Status:  operatorv1.ConditionFalse,
+		Reason:  "RegenerationCompleted",
+		Message: fmt.Sprintf("regenerated %s%s", strings.Join(requested, ", "), request),
+	}
+}
+
 // newCertSecret ensures consistency of secret creation between the controller
-// and its tests.
-func newCertSecret(secretName, nodeUID string, cert, key []byte) *corev1.Secret {
-	return &corev1.Secret{
+// and its tests. The node uid annotation is omitted for certs not issued for a
+// node.
+func newCertSecret(namespace, secretName, nodeUID string, cert, key []byte) *corev1.Secret {
+	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: operatorclient.TargetNamespace,
-			Annotations: map[string]string{
-				nodeUIDAnnotation: nodeUID,
-			},
+			Name:        secretName,
+			Namespace:   namespace,
+			Annotations: map[string]string{},
 		},
 		Type: corev1.SecretTypeTLS,
 		Data: map[string][]byte{
@@ -295,13 +604,35 @@ func newCertSecret(secretName, nodeUID string, cert, key []byte) *corev1.Secret
 			"tls.key": key,
 		},
 	}
+	if len(nodeUID) > 0 {
+		secret.Annotations[nodeUIDAnnotation] = nodeUID
+	}
+	return secret
+}
+
+// checkCommonName returns why a cert has to be regenerated if the common name
+// of its leaf cert is not commonName. An empty commonName is not checked.
+// Unparsable certs are left to checkCertValidity.
+func checkCommonName(certBytes []byte, commonName string) string {
+	if len(commonName) == 0 {
+		return ""
+	}
+	certs, err := crypto.CertsFromPEM(certBytes)
+	if err != nil {
+		return ""
+	}
+	if certs[0].Subject.CommonName != commonName {
+		return fmt.Sprintf("common name %q is not %q", certs[0].Subject.CommonName, commonName)
+	}
+	return ""
 }
 
 // checkCertValidity validates the provided cert bytes. If the cert needs to be
 // regenerated, a message will be returned indicating why. An empty message
 // indicates a valid cert. An error will be returned if the cert is not valid
-// and should not be regenerated.
-func checkCertValidity(certBytes, keyBytes []byte, ipAddresses []string, nodeUID, storedUID string) (string, error) {
+// and should not be regenerated. Host names that are not IP addresses are DNS
+// names of the member, a cert missing one is regenerated.
+func checkCertValidity(certBytes, keyBytes, caCertBytes []byte, hostNames []string, nodeUID, storedUID string, minDurationPercent float64) (string, error) {
 	// Loading the keypair without error indicates the key material is valid and
 	// the cert and private key are related.
 	keyPair, err := tls.X509KeyPair(certBytes, keyBytes)
@@ -317,8 +648,14 @@ func checkCertValidity(certBytes, keyBytes []byte, ipAddresses []string, nodeUID
 
 	// Check that the cert is valid for the provided ip addresses
 	errs := []error{}
-	for _, ipAddress := range ipAddresses {
-		if err := leafCert.VerifyHostname(ipAddress); err != nil {
+	dnsNameErrs := []string{}
+	for _, hostName := range hostNames {
+		err := leafCert.VerifyHostname(hostName)
+		switch {
+		case err == nil:
+		case net.ParseIP(hostName) == nil:
+			dnsNameErrs = append(dnsNameErrs, fmt.Sprintf("%v", err))
+		default:
 			errs = append(errs, err)
 		}
 	}
@@ -338,9 +675,9 @@ func checkCertValidity(certBytes, keyBytes []byte, ipAddresses []string, nodeUID
 
 		if nodeUIDUnchanged {
 			// This is an error condition. If the cert SAN doesn't include all
-			// of ip's for the node that it was generated for, an out-of-band
-			// etcd cluster membership change may be required. The operator
-			// doesn't currently handle this.
+			// of ip's for the node that it was generated for, the peer URL
+			// of the member has to change as well. The cert is only
+			// regenerated on request, see ensureCertSecret.
 			return "", utilerrors.NewAggregate(errs)
 		} else {
 			// A different node uid indicates node removal and addition with the
@@ -354,11 +691,24 @@ func checkCertValidity(certBytes, keyBytes []byte, ipAddresses []string, nodeUID
 		}
 	}
 
-	if ok := lessThanMinimumDuration(leafCert.NotBefore, leafCert.NotAfter, defaultMinDurationPercent); ok {
-		return fmt.Sprintf("less than %d%% duration remaining", int64(defaultMinDurationPercent*100)), nil
+	if len(dnsNameErrs) > 0 {
+		// The member switched to or changed its DNS name
+		return strings.Join(dnsNameErrs, ", "), nil
+	}
+
+	if ok := lessThanMinimumDuration(leafCert.NotBefore, leafCert.NotAfter, minDurationPercent); ok {
+		return fmt.Sprintf("less than %d%% duration remaining", int64(minDurationPercent*100)), nil
 	}
 
-	// TODO(marun) Check that the certificate was issued by the CA
+	// A cert not issued by the current CA was issued by a CA that has since
+	// been rotated and will not be trusted once the old CA is dropped.
+	issued, err := tlshelpers.IsIssuedBy(certBytes, caCertBytes)
+	if err != nil {
+		return "", fmt.Errorf("unable to verify cert issuer: %w", err)
+	}
+	if !issued {
+		return "not issued by the current CA", nil
+	}
 
 	// Cert is valid
 	return "", nil
@@ -367,9 +717,126 @@ func checkCertValidity(certBytes, keyBytes []byte, ipAddresses []string, nodeUID
 // lessThanMinimumDuration indicates whether the provided cert has less
 // than the provided minimum percentage of its duration remaining.
 func lessThanMinimumDuration(notBefore, notAfter time.Time, minDurationPercent float64) bool {
+	return lessThanMinimumDurationAt(time.Now(), notBefore, notAfter, minDurationPercent)
+}
+
+func lessThanMinimumDurationAt(now, notBefore, notAfter time.Time, minDurationPercent float64) bool {
 	expiry := notAfter
 	duration := expiry.Sub(notBefore)
 	minDuration := time.Duration(float64(duration.Nanoseconds()) * minDurationPercent)
 	replacementTime := expiry.Add(-minDuration)
-	return time.Now().After(replacementTime)
+	return now.After(replacementTime)
+}
+
+// observeCertExpiry returns the validity of every cert aggregated in the
+// etcd-all-certs secret, of the client certs of consumers outside of the etcd
+// static pods and of the signer CAs, with the regeneration threshold of its
+// class in configs. Missing secrets are skipped, a cert that can't be parsed
+// is reported as an error without preventing the other certs from being
+// observed.
+func (c *EtcdCertSignerController) observeCertExpiry(configs map[string]etcdCertConfig) (map[certKey]certValidity, error) {
+	certs := map[certKey]certValidity{}
+	errs := []error{}
+
+	// Certs that are not issued by this controller, i.e. the signer CAs, are
+	// checked against the default threshold.
+	minDurationPercents := map[certKey]float64{}
+	nodes, err := c.nodeLister.List(labels.Set{"node-role.kubernetes.io/master": ""}.AsSelector())
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, node := range nodes {
+		for class, certConfig := range configs {
+			if class == clientCertClass {
+				continue
+			}
+			key := certKey{namespace: operatorclient.TargetNamespace, secret: tlshelpers.EtcdAllCertsSecretName, cert: fmt.Sprintf("%s.crt", certConfig.secretNameFunc(node.Name))}
+			minDurationPercents[key] = certConfig.minDurationPercent
+		}
+	}
+	for _, consumer := range clientCertConsumers {
+		key := certKey{namespace: consumer.namespace, secret: consumer.secretName, cert: "tls.crt"}
+		if consumer.staticPod {
+			key = certKey{namespace: operatorclient.TargetNamespace, secret: tlshelpers.EtcdAllCertsSecretName, cert: fmt.Sprintf("%s.crt", consumer.secretName)}
+		}
+		minDurationPercents[key] = configs[clientCertClass].minDurationPercent
+	}
+
+	secretRefs := []certKey{{namespace: operatorclient.TargetNamespace, secret: tlshelpers.EtcdAllCertsSecretName}}
+	for _, consumer := range clientCertConsumers {
+		if !consumer.staticPod {
+			secretRefs = append(secretRefs, certKey{namespace: consumer.namespace, secret: consumer.secretName})
+		}
+	}
+	caSecretNames := sets.NewString()
+	for _, certConfig := range certConfigs {
+		caSecretNames.Insert(certConfig.caSecretName)
+	}
+	for _, name := range caSecretNames.List() {
+		secretRefs = append(secretRefs, certKey{namespace: operatorclient.GlobalUserSpecifiedConfigNamespace, secret: name})
+	}
+
+	for _, ref := range secretRefs {
+		secret, err := c.secretLister.Secrets(ref.namespace).Get(ref.secret)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for name, data := range secret.Data {
+			if !strings.HasSuffix(name, ".crt") {
+				continue
+			}
+			key := certKey{namespace: ref.namespace, secret: ref.secret, cert: name}
+			parsed, err := crypto.CertsFromPEM(data)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			minDurationPercent, ok := minDurationPercents[key]
+			if !ok {
+				minDurationPercent = defaultMinDurationPercent
+			}
+			// The leaf is the first cert of a chain
+			certs[key] = certValidity{notBefore: parsed[0].NotBefore, notAfter: parsed[0].NotAfter, minDurationPercent: minDurationPercent}
+		}
+	}
+	return certs, utilerrors.NewAggregate(errs)
+}
+
+// newExpiringCondition reports certs that should already have been
+// regenerated according to the threshold of their class when the sync failed. A cert past its regeneration point is
+// expected while the sync succeeds since the regenerated certs are only
+// observed on the next sync.
+func newExpiringCondition(certs map[certKey]certValidity, syncErr error, now time.Time) operatorv1.OperatorCondition {
+	if syncErr == nil {
+		return operatorv1.OperatorCondition{
+			Type:   certificatesExpiringCondition,
+			Status: operatorv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+
+	expiring := []string{}
+	for key, validity := range certs {
+		if lessThanMinimumDurationAt(now, validity.notBefore, validity.notAfter, validity.minDurationPercent) {
+			expiring = append(expiring, fmt.Sprintf("%s expires %s", key, validity.notAfter.UTC().Format(time.RFC3339)))
+		}
+	}
+	if len(expiring) == 0 {
+		return operatorv1.OperatorCondition{
+			Type:   certificatesExpiringCondition,
+			Status: operatorv1.ConditionFalse,
+			Reason: "AsExpected",
+		}
+	}
+	sort.Strings(expiring)
+	return operatorv1.OperatorCondition{
+		Type:    certificatesExpiringCondition,
+		Status:  operatorv1.ConditionTrue,
+		Reason:  "RotationFailed",
+		Message: fmt.Sprintf("certificates could not be regenerated before expiry: %s: %v", strings.Join(expiring, ", "), syncErr),
+	}
 }
@@ -2,9 +2,11 @@ package etcdcertsigner
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,11 +15,14 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes/fake"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 
+	operatorv1 "github.com/openshift/api/operator/v1"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
 	u "github.com/openshift/cluster-etcd-operator/pkg/testutils"
 	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
@@ -42,10 +47,23 @@ func TestCheckCertValidity(t *testing.T) {
 		Config:          caConfig,
 		SerialGenerator: &crypto.RandomSerialGenerator{},
 	}
+	caCertBytes, _, err := caConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatalf("Error converting ca to bytes: %v", err)
+	}
+	differentCAConfig, err := crypto.MakeSelfSignedCAConfig("foo", expireDays)
+	if err != nil {
+		t.Fatalf("Failed to create ca config: %v", err)
+	}
+	differentCACertBytes, _, err := differentCAConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatalf("Error converting ca to bytes: %v", err)
+	}
 
 	testCases := map[string]struct {
 		invalidCertPair     bool
 		lessThanMinDuration bool
+		differentCA         bool
 		certIPAddresses     []string
 		nodeIPAddresses     []string
 		storedNodeUID       string
@@ -80,6 +98,24 @@ func TestCheckCertValidity(t *testing.T) {
 			lessThanMinDuration: true,
 			expectedRegenMsg:    true,
 		},
+		"issued by a different ca": {
+			certIPAddresses:  ipAddresses,
+			nodeIPAddresses:  ipAddresses,
+			storedNodeUID:    nodeUID,
+			differentCA:      true,
+			expectedRegenMsg: true,
+		},
+		"missing dns name": {
+			certIPAddresses:  ipAddresses,
+			nodeIPAddresses:  append(ipAddresses, "master-0.example.com"),
+			storedNodeUID:    nodeUID,
+			expectedRegenMsg: true,
+		},
+		"valid with dns name": {
+			certIPAddresses: append(ipAddresses, "master-0.example.com"),
+			nodeIPAddresses: append(ipAddresses, "master-0.example.com"),
+			storedNodeUID:   nodeUID,
+		},
 		"valid": {
 			certIPAddresses: ipAddresses,
 			nodeIPAddresses: ipAddresses,
@@ -104,7 +140,11 @@ func TestCheckCertValidity(t *testing.T) {
 					t.Fatalf("Error converting cert to bytes: %v", err)
 				}
 			}
-			msg, err := checkCertValidity(certBytes, keyBytes, tc.nodeIPAddresses, nodeUID, tc.storedNodeUID)
+			currentCACertBytes := caCertBytes
+			if tc.differentCA {
+				currentCACertBytes = differentCACertBytes
+			}
+			msg, err := checkCertValidity(certBytes, keyBytes, currentCACertBytes, tc.nodeIPAddresses, nodeUID, tc.storedNodeUID, defaultMinDurationPercent)
 			if tc.expectedRegenMsg && len(msg) == 0 {
 				t.Fatalf("Expected a regen message")
 			}
@@ -144,7 +184,7 @@ func TestEnsureCertForNode(t *testing.T) {
 			createExpected: true,
 		},
 		"invalid cert secret is regenerated": {
-			certSecret:     newCertSecret(secretName, "", nil, nil),
+			certSecret:     newCertSecret(operatorclient.TargetNamespace, secretName, "", nil, nil),
 			updateExpected: true,
 		},
 		// The test for a valid cert secret is performed after a successful
@@ -160,7 +200,7 @@ func TestEnsureCertForNode(t *testing.T) {
 			fakeKubeClient, controller, recorder := setupController(t, objects)
 			secretName := certConfig.secretNameFunc(node.Name)
 			nodeUID := string(node.UID)
-			_, _, err := controller.ensureCertSecret(context.TODO(), secretName, nodeUID, ipAddresses, certConfig, recorder)
+			_, _, err := controller.ensureCertSecret(context.TODO(), operatorclient.TargetNamespace, secretName, nodeUID, "", ipAddresses, certConfig, recorder)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -182,20 +222,24 @@ func TestEnsureCertForNode(t *testing.T) {
 			if !tc.updateExpected && updatedSecret != nil {
 				t.Fatalf("Secret unexpectedly updated")
 			}
+			caSecret, err := controller.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(certConfig.caSecretName)
+			if err != nil {
+				t.Fatal(err)
+			}
 			if updatedSecret != nil {
-				validateTestSecret(t, "updated", updatedSecret, ipAddresses)
+				validateTestSecret(t, "updated", updatedSecret, caSecret.Data["tls.crt"], ipAddresses)
 			}
 			if !tc.createExpected && createdSecret != nil {
 				t.Fatalf("Secret unexpectedly created")
 			}
 			if createdSecret != nil {
-				validateTestSecret(t, "created", createdSecret, ipAddresses)
+				validateTestSecret(t, "created", createdSecret, caSecret.Data["tls.crt"], ipAddresses)
 
 				// Verify that a cert secret created by ensureCertSecret will
 				// not be updated when immediately round-tripped.
-				objects := []runtime.Object{createdSecret}
+				objects := []runtime.Object{createdSecret, caSecret}
 				fakeKubeClient, controller, recorder := setupController(t, objects)
-				_, _, err := controller.ensureCertSecret(context.TODO(), secretName, nodeUID, ipAddresses, certConfig, recorder)
+				_, _, err := controller.ensureCertSecret(context.TODO(), operatorclient.TargetNamespace, secretName, nodeUID, "", ipAddresses, certConfig, recorder)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -212,11 +256,201 @@ func TestEnsureCertForNode(t *testing.T) {
 	}
 }
 
+func TestEnsureCertSecretRegenerationRequest(t *testing.T) {
+	certConfig := certConfigs["serving"]
+	nodeUID := string(uuid.NewUUID())
+	secretName := certConfig.secretNameFunc("master-0")
+	ipAddresses := []string{"127.0.0.1"}
+
+	// Create a valid cert secret
+	fakeKubeClient, controller, recorder := setupController(t, []runtime.Object{})
+	if _, _, err := controller.ensureCertSecret(context.TODO(), operatorclient.TargetNamespace, secretName, nodeUID, "", ipAddresses, certConfig, recorder); err != nil {
+		t.Fatal(err)
+	}
+	validSecret, err := fakeKubeClient.CoreV1().Secrets(operatorclient.TargetNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caSecret, err := controller.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(certConfig.caSecretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := map[string]struct {
+		annotations       map[string]string
+		requestID         string
+		nodeIPAddresses   []string
+		regenExpected     bool
+		expectedCompleted string
+		expectedErr       bool
+	}{
+		"secret annotated": {
+			annotations:   map[string]string{CertRegenerationRequestedAnnotation: ""},
+			regenExpected: true,
+		},
+		"operator request": {
+			requestID:         "1",
+			regenExpected:     true,
+			expectedCompleted: "1",
+		},
+		"operator request completed": {
+			annotations: map[string]string{certRegenerationCompletedAnnotation: "1"},
+			requestID:   "1",
+		},
+		"new operator request": {
+			annotations:       map[string]string{certRegenerationCompletedAnnotation: "1"},
+			requestID:         "2",
+			regenExpected:     true,
+			expectedCompleted: "2",
+		},
+		"node ip changed": {
+			nodeIPAddresses: []string{"127.0.0.2"},
+			expectedErr:     true,
+		},
+		"node ip changed; secret annotated": {
+			annotations:     map[string]string{CertRegenerationRequestedAnnotation: ""},
+			nodeIPAddresses: []string{"127.0.0.2"},
+			regenExpected:   true,
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			secret := validSecret.DeepCopy()
+			for key, value := range tc.annotations {
+				secret.Annotations[key] = value
+			}
+			nodeIPAddresses := ipAddresses
+			if tc.nodeIPAddresses != nil {
+				nodeIPAddresses = tc.nodeIPAddresses
+			}
+			fakeKubeClient, controller, recorder := setupController(t, []runtime.Object{secret, caSecret})
+			cert, _, err := controller.ensureCertSecret(context.TODO(), operatorclient.TargetNamespace, secretName, nodeUID, tc.requestID, nodeIPAddresses, certConfig, recorder)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr {
+				return
+			}
+			regenerated := string(cert) != string(validSecret.Data["tls.crt"])
+			if regenerated != tc.regenExpected {
+				t.Fatalf("expected regeneration %v, got %v", tc.regenExpected, regenerated)
+			}
+			if !tc.regenExpected {
+				return
+			}
+			var updatedSecret *corev1.Secret
+			for _, action := range fakeKubeClient.Actions() {
+				if action.Matches("update", "secrets") {
+					updatedSecret = action.(clientgotesting.UpdateAction).GetObject().(*corev1.Secret)
+				}
+			}
+			if updatedSecret == nil {
+				t.Fatalf("secret was not updated")
+			}
+			validateTestSecret(t, "updated", updatedSecret, caSecret.Data["tls.crt"], nodeIPAddresses)
+			if _, ok := updatedSecret.Annotations[CertRegenerationRequestedAnnotation]; ok {
+				t.Fatalf("regeneration request was not removed")
+			}
+			if completed := updatedSecret.Annotations[certRegenerationCompletedAnnotation]; completed != tc.expectedCompleted {
+				t.Fatalf("expected completed request %q, got %q", tc.expectedCompleted, completed)
+			}
+		})
+	}
+}
+
+func TestNewRegenerationCondition(t *testing.T) {
+	requested := []string{"etcd-peer-master-0", "etcd-serving-master-0"}
+	testCases := map[string]struct {
+		requested       []string
+		syncErr         error
+		expectedNil     bool
+		expectedStatus  operatorv1.ConditionStatus
+		expectedMessage string
+	}{
+		"no request": {
+			expectedNil: true,
+		},
+		"request pending": {
+			requested:       requested,
+			syncErr:         fmt.Errorf("signer not found"),
+			expectedStatus:  operatorv1.ConditionTrue,
+			expectedMessage: `regeneration of etcd-peer-master-0, etcd-serving-master-0 for request "1" pending: signer not found`,
+		},
+		"request completed": {
+			requested:       requested,
+			expectedStatus:  operatorv1.ConditionFalse,
+			expectedMessage: `regenerated etcd-peer-master-0, etcd-serving-master-0 for request "1"`,
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			condition := newRegenerationCondition("1", tc.requested, tc.syncErr)
+			if tc.expectedNil {
+				if condition != nil {
+					t.Fatalf("unexpected condition: %v", condition)
+				}
+				return
+			}
+			if condition.Status != tc.expectedStatus {
+				t.Fatalf("expected status %s, got %s", tc.expectedStatus, condition.Status)
+			}
+			if condition.Message != tc.expectedMessage {
+				t.Fatalf("expected message %q, got %q", tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
+
+func TestEnsureCertSecretCertOptions(t *testing.T) {
+	overrides := map[string]ceohelpers.CertConfig{
+		"peer": {
+			Validity:                 30 * 24 * time.Hour,
+			RotationThresholdPercent: 50,
+			KeyAlgorithm:             tlshelpers.ECDSAP256KeyAlgorithm,
+		},
+	}
+	configs := applyCertConfigOverrides(certConfigs, overrides)
+	if configs["serving"].certOptions != tlshelpers.DefaultCertOptions() || configs["serving"].minDurationPercent != defaultMinDurationPercent {
+		t.Fatalf("defaults of a class without overrides were changed")
+	}
+	certConfig := configs["peer"]
+	if certConfig.minDurationPercent != 0.5 {
+		t.Fatalf("expected rotation threshold of 0.5, got %v", certConfig.minDurationPercent)
+	}
+
+	ipAddresses := []string{"127.0.0.1"}
+	secretName := certConfig.secretNameFunc("master-0")
+	fakeKubeClient, controller, recorder := setupController(t, []runtime.Object{})
+	if _, _, err := controller.ensureCertSecret(context.TODO(), operatorclient.TargetNamespace, secretName, "uid", "", ipAddresses, certConfig, recorder); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := fakeKubeClient.CoreV1().Secrets(operatorclient.TargetNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caSecret, err := controller.secretLister.Secrets(operatorclient.GlobalUserSpecifiedConfigNamespace).Get(certConfig.caSecretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateTestSecret(t, "created", secret, caSecret.Data["tls.crt"], ipAddresses)
+
+	certs, err := crypto.CertsFromPEM(secret.Data["tls.crt"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := certs[0].PublicKey.(*ecdsa.PublicKey); !ok {
+		t.Fatalf("expected an ECDSA key, got %T", certs[0].PublicKey)
+	}
+	if validity := certs[0].NotAfter.Sub(certs[0].NotBefore).Round(time.Hour); validity != 30*24*time.Hour {
+		t.Fatalf("expected a validity of 30 days, got %s", validity)
+	}
+}
+
 // Validate that a successful test run will result in a secret per
 // cert type per node and an aggregated secret per cert type.
 func TestSyncAllMasters(t *testing.T) {
 	fakeKubeClient, controller, recorder := setupController(t, []runtime.Object{})
-	err := controller.syncAllMasters(context.TODO(), recorder)
+	err := controller.syncAllMasters(context.TODO(), recorder, certConfigs, ceohelpers.MemberURLConfig{}, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -232,7 +466,10 @@ func TestSyncAllMasters(t *testing.T) {
 	for _, secret := range secrets.Items {
 		secretMap[secret.Name] = secret
 	}
-	for _, certConfig := range certConfigs {
+	for class, certConfig := range certConfigs {
+		if class == clientCertClass {
+			continue
+		}
 		// Cert secret per type per node
 		for _, node := range nodes.Items {
 			secretName := certConfig.secretNameFunc(node.Name)
@@ -253,7 +490,10 @@ func TestSyncAllMasters(t *testing.T) {
 			t.Fatalf("secret %s is missing", secretName)
 		}
 		// Cert pair per type per node
-		for _, certConfig := range certConfigs {
+		for class, certConfig := range certConfigs {
+			if class == clientCertClass {
+				continue
+			}
 			for _, node := range nodes.Items {
 				secretName := certConfig.secretNameFunc(node.Name)
 				certName := fmt.Sprintf("%s.crt", secretName)
@@ -261,7 +501,217 @@ func TestSyncAllMasters(t *testing.T) {
 				checkCertPairSecret(t, secretName, certName, keyName, allSecret.Data)
 			}
 		}
+		// Cert pair per client cert consumer of the static pods
+		for _, consumer := range clientCertConsumers {
+			if !consumer.staticPod {
+				continue
+			}
+			certName := fmt.Sprintf("%s.crt", consumer.secretName)
+			keyName := fmt.Sprintf("%s.key", consumer.secretName)
+			checkCertPairSecret(t, consumer.secretName, certName, keyName, allSecret.Data)
+		}
+	})
+	// A client cert secret per consumer
+	for _, consumer := range clientCertConsumers {
+		secret, err := fakeKubeClient.CoreV1().Secrets(consumer.namespace).Get(context.Background(), consumer.secretName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("secret %s/%s is missing: %v", consumer.namespace, consumer.secretName, err)
+		}
+		checkCertPairSecret(t, consumer.secretName, "tls.crt", "tls.key", secret.Data)
+	}
+}
+
+// Validate that consumers are issued client certs identifying them, that
+// certs issued by the operator with a different identity are regenerated and
+// that the shared etcd-client cert created by the installer is kept.
+func TestEnsureClientCerts(t *testing.T) {
+	caSecret := newCASecret(t, "etcd-signer")
+	caConfig, err := crypto.GetCAFromBytes(caSecret.Data["tls.crt"], caSecret.Data["tls.key"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedConfig, err := caConfig.MakeClientCertificateForDuration(&user.DefaultInfo{Name: "etcd", Groups: []string{"etcd"}}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedCert, sharedKey, err := sharedConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedSecret := newCertSecret(operatorclient.GlobalUserSpecifiedConfigNamespace, "etcd-client", "", sharedCert, sharedKey)
+	issuedSecret := newCertSecret(operatorclient.OperatorNamespace, "etcd-client", "", sharedCert, sharedKey)
+	issuedSecret.Annotations[issuedClientCertAnnotation] = "etcd"
+
+	fakeKubeClient, controller, recorder := setupController(t, []runtime.Object{caSecret, sharedSecret, issuedSecret})
+	certs, err := controller.ensureClientCerts(context.TODO(), recorder, certConfigs[clientCertClass], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 4 {
+		t.Fatalf("expected the cert pairs of the backup and monitor consumers, got %v", sets.StringKeySet(certs).List())
+	}
+
+	commonNames := sets.NewString()
+	for _, consumer := range clientCertConsumers {
+		secret, err := fakeKubeClient.CoreV1().Secrets(consumer.namespace).Get(context.TODO(), consumer.secretName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := secret.Annotations[nodeUIDAnnotation]; ok {
+			t.Fatalf("%s/%s: unexpected node uid annotation", consumer.namespace, consumer.secretName)
+		}
+		validateTestSecret(t, "ensure", secret, caSecret.Data["tls.crt"], nil)
+		parsed, err := crypto.CertsFromPEM(secret.Data["tls.crt"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert := parsed[0]
+		if consumer.namespace == sharedSecret.Namespace && consumer.secretName == sharedSecret.Name {
+			if string(secret.Data["tls.crt"]) != string(sharedCert) {
+				t.Fatalf("%s/%s: the cert created by the installer was regenerated", consumer.namespace, consumer.secretName)
+			}
+			continue
+		}
+		expected := tlshelpers.GetClientCommonNameForConsumer(consumer.name)
+		if secret.Annotations[issuedClientCertAnnotation] != expected {
+			t.Fatalf("%s/%s: expected the issued client cert annotation %q, got %q", consumer.namespace, consumer.secretName, expected, secret.Annotations[issuedClientCertAnnotation])
+		}
+		if cert.Subject.CommonName != expected {
+			t.Fatalf("%s/%s: expected common name %q, got %q", consumer.namespace, consumer.secretName, expected, cert.Subject.CommonName)
+		}
+		if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+			t.Fatalf("%s/%s: expected a client cert, got ext key usages %v", consumer.namespace, consumer.secretName, cert.ExtKeyUsage)
+		}
+		commonNames.Insert(cert.Subject.CommonName)
+	}
+	if commonNames.Len() != len(clientCertConsumers)-1 {
+		t.Fatalf("expected a distinct identity per consumer, got %v", commonNames.List())
+	}
+}
+
+func TestObserveCertExpiry(t *testing.T) {
+	_, controller, _ := setupController(t, []runtime.Object{})
+	// Before the aggregated secret is written only the signers are observed
+	certs, err := controller.observeCertExpiry(certConfigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected the 2 signer certs, got %v", certs)
+	}
+
+	fakeKubeClient, controller, recorder := setupController(t, []runtime.Object{})
+	if err := controller.syncAllMasters(context.TODO(), recorder, certConfigs, ceohelpers.MemberURLConfig{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	allCerts, err := fakeKubeClient.CoreV1().Secrets(operatorclient.TargetNamespace).Get(context.TODO(), tlshelpers.EtcdAllCertsSecretName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The controller lister is backed by a static indexer
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if err := indexer.Add(allCerts); err != nil {
+		t.Fatal(err)
+	}
+	controller.secretLister = corev1listers.NewSecretLister(indexer)
+
+	configs := applyCertConfigOverrides(certConfigs, map[string]ceohelpers.CertConfig{
+		"peer":          {RotationThresholdPercent: 50},
+		clientCertClass: {RotationThresholdPercent: 40},
 	})
+	certs, err = controller.observeCertExpiry(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := fakeKubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for class, certConfig := range certConfigs {
+		if class == clientCertClass {
+			continue
+		}
+		for _, node := range nodes.Items {
+			key := certKey{
+				namespace: operatorclient.TargetNamespace,
+				secret:    tlshelpers.EtcdAllCertsSecretName,
+				cert:      fmt.Sprintf("%s.crt", certConfig.secretNameFunc(node.Name)),
+			}
+			validity, ok := certs[key]
+			if !ok {
+				t.Fatalf("expiry of %s is missing", key)
+			}
+			if !validity.notAfter.After(time.Now()) {
+				t.Fatalf("unexpected expiry of %s: %v", key, validity.notAfter)
+			}
+			if validity.minDurationPercent != configs[class].minDurationPercent {
+				t.Fatalf("expected regeneration threshold %v of %s, got %v", configs[class].minDurationPercent, key, validity.minDurationPercent)
+			}
+		}
+	}
+	for _, consumer := range clientCertConsumers {
+		if !consumer.staticPod {
+			continue
+		}
+		key := certKey{namespace: operatorclient.TargetNamespace, secret: tlshelpers.EtcdAllCertsSecretName, cert: fmt.Sprintf("%s.crt", consumer.secretName)}
+		if certs[key].minDurationPercent != 0.4 {
+			t.Fatalf("expected regeneration threshold 0.4 of %s, got %v", key, certs[key].minDurationPercent)
+		}
+	}
+	// The backup and monitor client certs are aggregated as well
+	if len(certs) != (len(certConfigs)-1)*len(nodes.Items)+2 {
+		t.Fatalf("expected only the aggregated certs, got %d", len(certs))
+	}
+}
+
+func TestNewExpiringCondition(t *testing.T) {
+	now := time.Now()
+	key := certKey{namespace: operatorclient.TargetNamespace, secret: tlshelpers.EtcdAllCertsSecretName, cert: "etcd-peer-master-0.crt"}
+	valid := certValidity{notBefore: now.Add(-time.Hour), notAfter: now.Add(9 * time.Hour), minDurationPercent: defaultMinDurationPercent}
+	expiring := certValidity{notBefore: now.Add(-9 * time.Hour), notAfter: now.Add(time.Hour), minDurationPercent: defaultMinDurationPercent}
+	// 30% remaining is past a configured threshold of 50%
+	expiringForClass := certValidity{notBefore: now.Add(-7 * time.Hour), notAfter: now.Add(3 * time.Hour), minDurationPercent: 0.5}
+	syncErr := fmt.Errorf("signer not found")
+
+	testCases := map[string]struct {
+		validity       certValidity
+		syncErr        error
+		expectedStatus operatorv1.ConditionStatus
+	}{
+		"valid cert": {
+			validity:       valid,
+			syncErr:        syncErr,
+			expectedStatus: operatorv1.ConditionFalse,
+		},
+		"expiring cert is being rotated": {
+			validity:       expiring,
+			expectedStatus: operatorv1.ConditionFalse,
+		},
+		"expiring cert rotation failed": {
+			validity:       expiring,
+			syncErr:        syncErr,
+			expectedStatus: operatorv1.ConditionTrue,
+		},
+		"cert past the threshold of its class rotation failed": {
+			validity:       expiringForClass,
+			syncErr:        syncErr,
+			expectedStatus: operatorv1.ConditionTrue,
+		},
+	}
+	for testName, tc := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			condition := newExpiringCondition(map[certKey]certValidity{key: tc.validity}, tc.syncErr, now)
+			if condition.Type != certificatesExpiringCondition {
+				t.Fatalf("unexpected condition type %s", condition.Type)
+			}
+			if condition.Status != tc.expectedStatus {
+				t.Fatalf("expected status %s, got %s: %s", tc.expectedStatus, condition.Status, condition.Message)
+			}
+			if condition.Status == operatorv1.ConditionTrue && !strings.Contains(condition.Message, key.String()) {
+				t.Fatalf("expected message to name %s: %s", key, condition.Message)
+			}
+		})
+	}
 }
 
 func checkCertPairSecret(t *testing.T, secretName, certName, keyName string, secretData map[string][]byte) {
@@ -281,6 +731,12 @@ func setupController(t *testing.T, objects []runtime.Object) (*fake.Clientset, *
 		u.FakeNode("master-2", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.3")),
 	)
 	signerNames := sets.NewString()
+	for _, obj := range objects {
+		// Reuse provided CAs
+		if secret, ok := obj.(*corev1.Secret); ok && secret.Namespace == operatorclient.GlobalUserSpecifiedConfigNamespace {
+			signerNames.Insert(secret.Name)
+		}
+	}
 	for _, certConfig := range certConfigs {
 		name := certConfig.caSecretName
 		if signerNames.Has(name) {
@@ -337,12 +793,12 @@ func newCASecret(t *testing.T, secretName string) *corev1.Secret {
 
 // validateTestSecret checks that a secret created or updated by
 // ensureCertSecret is valid acording to checkCertValidity.
-func validateTestSecret(t *testing.T, action string, secret *corev1.Secret, ipAddresses []string) {
+func validateTestSecret(t *testing.T, action string, secret *corev1.Secret, caCert []byte, ipAddresses []string) {
 	if secret.Data == nil {
 		t.Fatalf("%s secret is empty", action)
 	}
 	storedNodeUID := secret.Annotations[nodeUIDAnnotation]
-	msg, err := checkCertValidity(secret.Data["tls.crt"], secret.Data["tls.key"], ipAddresses, storedNodeUID, storedNodeUID)
+	msg, err := checkCertValidity(secret.Data["tls.crt"], secret.Data["tls.key"], caCert, ipAddresses, storedNodeUID, storedNodeUID, defaultMinDurationPercent)
 	if len(msg) > 0 {
 		t.Fatalf("%s secret is invalid with message: %s", action, msg)
 	}
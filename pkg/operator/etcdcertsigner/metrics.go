@@ -0,0 +1,78 @@
+package etcdcertsigner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+func init() {
+	legacyregistry.RawMustRegister(certExpiry)
+}
+
+const certNotAfterMetricName = "etcd_operator_certificate_not_after_timestamp_seconds"
+
+var certExpiry = &certExpiryCollector{
+	desc: prometheus.NewDesc(
+		certNotAfterMetricName,
+		"Expiry of the etcd certificates and signer CAs maintained by the operator as a unix timestamp.",
+		[]string{"namespace", "secret", "cert"},
+		prometheus.Labels{},
+	),
+	certs: map[certKey]certValidity{},
+	lock:  sync.RWMutex{},
+}
+
+// certKey identifies a cert by the secret and the secret key it is stored in.
+type certKey struct {
+	namespace string
+	secret    string
+	cert      string
+}
+
+func (k certKey) String() string {
+	return k.namespace + "/" + k.secret + "/" + k.cert
+}
+
+// certValidity is the validity period of a cert tracked for expiry.
+type certValidity struct {
+	notBefore time.Time
+	notAfter  time.Time
+	// Percentage duration of the cert remaining at which it is regenerated
+	minDurationPercent float64
+}
+
+// certExpiryCollector is a Prometheus collector exposing the notAfter of the certs last observed by the controller.
+type certExpiryCollector struct {
+	desc  *prometheus.Desc
+	certs map[certKey]certValidity
+	lock  sync.RWMutex
+}
+
+func (c *certExpiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Set replaces all tracked certs so that certs of removed nodes are no longer exposed.
+func (c *certExpiryCollector) Set(certs map[certKey]certValidity) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.certs = certs
+}
+
+func (c *certExpiryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for key, validity := range c.certs {
+		ch <- prometheus.MustNewConstMetric(
+			c.desc,
+			prometheus.GaugeValue,
+			float64(validity.notAfter.Unix()),
+			key.namespace,
+			key.secret,
+			key.cert,
+		)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1informers "github.com/openshift/client-go/config/informers/externalversions/config/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
 	"github.com/openshift/library-go/pkg/controller/factory"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
@@ -21,6 +23,7 @@ import (
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 
+	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
@@ -32,6 +35,7 @@ type EtcdEndpointsController struct {
 	operatorClient  v1helpers.StaticPodOperatorClient
 	etcdClient      etcdcli.EtcdClient
 	nodeLister      corev1listers.NodeLister
+	networkLister   configv1listers.NetworkLister
 	configmapLister corev1listers.ConfigMapLister
 	configmapClient corev1client.ConfigMapsGetter
 }
@@ -42,6 +46,7 @@ func NewEtcdEndpointsController(
 	eventRecorder events.Recorder,
 	kubeClient kubernetes.Interface,
 	kubeInformers operatorv1helpers.KubeInformersForNamespaces,
+	networkInformer configv1informers.NetworkInformer,
 ) factory.Controller {
 	nodeInformer := kubeInformers.InformersFor("").Core().V1().Nodes()
 
@@ -49,6 +54,7 @@ func NewEtcdEndpointsController(
 		operatorClient:  operatorClient,
 		etcdClient:      etcdClient,
 		nodeLister:      nodeInformer.Lister(),
+		networkLister:   networkInformer.Lister(),
 		configmapLister: kubeInformers.ConfigMapLister(),
 		configmapClient: kubeClient.CoreV1(),
 	}
@@ -56,6 +62,7 @@ func NewEtcdEndpointsController(
 		operatorClient.Informer(),
 		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().ConfigMaps().Informer(),
 		nodeInformer.Informer(),
+		networkInformer.Informer(),
 	).WithSync(c.sync).ToController("EtcdEndpointsController", eventRecorder.WithComponentSuffix("etcd-endpoints-controller"))
 }
 
@@ -123,15 +130,9 @@ func (c *EtcdEndpointsController) syncConfigMap(ctx context.Context, recorder ev
 	}
 	endpointAddresses := map[string]string{}
 	for _, node := range nodes {
-		var nodeInternalIP string
-		for _, nodeAddress := range node.Status.Addresses {
-			if nodeAddress.Type == corev1.NodeInternalIP {
-				nodeInternalIP = nodeAddress.Address
-				break
-			}
-		}
-		if len(nodeInternalIP) == 0 {
-			return fmt.Errorf("unable to determine internal ip address for node %s", node.Name)
+		nodeInternalIP, err := c.nodeInternalIP(node)
+		if err != nil {
+			return fmt.Errorf("unable to determine internal ip address for node %s: %w", node.Name, err)
 		}
 		endpointAddresses[base64.StdEncoding.WithPadding(base64.NoPadding).EncodeToString([]byte(nodeInternalIP))] = nodeInternalIP
 	}
@@ -150,6 +151,25 @@ func (c *EtcdEndpointsController) syncConfigMap(ctx context.Context, recorder ev
 	return nil
 }
 
+// nodeInternalIP returns the first internal IP of the node. If the node is annotated with
+// dnshelpers.NodeIPSelectorAnnotation the selected IP is returned instead, the same IP the member is configured with.
+func (c *EtcdEndpointsController) nodeInternalIP(node *corev1.Node) (string, error) {
+	if _, hasSelector := node.Annotations[dnshelpers.NodeIPSelectorAnnotation]; hasSelector {
+		network, err := c.networkLister.Get("cluster")
+		if err != nil {
+			return "", err
+		}
+		nodeInternalIP, _, err := dnshelpers.GetPreferredInternalIPAddressForNodeName(network, node)
+		return nodeInternalIP, err
+	}
+	for _, nodeAddress := range node.Status.Addresses {
+		if nodeAddress.Type == corev1.NodeInternalIP {
+			return nodeAddress.Address, nil
+		}
+	}
+	return "", fmt.Errorf("node has no internal ip address")
+}
+
 func configMapAsset() *corev1.ConfigMap {
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
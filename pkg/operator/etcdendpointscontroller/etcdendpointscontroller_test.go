@@ -4,11 +4,14 @@ import (
 	"context"
 	"testing"
 
+	configv1 "github.com/openshift/api/config/v1"
 	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
 	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/pkg/mock/mockserver"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
 	corev1listers "k8s.io/client-go/listers/core/v1"
@@ -256,6 +259,78 @@ func TestBootstrapAnnotationRemoval(t *testing.T) {
 				}
 			},
 		},
+		{
+			// The IP selected by the node annotation is used instead of the first one.
+			name: "NodeIPSelector",
+			objects: []runtime.Object{
+				u.FakeNode("master-0", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.1")),
+				u.FakeNode("master-1", u.WithMasterLabel(), u.WithNodeInternalIP("192.168.0.2"), u.WithNodeInternalIP("10.0.0.2"), u.WithNodeIPSelector("10.0.0.0/24")),
+				u.FakeNode("master-2", u.WithMasterLabel(), u.WithNodeInternalIP("192.168.0.3"), u.WithNodeInternalIP("10.0.0.3"), u.WithNodeIPSelector("10.0.0.3")),
+				u.BootstrapConfigMap(u.WithBootstrapStatus("complete")),
+			},
+			staticPodStatus: u.StaticPodOperatorStatus(
+				u.WithLatestRevision(3),
+				u.WithNodeStatusAtCurrentRevision(3),
+				u.WithNodeStatusAtCurrentRevision(3),
+				u.WithNodeStatusAtCurrentRevision(3),
+			),
+			validateFunc: func(ts *testing.T, actions []clientgotesting.Action) {
+				wasValidated := false
+				for _, action := range actions {
+					if action.Matches("create", "configmaps") {
+						actual := action.(clientgotesting.CreateAction).GetObject().(*corev1.ConfigMap)
+						expected := u.EndpointsConfigMap(
+							u.WithAddress("10.0.0.1"),
+							u.WithAddress("10.0.0.2"),
+							u.WithAddress("10.0.0.3"),
+						)
+						if !equality.Semantic.DeepEqual(actual, expected) {
+							ts.Errorf(diff.ObjectDiff(expected, actual))
+						}
+						wasValidated = true
+					}
+				}
+				if !wasValidated {
+					ts.Errorf("the endpoints configmap wasn't validated")
+				}
+			},
+		},
+		{
+			// Without the node annotation the first internal IP is used regardless of the preferred IP family.
+			name: "FirstInternalIPWithoutSelector",
+			objects: []runtime.Object{
+				u.FakeNode("master-0", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.1")),
+				u.FakeNode("master-1", u.WithMasterLabel(), u.WithNodeInternalIP("fd00::2"), u.WithNodeInternalIP("10.0.0.2")),
+				u.FakeNode("master-2", u.WithMasterLabel(), u.WithNodeInternalIP("192.168.0.3"), u.WithNodeInternalIP("10.0.0.3")),
+				u.BootstrapConfigMap(u.WithBootstrapStatus("complete")),
+			},
+			staticPodStatus: u.StaticPodOperatorStatus(
+				u.WithLatestRevision(3),
+				u.WithNodeStatusAtCurrentRevision(3),
+				u.WithNodeStatusAtCurrentRevision(3),
+				u.WithNodeStatusAtCurrentRevision(3),
+			),
+			validateFunc: func(ts *testing.T, actions []clientgotesting.Action) {
+				wasValidated := false
+				for _, action := range actions {
+					if action.Matches("create", "configmaps") {
+						actual := action.(clientgotesting.CreateAction).GetObject().(*corev1.ConfigMap)
+						expected := u.EndpointsConfigMap(
+							u.WithAddress("10.0.0.1"),
+							u.WithAddress("fd00::2"),
+							u.WithAddress("192.168.0.3"),
+						)
+						if !equality.Semantic.DeepEqual(actual, expected) {
+							ts.Errorf(diff.ObjectDiff(expected, actual))
+						}
+						wasValidated = true
+					}
+				}
+				if !wasValidated {
+					ts.Errorf("the endpoints configmap wasn't validated")
+				}
+			},
+		},
 	}
 	for _, scenario := range scenarios {
 		t.Run(scenario.name, func(t *testing.T) {
@@ -274,7 +349,10 @@ func TestBootstrapAnnotationRemoval(t *testing.T) {
 			fakeEtcdClient := etcdcli.NewFakeEtcdClient(scenario.etcdMembers)
 			eventRecorder := events.NewRecorder(fakeKubeClient.CoreV1().Events(operatorclient.TargetNamespace), "test-etcdendpointscontroller", &corev1.ObjectReference{})
 			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-			for _, obj := range scenario.objects {
+			for _, obj := range append(scenario.objects, &configv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec:       configv1.NetworkSpec{ServiceNetwork: []string{"172.30.0.0/16"}},
+			}) {
 				if err := indexer.Add(obj); err != nil {
 					t.Fatal(err)
 				}
@@ -283,6 +361,7 @@ func TestBootstrapAnnotationRemoval(t *testing.T) {
 				operatorClient:  fakeOperatorClient,
 				etcdClient:      fakeEtcdClient,
 				nodeLister:      corev1listers.NewNodeLister(indexer),
+				networkLister:   configv1listers.NewNetworkLister(indexer),
 				configmapLister: corev1listers.NewConfigMapLister(indexer),
 				configmapClient: fakeKubeClient.CoreV1(),
 			}
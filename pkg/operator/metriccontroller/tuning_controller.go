@@ -0,0 +1,302 @@
+package metriccontroller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
+	operatorv1helpers "github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+)
+
+const (
+	// the highest p99 over a window, so that short quiet periods don't
+	// lower the recommendation
+	peerRoundTripTimeQueryFormat = "max(max_over_time(histogram_quantile(0.99, rate(etcd_network_peer_round_trip_time_seconds_bucket[5m]))[%s:1m]))"
+	walFsyncDurationQueryFormat  = "max(max_over_time(histogram_quantile(0.99, rate(etcd_disk_wal_fsync_duration_seconds_bucket[5m]))[%s:1m]))"
+	// latencies are sampled over the last hour, the recommendation is only
+	// lowered if the latencies were lower for the last 6 hours
+	sampleWindow    = "1h"
+	sustainedWindow = "6h"
+
+	maxRecommendedHeartbeatIntervalMillis = 5000
+	recommendedHeartbeatIntervalStep      = 100
+	recommendedElectionTimeoutRatio       = 10
+)
+
+// TuningController recommends the heartbeat interval and election timeout of
+// etcd from the p99 peer round trip time and WAL fsync duration observed by
+// Prometheus. The recommendation is published in the EtcdTuningRecommendation
+// condition and recorded in a configmap, which the env var controller applies
+// with the Auto tuning profile. Higher latencies raise the recommendation
+// right away, it is only lowered by more than one step once lower latencies
+// are sustained, so that applying the recommendation doesn't flap. The
+// recommendation is never below the defaults of the platform. Latencies are
+// not sampled while a revision rolls out. A recorded recommendation that fails
+// to decode degrades the controller instead of being replaced.
+type TuningController struct {
+	operatorClient       operatorv1helpers.StaticPodOperatorClient
+	infrastructureLister configv1listers.InfrastructureLister
+	configmapClient      corev1client.ConfigMapsGetter
+	// newPrometheusClient returns the client and a func releasing its
+	// connections.
+	newPrometheusClient func(ctx context.Context) (prometheusv1.API, func(), error)
+}
+
+func NewTuningController(operatorClient operatorv1helpers.StaticPodOperatorClient, infrastructureLister configv1listers.InfrastructureLister, coreClient corev1client.CoreV1Interface, recorder events.Recorder) factory.Controller {
+	c := &TuningController{
+		operatorClient:       operatorClient,
+		infrastructureLister: infrastructureLister,
+		configmapClient:      coreClient,
+		newPrometheusClient: func(ctx context.Context) (prometheusv1.API, func(), error) {
+			transport, err := getTransport()
+			if err != nil {
+				return nil, nil, err
+			}
+			client, err := getPrometheusClient(ctx, coreClient, transport)
+			if err != nil {
+				transport.CloseIdleConnections()
+				return nil, nil, err
+			}
+			return client, transport.CloseIdleConnections, nil
+		},
+	}
+	return factory.New().ResyncEvery(5*time.Minute).WithSync(c.sync).ToController("TuningController", recorder.WithComponentSuffix("tuning-controller"))
+}
+
+func (c *TuningController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	// a rollout restarts the members, latencies observed meanwhile are not
+	// representative
+	rollingOut, err := c.rolloutInProgress()
+	if err != nil || rollingOut {
+		return err
+	}
+
+	infra, err := c.infrastructureLister.Get("cluster")
+	if err != nil {
+		return err
+	}
+	defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis := ceohelpers.PlatformDefaultHeartbeatAndElectionTimeout(infra)
+
+	client, closeClient, err := c.newPrometheusClient(ctx)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	peerRoundTripTime, walFsyncDuration, ok, err := queryLatencies(ctx, client, sampleWindow)
+	if err != nil || !ok {
+		return err
+	}
+	sampledHeartbeatIntervalMillis, _ := recommendTuning(peerRoundTripTime, walFsyncDuration, defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis)
+
+	currentHeartbeatIntervalMillis, recorded, err := c.recordedHeartbeatInterval(ctx)
+	if recorded && err != nil {
+		// an invalid recommendation was not recorded by this controller and is
+		// not overwritten
+		return c.reportInvalidRecommendation(err)
+	}
+	if err != nil {
+		return err
+	}
+	sustainedHeartbeatIntervalMillis := sampledHeartbeatIntervalMillis
+	if recorded && sampledHeartbeatIntervalMillis < currentHeartbeatIntervalMillis {
+		sustainedPeerRoundTripTime, sustainedWalFsyncDuration, ok, err := queryLatencies(ctx, client, sustainedWindow)
+		if err != nil || !ok {
+			return err
+		}
+		sustainedHeartbeatIntervalMillis, _ = recommendTuning(sustainedPeerRoundTripTime, sustainedWalFsyncDuration, defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis)
+	}
+	heartbeatIntervalMillis := nextHeartbeatInterval(currentHeartbeatIntervalMillis, recorded, sampledHeartbeatIntervalMillis, sustainedHeartbeatIntervalMillis)
+	electionTimeoutMillis := recommendElectionTimeout(heartbeatIntervalMillis, defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis)
+	klog.V(4).Infof("recommending a heartbeat interval of %dms and an election timeout of %dms for a p99 peer round trip time of %s and a p99 WAL fsync duration of %s",
+		heartbeatIntervalMillis, electionTimeoutMillis, peerRoundTripTime, walFsyncDuration)
+
+	required := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ceohelpers.TuningRecommendationConfigMapName,
+			Namespace: operatorclient.TargetNamespace,
+		},
+		Data: map[string]string{
+			ceohelpers.HeartbeatIntervalMillisKey: strconv.Itoa(heartbeatIntervalMillis),
+			ceohelpers.ElectionTimeoutMillisKey:   strconv.Itoa(electionTimeoutMillis),
+		},
+	}
+	if _, _, err := resourceapply.ApplyConfigMap(ctx, c.configmapClient, syncCtx.Recorder(), required); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("recommended ETCD_HEARTBEAT_INTERVAL=%d ETCD_ELECTION_TIMEOUT=%d for a p99 peer round trip time of %s and a p99 WAL fsync duration of %s over the last hour",
+		heartbeatIntervalMillis, electionTimeoutMillis, peerRoundTripTime, walFsyncDuration)
+	if heartbeatIntervalMillis > sampledHeartbeatIntervalMillis {
+		message += fmt.Sprintf(", lower latencies must be sustained for %s to lower the recommendation", sustainedWindow)
+	}
+	_, _, err = operatorv1helpers.UpdateStatus(c.operatorClient,
+		operatorv1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+			Type:    "EtcdTuningRecommendation",
+			Status:  operatorv1.ConditionTrue,
+			Reason:  "LatencySampled",
+			Message: message,
+		}),
+		operatorv1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+			Type:   "TuningControllerDegraded",
+			Status: operatorv1.ConditionFalse,
+			Reason: "AsExpected",
+		}))
+	return err
+}
+
+// reportInvalidRecommendation degrades the controller until the invalid
+// recommendation configmap is fixed or deleted.
+func (c *TuningController) reportInvalidRecommendation(decodeErr error) error {
+	err := fmt.Errorf("%w, delete the configmap to record a new recommendation", decodeErr)
+	_, _, updateErr := operatorv1helpers.UpdateStatus(c.operatorClient, operatorv1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+		Type:    "TuningControllerDegraded",
+		Status:  operatorv1.ConditionTrue,
+		Reason:  "InvalidRecommendation",
+		Message: err.Error(),
+	}))
+	if updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// rolloutInProgress returns true while a member does not run the latest
+// revision.
+func (c *TuningController) rolloutInProgress() (bool, error) {
+	_, status, _, err := c.operatorClient.GetStaticPodOperatorState()
+	if err != nil {
+		return false, err
+	}
+	for _, nodeStatus := range status.NodeStatuses {
+		if nodeStatus.CurrentRevision != status.LatestAvailableRevision || nodeStatus.TargetRevision != 0 {
+			klog.V(4).Infof("not sampling latencies while revision %d rolls out", status.LatestAvailableRevision)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordedHeartbeatInterval returns the heartbeat interval in milliseconds
+// recorded in the recommendation configmap, or false if none is recorded. A
+// recorded interval that fails to decode is returned as an error together with
+// true.
+func (c *TuningController) recordedHeartbeatInterval(ctx context.Context) (int, bool, error) {
+	configMap, err := c.configmapClient.ConfigMaps(operatorclient.TargetNamespace).Get(ctx, ceohelpers.TuningRecommendationConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	heartbeatIntervalMillis, err := strconv.Atoi(configMap.Data[ceohelpers.HeartbeatIntervalMillisKey])
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid %s in configmap %s/%s: %w", ceohelpers.HeartbeatIntervalMillisKey, operatorclient.TargetNamespace, ceohelpers.TuningRecommendationConfigMapName, err)
+	}
+	return heartbeatIntervalMillis, true, nil
+}
+
+// queryLatencies returns the p99 peer round trip time and WAL fsync duration
+// over the window, or false if there are no samples yet.
+func queryLatencies(ctx context.Context, client prometheusv1.API, window string) (time.Duration, time.Duration, bool, error) {
+	peerRoundTripTime, ok, err := queryDuration(ctx, client, fmt.Sprintf(peerRoundTripTimeQueryFormat, window))
+	if err != nil || !ok {
+		return 0, 0, false, err
+	}
+	walFsyncDuration, ok, err := queryDuration(ctx, client, fmt.Sprintf(walFsyncDurationQueryFormat, window))
+	if err != nil || !ok {
+		return 0, 0, false, err
+	}
+	return peerRoundTripTime, walFsyncDuration, true, nil
+}
+
+// queryDuration returns the single sample in seconds returned by the query as
+// a duration, or false if there is no sample yet, e.g. without peer traffic.
+func queryDuration(ctx context.Context, client prometheusv1.API, query string) (time.Duration, bool, error) {
+	result, _, err := client.Query(ctx, query, time.Now())
+	if err != nil {
+		return 0, false, err
+	}
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected type, expected Vector, got %T", result)
+	}
+	if len(vector) == 0 || math.IsNaN(float64(vector[0].Value)) {
+		klog.V(4).Infof("no samples for query %q", query)
+		return 0, false, nil
+	}
+	return time.Duration(float64(vector[0].Value) * float64(time.Second)), true, nil
+}
+
+// recommendTuning returns the recommended heartbeat interval and election
+// timeout in milliseconds. The heartbeat interval covers 1.5 times the slower
+// of the peer round trip time and the WAL fsync duration, since a slow disk
+// delays the heartbeats of the leader as well. It is rounded up to 100ms so
+// that small latency changes don't roll out new revisions, and is at least the
+// default heartbeat interval of the platform.
+func recommendTuning(peerRoundTripTime, walFsyncDuration time.Duration, defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis int) (int, int) {
+	latency := peerRoundTripTime
+	if walFsyncDuration > latency {
+		latency = walFsyncDuration
+	}
+	heartbeatIntervalMillis := int(math.Ceil(1.5*float64(latency/time.Millisecond)/recommendedHeartbeatIntervalStep)) * recommendedHeartbeatIntervalStep
+	if heartbeatIntervalMillis < defaultHeartbeatIntervalMillis {
+		heartbeatIntervalMillis = defaultHeartbeatIntervalMillis
+	}
+	if heartbeatIntervalMillis > maxRecommendedHeartbeatIntervalMillis {
+		heartbeatIntervalMillis = maxRecommendedHeartbeatIntervalMillis
+	}
+	return heartbeatIntervalMillis, recommendElectionTimeout(heartbeatIntervalMillis, defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis)
+}
+
+// recommendElectionTimeout returns the election timeout in milliseconds for the
+// heartbeat interval. It is 10 times the heartbeat interval, as recommended by
+// etcd, and at least the default election timeout of the platform.
+func recommendElectionTimeout(heartbeatIntervalMillis, defaultHeartbeatIntervalMillis, defaultElectionTimeoutMillis int) int {
+	if heartbeatIntervalMillis <= defaultHeartbeatIntervalMillis {
+		return defaultElectionTimeoutMillis
+	}
+	electionTimeoutMillis := recommendedElectionTimeoutRatio * heartbeatIntervalMillis
+	if electionTimeoutMillis < defaultElectionTimeoutMillis {
+		return defaultElectionTimeoutMillis
+	}
+	return electionTimeoutMillis
+}
+
+// nextHeartbeatInterval returns the heartbeat interval in milliseconds to
+// recommend given the current recommendation, the interval recommended for the
+// latencies sampled over the last hour and the interval recommended for the
+// latencies sustained over the longer window. A higher interval is
+// recommended right away, a lower one only if the sustained interval is more
+// than one step below the current recommendation.
+func nextHeartbeatInterval(currentMillis int, recorded bool, sampledMillis, sustainedMillis int) int {
+	if !recorded || sampledMillis >= currentMillis {
+		return sampledMillis
+	}
+	if currentMillis-sustainedMillis > recommendedHeartbeatIntervalStep {
+		return sustainedMillis
+	}
+	return currentMillis
+}
@@ -0,0 +1,385 @@
+package metriccontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+	prometheusv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/ceohelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	u "github.com/openshift/cluster-etcd-operator/pkg/testutils"
+)
+
+func TestRecommendTuning(t *testing.T) {
+	tests := []struct {
+		name              string
+		peerRoundTripTime time.Duration
+		walFsyncDuration  time.Duration
+		azure             bool
+		wantHeartbeat     int
+		wantTimeout       int
+	}{
+		{
+			name:              "low latencies keep the etcd defaults",
+			peerRoundTripTime: 2 * time.Millisecond,
+			walFsyncDuration:  10 * time.Millisecond,
+			wantHeartbeat:     100,
+			wantTimeout:       1000,
+		},
+		{
+			name:              "low latencies keep the azure defaults",
+			peerRoundTripTime: 2 * time.Millisecond,
+			walFsyncDuration:  10 * time.Millisecond,
+			azure:             true,
+			wantHeartbeat:     500,
+			wantTimeout:       2500,
+		},
+		{
+			name:              "slow peers on azure",
+			peerRoundTripTime: 350 * time.Millisecond,
+			walFsyncDuration:  10 * time.Millisecond,
+			azure:             true,
+			wantHeartbeat:     600,
+			wantTimeout:       6000,
+		},
+		{
+			name:              "slow peers",
+			peerRoundTripTime: 150 * time.Millisecond,
+			walFsyncDuration:  10 * time.Millisecond,
+			wantHeartbeat:     300,
+			wantTimeout:       3000,
+		},
+		{
+			name:              "slow disk",
+			peerRoundTripTime: 5 * time.Millisecond,
+			walFsyncDuration:  210 * time.Millisecond,
+			wantHeartbeat:     400,
+			wantTimeout:       4000,
+		},
+		{
+			name:              "capped to the etcd maximum election timeout",
+			peerRoundTripTime: 10 * time.Second,
+			walFsyncDuration:  10 * time.Millisecond,
+			wantHeartbeat:     5000,
+			wantTimeout:       50000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaultHeartbeat, defaultTimeout := ceohelpers.PlatformDefaultHeartbeatAndElectionTimeout(infrastructure(tt.azure))
+			heartbeat, timeout := recommendTuning(tt.peerRoundTripTime, tt.walFsyncDuration, defaultHeartbeat, defaultTimeout)
+			if heartbeat != tt.wantHeartbeat || timeout != tt.wantTimeout {
+				t.Fatalf("recommendTuning() got = %d, %d, want %d, %d", heartbeat, timeout, tt.wantHeartbeat, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestNextHeartbeatInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   int
+		recorded  bool
+		sampled   int
+		sustained int
+		want      int
+	}{
+		{
+			name:    "first recommendation",
+			sampled: 300,
+			want:    300,
+		},
+		{
+			name:     "increase right away",
+			current:  300,
+			recorded: true,
+			sampled:  400,
+			want:     400,
+		},
+		{
+			name:      "lower sample is not sustained",
+			current:   500,
+			recorded:  true,
+			sampled:   200,
+			sustained: 500,
+			want:      500,
+		},
+		{
+			name:      "sustained decrease of a single step",
+			current:   500,
+			recorded:  true,
+			sampled:   400,
+			sustained: 400,
+			want:      500,
+		},
+		{
+			name:      "sustained decrease of more than one step",
+			current:   500,
+			recorded:  true,
+			sampled:   200,
+			sustained: 300,
+			want:      300,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextHeartbeatInterval(tt.current, tt.recorded, tt.sampled, tt.sustained); got != tt.want {
+				t.Fatalf("nextHeartbeatInterval() got = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTuningDoesNotFlap samples latencies alternating around a step boundary
+// every hour for two days and expects the recommendation to change once.
+func TestTuningDoesNotFlap(t *testing.T) {
+	var samples []int
+	current, recorded, changes := 0, false, 0
+	for hour := 0; hour < 48; hour++ {
+		peerRoundTripTime := 190 * time.Millisecond
+		if hour%2 == 1 {
+			peerRoundTripTime = 210 * time.Millisecond
+		}
+		sampled, _ := recommendTuning(peerRoundTripTime, 10*time.Millisecond, 100, 1000)
+		samples = append(samples, sampled)
+		// the sustained window covers the last 6 hourly samples
+		sustained := sampled
+		for i := len(samples) - 1; i >= 0 && i >= len(samples)-6; i-- {
+			if samples[i] > sustained {
+				sustained = samples[i]
+			}
+		}
+		next := nextHeartbeatInterval(current, recorded, sampled, sustained)
+		if recorded && next != current {
+			changes++
+		}
+		current, recorded = next, true
+	}
+	if changes > 1 {
+		t.Fatalf("expected the recommendation to change at most once, changed %d times", changes)
+	}
+	if current != 400 {
+		t.Fatalf("expected the recommendation to cover the highest latency, got %d", current)
+	}
+}
+
+func TestRolloutInProgress(t *testing.T) {
+	tests := []struct {
+		name   string
+		status *operatorv1.StaticPodOperatorStatus
+		want   bool
+	}{
+		{
+			name: "all members at the latest revision",
+			status: u.StaticPodOperatorStatus(
+				u.WithLatestRevision(2),
+				u.WithNodeStatusAtCurrentRevision(2),
+				u.WithNodeStatusAtCurrentRevision(2),
+			),
+		},
+		{
+			name: "member at a previous revision",
+			status: u.StaticPodOperatorStatus(
+				u.WithLatestRevision(2),
+				u.WithNodeStatusAtCurrentRevision(2),
+				u.WithNodeStatusAtCurrentRevision(1),
+			),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &TuningController{
+				operatorClient: v1helpers.NewFakeStaticPodOperatorClient(&operatorv1.StaticPodOperatorSpec{}, tt.status, nil, nil),
+			}
+			got, err := c.rolloutInProgress()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("rolloutInProgress() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name              string
+		azure             bool
+		peerRoundTripTime time.Duration
+		walFsyncDuration  time.Duration
+		wantHeartbeat     string
+		wantTimeout       string
+		wantMessage       string
+	}{
+		{
+			name:              "low latencies",
+			peerRoundTripTime: 2 * time.Millisecond,
+			walFsyncDuration:  10 * time.Millisecond,
+			wantHeartbeat:     "100",
+			wantTimeout:       "1000",
+			wantMessage:       "recommended ETCD_HEARTBEAT_INTERVAL=100 ETCD_ELECTION_TIMEOUT=1000 for a p99 peer round trip time of 2ms and a p99 WAL fsync duration of 10ms over the last hour",
+		},
+		{
+			name:              "low latencies on azure keep the platform defaults",
+			azure:             true,
+			peerRoundTripTime: 2 * time.Millisecond,
+			walFsyncDuration:  10 * time.Millisecond,
+			wantHeartbeat:     "500",
+			wantTimeout:       "2500",
+			wantMessage:       "recommended ETCD_HEARTBEAT_INTERVAL=500 ETCD_ELECTION_TIMEOUT=2500 for a p99 peer round trip time of 2ms and a p99 WAL fsync duration of 10ms over the last hour",
+		},
+		{
+			name:              "slow disk",
+			peerRoundTripTime: 5 * time.Millisecond,
+			walFsyncDuration:  210 * time.Millisecond,
+			wantHeartbeat:     "400",
+			wantTimeout:       "4000",
+			wantMessage:       "recommended ETCD_HEARTBEAT_INTERVAL=400 ETCD_ELECTION_TIMEOUT=4000 for a p99 peer round trip time of 5ms and a p99 WAL fsync duration of 210ms over the last hour",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operatorClient := v1helpers.NewFakeStaticPodOperatorClient(
+				&operatorv1.StaticPodOperatorSpec{},
+				u.StaticPodOperatorStatus(u.WithLatestRevision(1), u.WithNodeStatusAtCurrentRevision(1)),
+				nil,
+				nil,
+			)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := indexer.Add(infrastructure(tt.azure)); err != nil {
+				t.Fatal(err)
+			}
+			kubeClient := fake.NewSimpleClientset()
+			c := &TuningController{
+				operatorClient:       operatorClient,
+				infrastructureLister: configv1listers.NewInfrastructureLister(indexer),
+				configmapClient:      kubeClient.CoreV1(),
+				newPrometheusClient: func(ctx context.Context) (prometheusv1.API, func(), error) {
+					return &fakePrometheusAPI{peerRoundTripTime: tt.peerRoundTripTime, walFsyncDuration: tt.walFsyncDuration}, func() {}, nil
+				},
+			}
+
+			if err := c.sync(context.TODO(), factory.NewSyncContext("test", events.NewInMemoryRecorder("test"))); err != nil {
+				t.Fatal(err)
+			}
+
+			configMap, err := kubeClient.CoreV1().ConfigMaps(operatorclient.TargetNamespace).Get(context.TODO(), ceohelpers.TuningRecommendationConfigMapName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := configMap.Data[ceohelpers.HeartbeatIntervalMillisKey]; got != tt.wantHeartbeat {
+				t.Errorf("recorded heartbeat interval got = %s, want %s", got, tt.wantHeartbeat)
+			}
+			if got := configMap.Data[ceohelpers.ElectionTimeoutMillisKey]; got != tt.wantTimeout {
+				t.Errorf("recorded election timeout got = %s, want %s", got, tt.wantTimeout)
+			}
+			_, status, _, err := operatorClient.GetOperatorState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			condition := v1helpers.FindOperatorCondition(status.Conditions, "EtcdTuningRecommendation")
+			if condition == nil {
+				t.Fatal("missing EtcdTuningRecommendation condition")
+			}
+			if condition.Status != operatorv1.ConditionTrue || condition.Message != tt.wantMessage {
+				t.Errorf("EtcdTuningRecommendation got = %s %q, want %s %q", condition.Status, condition.Message, operatorv1.ConditionTrue, tt.wantMessage)
+			}
+			if degraded := v1helpers.FindOperatorCondition(status.Conditions, "TuningControllerDegraded"); degraded == nil || degraded.Status != operatorv1.ConditionFalse {
+				t.Errorf("TuningControllerDegraded got = %v, want %s", degraded, operatorv1.ConditionFalse)
+			}
+		})
+	}
+}
+
+func TestSyncInvalidRecommendation(t *testing.T) {
+	operatorClient := v1helpers.NewFakeStaticPodOperatorClient(
+		&operatorv1.StaticPodOperatorSpec{},
+		u.StaticPodOperatorStatus(u.WithLatestRevision(1), u.WithNodeStatusAtCurrentRevision(1)),
+		nil,
+		nil,
+	)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := indexer.Add(infrastructure(false)); err != nil {
+		t.Fatal(err)
+	}
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ceohelpers.TuningRecommendationConfigMapName, Namespace: operatorclient.TargetNamespace},
+		Data:       map[string]string{ceohelpers.HeartbeatIntervalMillisKey: "100ms", ceohelpers.ElectionTimeoutMillisKey: "1000"},
+	})
+	c := &TuningController{
+		operatorClient:       operatorClient,
+		infrastructureLister: configv1listers.NewInfrastructureLister(indexer),
+		configmapClient:      kubeClient.CoreV1(),
+		newPrometheusClient: func(ctx context.Context) (prometheusv1.API, func(), error) {
+			return &fakePrometheusAPI{peerRoundTripTime: 2 * time.Millisecond, walFsyncDuration: 10 * time.Millisecond}, func() {}, nil
+		},
+	}
+
+	if err := c.sync(context.TODO(), factory.NewSyncContext("test", events.NewInMemoryRecorder("test"))); err == nil {
+		t.Fatal("expected an error for the invalid recommendation")
+	}
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps(operatorclient.TargetNamespace).Get(context.TODO(), ceohelpers.TuningRecommendationConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := configMap.Data[ceohelpers.HeartbeatIntervalMillisKey]; got != "100ms" {
+		t.Errorf("invalid recommendation was replaced with %s", got)
+	}
+	_, status, _, err := operatorClient.GetOperatorState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	degraded := v1helpers.FindOperatorCondition(status.Conditions, "TuningControllerDegraded")
+	if degraded == nil || degraded.Status != operatorv1.ConditionTrue || degraded.Reason != "InvalidRecommendation" {
+		t.Fatalf("TuningControllerDegraded got = %v, want %s InvalidRecommendation", degraded, operatorv1.ConditionTrue)
+	}
+}
+
+func infrastructure(azure bool) *configv1.Infrastructure {
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status:     configv1.InfrastructureStatus{PlatformStatus: &configv1.PlatformStatus{}},
+	}
+	if azure {
+		infra.Status.PlatformStatus.Azure = &configv1.AzurePlatformStatus{}
+	}
+	return infra
+}
+
+// fakePrometheusAPI returns the latencies for every window.
+type fakePrometheusAPI struct {
+	prometheusv1.API
+	peerRoundTripTime time.Duration
+	walFsyncDuration  time.Duration
+}
+
+func (f *fakePrometheusAPI) Query(ctx context.Context, query string, ts time.Time) (model.Value, prometheusv1.Warnings, error) {
+	var latency time.Duration
+	switch {
+	case strings.Contains(query, "etcd_network_peer_round_trip_time_seconds_bucket"):
+		latency = f.peerRoundTripTime
+	case strings.Contains(query, "etcd_disk_wal_fsync_duration_seconds_bucket"):
+		latency = f.walFsyncDuration
+	default:
+		return nil, nil, fmt.Errorf("unexpected query %q", query)
+	}
+	return model.Vector{&model.Sample{Value: model.SampleValue(latency.Seconds())}}, nil, nil
+}
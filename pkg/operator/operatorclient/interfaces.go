@@ -5,4 +5,5 @@ const (
 	GlobalMachineSpecifiedConfigNamespace = "openshift-config-managed"
 	OperatorNamespace                     = "openshift-etcd-operator"
 	TargetNamespace                       = "openshift-etcd"
+	KubeAPIServerNamespace                = "openshift-kube-apiserver"
 )
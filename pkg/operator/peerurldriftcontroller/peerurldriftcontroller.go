@@ -0,0 +1,219 @@
+package peerurldriftcontroller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	configv1informers "github.com/openshift/client-go/config/informers/externalversions/config/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/controller/factory"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/v1helpers"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
+	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/etcdcertsigner"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+)
+
+// PeerURLDriftController repairs the peer URL of members whose node changed
+// its preferred internal IP, e.g. after a DHCP lease change. Such a member
+// keeps advertising the old IP and can't rejoin the cluster.
+//
+// The controller requests the regeneration of the certs of the node, which
+// are otherwise not valid for the new IP, and updates the peer URL of the
+// member. The env var controller already follows the node IP, so the
+// repaired member is rolled out in a new revision. Members using a DNS name
+// are not affected by IP changes and are left alone.
+type PeerURLDriftController struct {
+	operatorClient v1helpers.OperatorClient
+	etcdClient     etcdcli.EtcdClient
+	nodeLister     corev1listers.NodeLister
+	networkLister  configv1listers.NetworkLister
+	secretLister   corev1listers.SecretLister
+	secretClient   corev1client.SecretsGetter
+}
+
+func NewPeerURLDriftController(
+	operatorClient v1helpers.OperatorClient,
+	kubeClient kubernetes.Interface,
+	kubeInformers v1helpers.KubeInformersForNamespaces,
+	networkInformer configv1informers.NetworkInformer,
+	etcdClient etcdcli.EtcdClient,
+	eventRecorder events.Recorder,
+) factory.Controller {
+	c := &PeerURLDriftController{
+		operatorClient: operatorClient,
+		etcdClient:     etcdClient,
+		nodeLister:     kubeInformers.InformersFor("").Core().V1().Nodes().Lister(),
+		networkLister:  networkInformer.Lister(),
+		secretLister:   kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Secrets().Lister(),
+		secretClient:   kubeClient.CoreV1(),
+	}
+	return factory.New().ResyncEvery(time.Minute).WithInformers(
+		kubeInformers.InformersFor("").Core().V1().Nodes().Informer(),
+		kubeInformers.InformersFor(operatorclient.TargetNamespace).Core().V1().Secrets().Informer(),
+		networkInformer.Informer(),
+		operatorClient.Informer(),
+	).WithSync(c.sync).ToController("PeerURLDriftController", eventRecorder.WithComponentSuffix("peer-url-drift-controller"))
+}
+
+func (c *PeerURLDriftController) sync(ctx context.Context, syncCtx factory.SyncContext) error {
+	err := c.repairPeerURLs(ctx, syncCtx.Recorder())
+	if err != nil {
+		_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient, v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+			Type:    "PeerURLDriftControllerDegraded",
+			Status:  operatorv1.ConditionTrue,
+			Reason:  "Error",
+			Message: err.Error(),
+		}))
+		if updateErr != nil {
+			syncCtx.Recorder().Warning("PeerURLDriftControllerUpdatingStatus", updateErr.Error())
+		}
+		return err
+	}
+
+	_, _, updateErr := v1helpers.UpdateStatus(c.operatorClient,
+		v1helpers.UpdateConditionFn(operatorv1.OperatorCondition{
+			Type:   "PeerURLDriftControllerDegraded",
+			Status: operatorv1.ConditionFalse,
+			Reason: "AsExpected",
+		}))
+	return updateErr
+}
+
+// repairPeerURLs repairs the peer URL of at most one drifted member per sync,
+// and only if the other members form a quorum.
+func (c *PeerURLDriftController) repairPeerURLs(ctx context.Context, recorder events.Recorder) error {
+	members, err := c.etcdClient.MemberList()
+	if err != nil {
+		return err
+	}
+	network, err := c.networkLister.Get("cluster")
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		// unstarted members are not named yet
+		if len(member.Name) == 0 || len(member.PeerURLs) == 0 {
+			continue
+		}
+		node, err := c.nodeLister.Get(member.Name)
+		if apierrors.IsNotFound(err) {
+			// e.g. the bootstrap member
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		peerURL, err := url.Parse(member.PeerURLs[0])
+		if err != nil {
+			return fmt.Errorf("member %q has an invalid peer URL: %w", member.Name, err)
+		}
+		memberIP := net.ParseIP(peerURL.Hostname())
+		if memberIP == nil {
+			// the member uses a DNS name
+			continue
+		}
+		nodeIP, _, err := dnshelpers.GetPreferredInternalIPAddressForNodeName(network, node)
+		if err != nil {
+			return err
+		}
+		if memberIP.Equal(net.ParseIP(nodeIP)) {
+			continue
+		}
+
+		unhealthyMembers, err := c.etcdClient.UnhealthyMembers()
+		if err != nil {
+			return err
+		}
+		if !isQuorumSafeToRepair(members, unhealthyMembers, member) {
+			return fmt.Errorf("peer URL of member %q drifted from %s to node IP %s, not repairing it since the other members don't form a quorum", member.Name, peerURL.Hostname(), nodeIP)
+		}
+
+		escapedIP, err := dnshelpers.GetURLHostForIP(nodeIP)
+		if err != nil {
+			return err
+		}
+		if err := c.requestCertRegeneration(ctx, recorder, node.Name, nodeIP); err != nil {
+			return err
+		}
+		newPeerURL := fmt.Sprintf("https://%s:2380", escapedIP)
+		if err := c.etcdClient.MemberUpdatePeerURL(member.ID, []string{newPeerURL}); err != nil {
+			return fmt.Errorf("failed to repair peer URL of member %q: %w", member.Name, err)
+		}
+		recorder.Eventf("PeerURLDriftRepaired", "updated peer URL of member %q from %s to %s", member.Name, member.PeerURLs[0], newPeerURL)
+		return nil
+	}
+	return nil
+}
+
+// isQuorumSafeToRepair returns whether the healthy members other than the
+// drifted member form a quorum, so that the member update can't be blocked
+// by the drifted member and doesn't depend on it.
+func isQuorumSafeToRepair(members, unhealthyMembers []*etcdserverpb.Member, drifted *etcdserverpb.Member) bool {
+	unhealthy := map[uint64]bool{}
+	for _, member := range unhealthyMembers {
+		unhealthy[member.ID] = true
+	}
+	quorum := len(members)/2 + 1
+	healthyOthers := 0
+	for _, member := range members {
+		if member.ID != drifted.ID && !unhealthy[member.ID] {
+			healthyOthers++
+		}
+	}
+	return healthyOthers >= quorum
+}
+
+// requestCertRegeneration requests the regeneration of the member certs of
+// the node that are not valid for its IP.
+func (c *PeerURLDriftController) requestCertRegeneration(ctx context.Context, recorder events.Recorder, nodeName, nodeIP string) error {
+	for _, secretName := range []string{
+		tlshelpers.GetPeerClientSecretNameForNode(nodeName),
+		tlshelpers.GetServingSecretNameForNode(nodeName),
+		tlshelpers.GetServingMetricsSecretNameForNode(nodeName),
+	} {
+		secret, err := c.secretLister.Secrets(operatorclient.TargetNamespace).Get(secretName)
+		if apierrors.IsNotFound(err) {
+			// will be created for the current IP
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := secret.Annotations[etcdcertsigner.CertRegenerationRequestedAnnotation]; ok {
+			continue
+		}
+		if certs, err := crypto.CertsFromPEM(secret.Data["tls.crt"]); err == nil && certs[0].VerifyHostname(nodeIP) == nil {
+			continue
+		}
+
+		secret = secret.DeepCopy()
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[etcdcertsigner.CertRegenerationRequestedAnnotation] = ""
+		if _, err := c.secretClient.Secrets(operatorclient.TargetNamespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		klog.V(2).Infof("requested regeneration of cert %s for node IP %s", secretName, nodeIP)
+		recorder.Eventf("CertRegenerationRequested", "requested regeneration of %s for the new IP %s of node %s", secretName, nodeIP, nodeName)
+	}
+	return nil
+}
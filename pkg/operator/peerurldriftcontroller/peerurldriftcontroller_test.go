@@ -0,0 +1,161 @@
+package peerurldriftcontroller
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	configv1listers "github.com/openshift/client-go/config/listers/config/v1"
+	"github.com/openshift/library-go/pkg/crypto"
+	"github.com/openshift/library-go/pkg/operator/events"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/etcdcertsigner"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	u "github.com/openshift/cluster-etcd-operator/pkg/testutils"
+	"github.com/openshift/cluster-etcd-operator/pkg/tlshelpers"
+)
+
+func TestRepairPeerURLs(t *testing.T) {
+	caConfig, err := crypto.MakeSelfSignedCAConfig("etcd-signer", 365)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, caKey, err := caConfig.GetPEMBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certSecret := func(secretName string, ips ...string) runtime.Object {
+		cert, key, err := tlshelpers.CreatePeerCertKey(caCert, caKey, ips, tlshelpers.DefaultCertOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.FakeSecret(operatorclient.TargetNamespace, secretName, map[string][]byte{"tls.crt": cert.Bytes(), "tls.key": key.Bytes()})
+	}
+
+	tests := []struct {
+		name                 string
+		peerURL              string
+		wantPeerURL          string
+		wantRegenerationReqs []string
+	}{
+		{
+			name:        "drifted member is repaired",
+			peerURL:     "https://10.0.0.1:2380",
+			wantPeerURL: "https://10.0.0.11:2380",
+			// the metrics cert was already issued for the new IP
+			wantRegenerationReqs: []string{"etcd-peer-master-0", "etcd-serving-master-0"},
+		},
+		{
+			name:        "member matching the node ip",
+			peerURL:     "https://10.0.0.11:2380",
+			wantPeerURL: "https://10.0.0.11:2380",
+		},
+		{
+			name:        "member using a dns name",
+			peerURL:     "https://master-0.example.com:2380",
+			wantPeerURL: "https://master-0.example.com:2380",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{
+				u.FakeNode("master-0", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.11")),
+				u.FakeNode("master-1", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.2")),
+				u.FakeNode("master-2", u.WithMasterLabel(), u.WithNodeInternalIP("10.0.0.3")),
+				certSecret(tlshelpers.GetPeerClientSecretNameForNode("master-0"), "10.0.0.1"),
+				certSecret(tlshelpers.GetServingSecretNameForNode("master-0"), "10.0.0.1"),
+				certSecret(tlshelpers.GetServingMetricsSecretNameForNode("master-0"), "10.0.0.11"),
+			}
+			fakeKubeClient := fake.NewSimpleClientset(objects...)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, obj := range append(objects, &configv1.Network{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+				Spec:       configv1.NetworkSpec{ServiceNetwork: []string{"172.30.0.0/16"}},
+			}) {
+				if err := indexer.Add(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+			drifted := &etcdserverpb.Member{Name: "master-0", ID: 1, PeerURLs: []string{tt.peerURL}}
+			c := &PeerURLDriftController{
+				etcdClient: etcdcli.NewFakeEtcdClient([]*etcdserverpb.Member{
+					drifted,
+					{Name: "master-1", ID: 2, PeerURLs: []string{"https://10.0.0.2:2380"}},
+					{Name: "master-2", ID: 3, PeerURLs: []string{"https://10.0.0.3:2380"}},
+				}),
+				nodeLister:    corev1listers.NewNodeLister(indexer),
+				networkLister: configv1listers.NewNetworkLister(indexer),
+				secretLister:  corev1listers.NewSecretLister(indexer),
+				secretClient:  fakeKubeClient.CoreV1(),
+			}
+			if err := c.repairPeerURLs(context.TODO(), events.NewInMemoryRecorder("test")); err != nil {
+				t.Fatal(err)
+			}
+			if drifted.PeerURLs[0] != tt.wantPeerURL {
+				t.Fatalf("expected peer URL %s, got %s", tt.wantPeerURL, drifted.PeerURLs[0])
+			}
+
+			requested := map[string]bool{}
+			for _, secretName := range tt.wantRegenerationReqs {
+				requested[secretName] = true
+			}
+			for _, secretName := range []string{"etcd-peer-master-0", "etcd-serving-master-0", "etcd-serving-metrics-master-0"} {
+				secret, err := fakeKubeClient.CoreV1().Secrets(operatorclient.TargetNamespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := secret.Annotations[etcdcertsigner.CertRegenerationRequestedAnnotation]; ok != requested[secretName] {
+					t.Fatalf("expected regeneration request of %s to be %v", secretName, requested[secretName])
+				}
+			}
+		})
+	}
+}
+
+func TestIsQuorumSafeToRepair(t *testing.T) {
+	member := func(id uint64) *etcdserverpb.Member {
+		return &etcdserverpb.Member{ID: id}
+	}
+	members := []*etcdserverpb.Member{member(1), member(2), member(3)}
+	tests := []struct {
+		name      string
+		members   []*etcdserverpb.Member
+		unhealthy []*etcdserverpb.Member
+		want      bool
+	}{
+		{
+			name:      "drifted member unhealthy",
+			members:   members,
+			unhealthy: []*etcdserverpb.Member{member(1)},
+			want:      true,
+		},
+		{
+			name:    "all members healthy",
+			members: members,
+			want:    true,
+		},
+		{
+			name:      "another member unhealthy",
+			members:   members,
+			unhealthy: []*etcdserverpb.Member{member(1), member(2)},
+		},
+		{
+			name:    "single member",
+			members: []*etcdserverpb.Member{member(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isQuorumSafeToRepair(tt.members, tt.unhealthy, member(1)); got != tt.want {
+				t.Fatalf("isQuorumSafeToRepair() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
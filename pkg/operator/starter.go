@@ -33,6 +33,7 @@ import (
 	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
 	"github.com/openshift/cluster-etcd-operator/pkg/etcdenvvar"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/bootstrapteardown"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/carotationcontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/clustermembercontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/configobservation/configobservercontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/etcd_assets"
@@ -41,6 +42,7 @@ import (
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/etcdmemberscontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/metriccontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/peerurldriftcontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/quorumguardcontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/resourcesynccontroller"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/scriptcontroller"
@@ -75,6 +77,7 @@ func RunOperator(ctx context.Context, controllerContext *controllercmd.Controlle
 		operatorclient.GlobalMachineSpecifiedConfigNamespace,
 		operatorclient.TargetNamespace,
 		operatorclient.OperatorNamespace,
+		operatorclient.KubeAPIServerNamespace,
 		"kube-system",
 	)
 	configInformers := configv1informers.NewSharedInformerFactory(configClient, 10*time.Minute)
@@ -164,6 +167,7 @@ func RunOperator(ctx context.Context, controllerContext *controllercmd.Controlle
 	}
 
 	fsyncMetricController := metriccontroller.NewFSyncController(operatorClient, configClient.ConfigV1(), kubeClient.CoreV1(), controllerContext.EventRecorder)
+	tuningMetricController := metriccontroller.NewTuningController(operatorClient, configInformers.Config().V1().Infrastructures().Lister(), kubeClient.CoreV1(), controllerContext.EventRecorder)
 
 	statusController := status.NewClusterOperatorStatusController(
 		"etcd",
@@ -195,16 +199,33 @@ func RunOperator(ctx context.Context, controllerContext *controllercmd.Controlle
 		kubeInformersForNamespaces,
 		controllerContext.EventRecorder,
 	)
+	caRotationController := carotationcontroller.NewCARotationController(
+		operatorClient,
+		coreClient.CoreV1(),
+		kubeInformersForNamespaces,
+		operatorInformers.Operator().V1().KubeAPIServers(),
+		controllerContext.EventRecorder,
+	)
 	etcdEndpointsController := etcdendpointscontroller.NewEtcdEndpointsController(
 		operatorClient,
 		etcdClient,
 		controllerContext.EventRecorder,
 		coreClient,
 		kubeInformersForNamespaces,
+		configInformers.Config().V1().Networks(),
 	)
 
 	clusterMemberController := clustermembercontroller.NewClusterMemberController(
 		operatorClient,
+		coreClient,
+		kubeInformersForNamespaces,
+		configInformers.Config().V1().Networks(),
+		etcdClient,
+		controllerContext.EventRecorder,
+	)
+	peerURLDriftController := peerurldriftcontroller.NewPeerURLDriftController(
+		operatorClient,
+		coreClient,
 		kubeInformersForNamespaces,
 		configInformers.Config().V1().Networks(),
 		etcdClient,
@@ -290,14 +311,17 @@ func RunOperator(ctx context.Context, controllerContext *controllercmd.Controlle
 
 	go staleConditions.Run(ctx, 1)
 	go fsyncMetricController.Run(ctx, 1)
+	go tuningMetricController.Run(ctx, 1)
 	go staticResourceController.Run(ctx, 1)
 	go targetConfigReconciler.Run(ctx, 1)
 	go etcdCertSignerController.Run(ctx, 1)
+	go caRotationController.Run(ctx, 1)
 	go etcdEndpointsController.Run(ctx, 1)
 	go resourceSyncController.Run(ctx, 1)
 	go statusController.Run(ctx, 1)
 	go configObserver.Run(ctx, 1)
 	go clusterMemberController.Run(ctx, 1)
+	go peerURLDriftController.Run(ctx, 1)
 	go etcdMembersController.Run(ctx, 1)
 	go bootstrapTeardownController.Run(ctx, 1)
 	go unsupportedConfigOverridesController.Run(ctx, 1)
@@ -1,10 +1,14 @@
 package targetconfigcontroller
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/openshift/library-go/pkg/operator/resource/resourceread"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-etcd-operator/pkg/operator/etcd_assets"
 )
 
 func Test_checkCSRControllerCAConfigMap(t *testing.T) {
@@ -50,3 +54,41 @@ func csrControllerCAConfigMap(manager string) *v1.ConfigMap {
 		},
 	}
 }
+
+func TestMonitorKubeconfig(t *testing.T) {
+	replacer := strings.NewReplacer(
+		"${IMAGE}", "etcd",
+		"${OPERATOR_IMAGE}", "operator",
+		"${VERBOSITY}", "info",
+		"${LISTEN_ON_ALL_IPS}", "0.0.0.0",
+		"${LOCALHOST_IP}", "127.0.0.1",
+		"${COMPUTED_ENV_VARS}", `      - name: "ALL_ETCD_ENDPOINTS"
+        value: "https://10.0.0.1:2379"`,
+	)
+	pod := resourceread.ReadPodV1OrDie([]byte(replacer.Replace(string(etcd_assets.MustAsset("etcd/pod.yaml")))))
+
+	var monitor *v1.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == "etcd-health-monitor" {
+			monitor = &pod.Spec.Containers[i]
+		}
+	}
+	if monitor == nil {
+		t.Fatalf("etcd-health-monitor container not found")
+	}
+	var kubeconfig string
+	for _, arg := range monitor.Args {
+		if strings.HasPrefix(arg, "--kubeconfig=") {
+			kubeconfig = strings.TrimPrefix(arg, "--kubeconfig=")
+		}
+	}
+	if len(kubeconfig) == 0 {
+		t.Fatalf("etcd-health-monitor is missing --kubeconfig, args: %v", monitor.Args)
+	}
+	for _, mount := range monitor.VolumeMounts {
+		if strings.HasPrefix(kubeconfig, mount.MountPath+"/") {
+			return
+		}
+	}
+	t.Errorf("kubeconfig %s is not mounted into etcd-health-monitor", kubeconfig)
+}
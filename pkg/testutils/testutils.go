@@ -12,6 +12,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/uuid"
 
+	"github.com/openshift/cluster-etcd-operator/pkg/dnshelpers"
 	"github.com/openshift/cluster-etcd-operator/pkg/etcdcli"
 	"github.com/openshift/cluster-etcd-operator/pkg/operator/operatorclient"
 )
@@ -50,6 +51,15 @@ func WithNodeInternalIP(ip string) func(*corev1.Node) {
 	}
 }
 
+func WithNodeIPSelector(selector string) func(*corev1.Node) {
+	return func(node *corev1.Node) {
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[dnshelpers.NodeIPSelectorAnnotation] = selector
+	}
+}
+
 func FakeSecret(namespace, name string, cert map[string][]byte) *corev1.Secret {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
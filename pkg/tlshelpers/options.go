@@ -0,0 +1,51 @@
+package tlshelpers
+
+import (
+	"fmt"
+	"time"
+)
+
+// KeyAlgorithm is the algorithm of the private key of a cert.
+type KeyAlgorithm string
+
+const (
+	RSAKeyAlgorithm       KeyAlgorithm = "RSA"
+	ECDSAP256KeyAlgorithm KeyAlgorithm = "ECDSA-P256"
+
+	rsaKeyBits = 2048
+
+	DefaultCertValidity = 3 * 365 * 24 * time.Hour
+	// Certs are rolled out by a static pod revision, a shorter validity would
+	// roll out new revisions too frequently. Certs are regenerated before they
+	// expire, the time between regenerations must not be shorter either.
+	MinCertValidity = 24 * time.Hour
+
+	// Certs are regenerated with less than this percentage of their validity
+	// remaining.
+	DefaultRotationThresholdPercent = 20
+)
+
+// CertOptions configures the certs issued for etcd members.
+type CertOptions struct {
+	Validity     time.Duration
+	KeyAlgorithm KeyAlgorithm
+}
+
+func DefaultCertOptions() CertOptions {
+	return CertOptions{
+		Validity:     DefaultCertValidity,
+		KeyAlgorithm: RSAKeyAlgorithm,
+	}
+}
+
+func (o CertOptions) Validate() error {
+	if o.Validity < MinCertValidity {
+		return fmt.Errorf("validity %s is less than the minimum of %s", o.Validity, MinCertValidity)
+	}
+	switch o.KeyAlgorithm {
+	case RSAKeyAlgorithm, ECDSAP256KeyAlgorithm:
+		return nil
+	default:
+		return fmt.Errorf("unsupported key algorithm %q, must be %s or %s", o.KeyAlgorithm, RSAKeyAlgorithm, ECDSAP256KeyAlgorithm)
+	}
+}
@@ -2,27 +2,34 @@ package tlshelpers
 
 import (
 	"bytes"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"errors"
 	"fmt"
+	"io"
+	"math/big"
 	"strings"
 	"time"
 
-	"k8s.io/apimachinery/pkg/util/sets"
-
 	"github.com/openshift/library-go/pkg/crypto"
 )
 
 const (
-	etcdCertValidity = 3 * 365 * 24 * time.Hour
-
 	peerOrg   = "system:etcd-peers"
 	serverOrg = "system:etcd-servers"
 	metricOrg = "system:etcd-metrics"
+	clientOrg = "system:etcd-clients"
 
-	// TODO debt left for @hexfusion or @sanchezl
-	fakePodFQDN = "etcd-client"
+	// nodeCertName is the name in the common name of the peer, serving and
+	// metrics certs. It is kept so the identity of existing members does not
+	// change, clients outside of the members use dedicated certs issued by
+	// CreateClientCertKey.
+	nodeCertName = "etcd-client"
 
 	EtcdAllCertsSecretName = "etcd-all-certs"
 )
@@ -36,6 +43,9 @@ func GetServingSecretNameForNode(nodeName string) string {
 func GetServingMetricsSecretNameForNode(nodeName string) string {
 	return fmt.Sprintf("etcd-serving-metrics-%s", nodeName)
 }
+func GetClientCommonNameForConsumer(consumer string) string {
+	return fmt.Sprintf("system:etcd-client:%s", consumer)
+}
 
 func getPeerHostNames(nodeInternalIPs []string) []string {
 	return append([]string{"localhost"}, nodeInternalIPs...)
@@ -54,67 +64,91 @@ func getServerHostNames(nodeInternalIPs []string) []string {
 	}, nodeInternalIPs...)
 }
 
-func CreatePeerCertKey(caCert, caKey []byte, nodeInternalIPs []string) (*bytes.Buffer, *bytes.Buffer, error) {
-	return createNewCombinedClientAndServingCerts(caCert, caKey, fakePodFQDN, peerOrg, getPeerHostNames(nodeInternalIPs))
+func CreatePeerCertKey(caCert, caKey []byte, nodeInternalIPs []string, opts CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
+	return createNewCombinedClientAndServingCerts(caCert, caKey, nodeCertName, peerOrg, getPeerHostNames(nodeInternalIPs), opts)
+}
+
+func CreateServerCertKey(caCert, caKey []byte, nodeInternalIPs []string, opts CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
+	return createNewCombinedClientAndServingCerts(caCert, caKey, nodeCertName, serverOrg, getServerHostNames(nodeInternalIPs), opts)
+}
+
+func CreateMetricCertKey(caCert, caKey []byte, nodeInternalIPs []string, opts CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
+	return createNewCombinedClientAndServingCerts(caCert, caKey, nodeCertName, metricOrg, getServerHostNames(nodeInternalIPs), opts)
 }
 
-func CreateServerCertKey(caCert, caKey []byte, nodeInternalIPs []string) (*bytes.Buffer, *bytes.Buffer, error) {
-	return createNewCombinedClientAndServingCerts(caCert, caKey, fakePodFQDN, serverOrg, getServerHostNames(nodeInternalIPs))
+// CreateClientCertKey issues a client cert identifying consumer, e.g. the
+// kube-apiserver, so that its requests can be told apart from other clients
+// and its cert can be revoked on its own.
+func CreateClientCertKey(caCert, caKey []byte, consumer string, opts CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
+	subject := pkix.Name{
+		Organization: []string{clientOrg},
+		CommonName:   GetClientCommonNameForConsumer(consumer),
+	}
+	return createCert(caCert, caKey, subject, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, nil, opts)
 }
 
-func CreateMetricCertKey(caCert, caKey []byte, nodeInternalIPs []string) (*bytes.Buffer, *bytes.Buffer, error) {
-	return createNewCombinedClientAndServingCerts(caCert, caKey, fakePodFQDN, metricOrg, getServerHostNames(nodeInternalIPs))
+func createNewCombinedClientAndServingCerts(caCert, caKey []byte, podFQDN, org string, hostNames []string, opts CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
+	subject := pkix.Name{
+		Organization: []string{org},
+		CommonName:   strings.TrimSuffix(org, "s") + ":" + podFQDN,
+	}
+	return createCert(caCert, caKey, subject, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}, hostNames, opts)
 }
 
-func createNewCombinedClientAndServingCerts(caCert, caKey []byte, podFQDN, org string, hostNames []string) (*bytes.Buffer, *bytes.Buffer, error) {
-	cn, err := getCommonNameFromOrg(org)
+func createCert(caCert, caKey []byte, subject pkix.Name, extKeyUsage []x509.ExtKeyUsage, hostNames []string, opts CertOptions) (*bytes.Buffer, *bytes.Buffer, error) {
 	etcdCAKeyPair, err := crypto.GetCAFromBytes(caCert, caKey)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	certConfig, err := etcdCAKeyPair.MakeServerCertForDuration(sets.NewString(hostNames...), etcdCertValidity, func(cert *x509.Certificate) error {
+	publicKey, privateKey, err := newKeyPair(opts.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	subjectKeyId, err := newSubjectKeyId(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
 
-		cert.Issuer = pkix.Name{
-			OrganizationalUnit: []string{"openshift"},
-			CommonName:         cn,
-		}
-		cert.Subject = pkix.Name{
-			Organization: []string{org},
-			CommonName:   strings.TrimSuffix(org, "s") + ":" + podFQDN,
-		}
-		cert.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
-
-		// TODO: Extended Key Usage:
-		// All profiles expect a x509.ExtKeyUsageCodeSigning set on extended Key Usages
-		// need to investigage: https://github.com/etcd-io/etcd/issues/9398#issuecomment-435340312
-		// TODO: some extensions are missing form cfssl.
-		// e.g.
-		//	X509v3 Subject Key Identifier:
-		//		B7:30:0B:CF:47:4E:21:AE:13:60:74:42:B0:D9:C4:F3:26:69:63:03
-		//	X509v3 Authority Key Identifier:
-		//		keyid:9B:C0:6B:0C:8E:5C:73:6A:83:B1:E4:54:97:D3:62:18:8A:9C:BC:1E
-		// TODO: Change serial number logic, to something as follows.
-		// The following is taken from CFSSL library.
-		// If CFSSL is providing the serial numbers, it makes
-		// sense to use the max supported size.
-
-		//	serialNumber := make([]byte, 20)
-		//	_, err = io.ReadFull(rand.Reader, serialNumber)
-		//	if err != nil {
-		//		return err
-		//	}
-		//
-		//	// SetBytes interprets buf as the bytes of a big-endian
-		//	// unsigned integer. The leading byte should be masked
-		//	// off to ensure it isn't negative.
-		//	serialNumber[0] &= 0x7F
-		//	cert.SerialNumber = new(big.Int).SetBytes(serialNumber)
-		return nil
-	})
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:      subject,
+		SerialNumber: serialNumber,
+		NotBefore:    now.Add(-1 * time.Second),
+		NotAfter:     now.Add(opts.Validity),
+
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+
+		AuthorityKeyId: etcdCAKeyPair.Config.Certs[0].SubjectKeyId,
+		SubjectKeyId:   subjectKeyId,
+	}
+	if opts.KeyAlgorithm == RSAKeyAlgorithm {
+		// Key encipherment only applies to RSA key exchange
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	template.IPAddresses, template.DNSNames = crypto.IPAddressesDNSNames(hostNames)
+
+	// TODO: Extended Key Usage:
+	// All profiles expect a x509.ExtKeyUsageCodeSigning set on extended Key Usages
+	// need to investigage: https://github.com/etcd-io/etcd/issues/9398#issuecomment-435340312
+	der, err := x509.CreateCertificate(rand.Reader, template, etcdCAKeyPair.Config.Certs[0], publicKey, etcdCAKeyPair.Config.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
 	if err != nil {
 		return nil, nil, err
 	}
+	certConfig := &crypto.TLSCertificateConfig{
+		Certs: append([]*x509.Certificate{cert}, etcdCAKeyPair.Config.Certs...),
+		Key:   privateKey,
+	}
 
 	certBytes := &bytes.Buffer{}
 	keyBytes := &bytes.Buffer{}
@@ -124,12 +158,64 @@ func createNewCombinedClientAndServingCerts(caCert, caKey []byte, podFQDN, org s
 	return certBytes, keyBytes, nil
 }
 
-func getCommonNameFromOrg(org string) (string, error) {
-	if strings.Contains(org, "peer") || strings.Contains(org, "server") {
-		return "etcd-signer", nil
+func newKeyPair(algorithm KeyAlgorithm) (gocrypto.PublicKey, gocrypto.PrivateKey, error) {
+	switch algorithm {
+	case RSAKeyAlgorithm:
+		privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &privateKey.PublicKey, privateKey, nil
+	case ECDSAP256KeyAlgorithm:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &privateKey.PublicKey, privateKey, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+}
+
+// newSubjectKeyId derives the key id from the hash of the public key as
+// described in RFC 5280 section 4.2.1.2.
+func newSubjectKeyId(publicKey gocrypto.PublicKey) ([]byte, error) {
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum(publicKeyBytes)
+	return hash[:], nil
+}
+
+// newSerialNumber returns a random 20 byte serial number, the maximum size
+// allowed by RFC 5280.
+func newSerialNumber() (*big.Int, error) {
+	serialNumber := make([]byte, 20)
+	if _, err := io.ReadFull(rand.Reader, serialNumber); err != nil {
+		return nil, err
 	}
-	if strings.Contains(org, "metric") {
-		return "etcd-metric-signer", nil
+	// SetBytes interprets buf as the bytes of a big-endian unsigned integer.
+	// The leading byte is masked off to ensure it isn't negative.
+	serialNumber[0] &= 0x7F
+	return new(big.Int).SetBytes(serialNumber), nil
+}
+
+// IsIssuedBy indicates whether the leaf cert of certPEM was signed by one of
+// the CA certs of caPEM. Validity periods are not checked.
+func IsIssuedBy(certPEM, caPEM []byte) (bool, error) {
+	certs, err := crypto.CertsFromPEM(certPEM)
+	if err != nil {
+		return false, err
+	}
+	caCerts, err := crypto.CertsFromPEM(caPEM)
+	if err != nil {
+		return false, err
+	}
+	for _, caCert := range caCerts {
+		if err := certs[0].CheckSignatureFrom(caCert); err == nil {
+			return true, nil
+		}
 	}
-	return "", errors.New("unable to recognise secret name")
+	return false, nil
 }